@@ -0,0 +1,115 @@
+package resdk
+
+import (
+	"context"
+	"net/http"
+)
+
+// Operation a request performs on a resource, for authorization
+type Action string
+
+const (
+	ActionRead   Action = "read"
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Returns the Action of a request with method: read for GET, HEAD and
+// OPTIONS, create for POST, update for PUT and PATCH and delete for
+// DELETE. Other methods are their own action, e.g. "TRACE".
+func ActionFromMethod(method string) Action {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ActionRead
+	case http.MethodPost:
+		return ActionCreate
+	case http.MethodPut, http.MethodPatch:
+		return ActionUpdate
+	case http.MethodDelete:
+		return ActionDelete
+	}
+	return Action(method)
+}
+
+// Extended Authorizable for Outputables whose access depends on the
+// operation, e.g. readable by everyone but only changeable by its
+// owner. BaseHandler prefers it over Authorizable.
+//
+// Writes are authorized before the Processor runs: BaseHandler calls
+// AuthorizeAction on the Inputable of a write if it implements it, and
+// on the resource of BaseHandler.Loader. Outputs are only authorized
+// for reads, since the write already happened.
+type ActionAuthorizable interface {
+	// Authorize action on the object based on details of an
+	// authenticated user represented as auth_details. action comes
+	// from BaseHandler.Action or the request method.
+	AuthorizeAction(action Action, auth_details interface{}) error
+}
+
+// Returns the Action of r for the handler
+func (m *BaseHandler) action(r *http.Request) Action {
+	if m.Action != "" {
+		return m.Action
+	}
+	return ActionFromMethod(r.Method)
+}
+
+// Authorizes out for action if it is ActionAuthorizable or Authorizable
+func authorizeOutput(out Outputable, action Action, auth_details interface{}) error {
+	if action_authorizer, ok := out.(ActionAuthorizable); ok {
+		return action_authorizer.AuthorizeAction(action, auth_details)
+	}
+	if authorizer := GetAuthorizer(out); authorizer != nil {
+		return authorizer.Authorize(auth_details)
+	}
+	return nil
+}
+
+// Loads the resource a write acts on, see BaseHandler.Loader
+type Loadable interface {
+	// Returns the current resource in refers to, nil if there is none,
+	// e.g. for creates. auth_details is the same as returned by
+	// Authenticatable.Authenticate.
+	Load(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error)
+}
+
+// The LoaderFunc type is an adapter to allow the use of ordinary
+// functions as a Loadable.
+type LoaderFunc func(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error)
+
+// Load calls f(ctx, in, auth_details)
+func (f LoaderFunc) Load(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error) {
+	return f(ctx, in, auth_details)
+}
+
+// Authorizes the write of x before the Processor runs: the Inputable
+// if it is ActionAuthorizable or Authorizer InputRequestAuthorizable,
+// and resource, loaded by Loader, like the Outputable of a read
+func (m *BaseHandler) authorizeWrite(r *http.Request, x *Exchange, resource Outputable) error {
+	if authorizer, ok := x.Input.(ActionAuthorizable); ok {
+		if err := authorizer.AuthorizeAction(x.Action, x.AuthDetails); err != nil {
+			return err
+		}
+	}
+	if authorizer, ok := m.Authorizer.(InputRequestAuthorizable); ok {
+		if err := authorizer.AuthorizeInputRequest(r, x.Input, x.AuthDetails); err != nil {
+			return err
+		}
+	}
+	if resource == nil {
+		return nil
+	}
+	return m.authorizeResource(r, resource, x.Action, x.AuthDetails)
+}
+
+// Authorizes resource with its own Authorize and Authorizer
+func (m *BaseHandler) authorizeResource(r *http.Request, resource Outputable, action Action, auth_details interface{}) error {
+	if err := authorizeOutput(resource, action, auth_details); err != nil {
+		return err
+	}
+	if m.Authorizer != nil {
+		return m.Authorizer.AuthorizeRequest(r, resource, auth_details)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package resdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type actionDocument struct {
+	Owner string
+}
+
+func (d *actionDocument) OwnerID() string {
+	return d.Owner
+}
+
+func (d *actionDocument) AuthorizeAction(action Action, auth_details interface{}) error {
+	if action != ActionRead && auth_details != d.Owner {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+type actionInput struct{}
+
+func (actionInput) Validate() error {
+	return nil
+}
+
+// A handler of one document owned by alice. processed tells whether
+// the Processor ran.
+func actionHandler(processed *bool) *BaseHandler {
+	document := &actionDocument{Owner: "alice"}
+	status := func(out Outputable, w http.ResponseWriter, r *http.Request) {
+		code := http.StatusOK
+		if coder, ok := out.(StatusCoder); ok {
+			code = coder.StatusCode()
+		}
+		w.WriteHeader(code)
+	}
+	return &BaseHandler{
+		Authenticator: AuthenticatorFunc(func(r *http.Request) (interface{}, error) {
+			return r.Header.Get("Authorization"), nil
+		}),
+		Deserializer: DeserializerFunc(func(r *http.Request) (Inputable, error) {
+			return actionInput{}, nil
+		}),
+		Loader: LoaderFunc(func(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error) {
+			return document, nil
+		}),
+		Processor: ProcessorFunc(func(in Inputable) (Outputable, error) {
+			*processed = true
+			return document, nil
+		}),
+		SuccessSerializer:            SerializerFunc(status),
+		AuthorizationErrorSerializer: SerializerFunc(status),
+	}
+}
+
+func TestDeniedWriteDoesNotReachProcessor(t *testing.T) {
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		processed := false
+		req := httptest.NewRequest(method, "/documents/1", nil)
+		req.Header.Set("Authorization", "mallory")
+		rec := httptest.NewRecorder()
+		actionHandler(&processed).ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s: got %d, want 403", method, rec.Code)
+		}
+		if processed {
+			t.Errorf("%s: denied write reached the processor", method)
+		}
+	}
+}
+
+func TestAllowedWriteReachesProcessor(t *testing.T) {
+	processed := false
+	req := httptest.NewRequest(http.MethodDelete, "/documents/1", nil)
+	req.Header.Set("Authorization", "alice")
+	rec := httptest.NewRecorder()
+	actionHandler(&processed).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !processed {
+		t.Errorf("got %d, processed %v, want 200 and processed", rec.Code, processed)
+	}
+}
+
+type actionDeniedInput struct{}
+
+func (actionDeniedInput) Validate() error {
+	return nil
+}
+
+func (actionDeniedInput) AuthorizeAction(action Action, auth_details interface{}) error {
+	return ErrNotOwner
+}
+
+func TestDeniedWriteInputDoesNotReachProcessor(t *testing.T) {
+	processed := false
+	handler := actionHandler(&processed)
+	handler.Loader = nil
+	handler.Deserializer = DeserializerFunc(func(r *http.Request) (Inputable, error) {
+		return actionDeniedInput{}, nil
+	})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/documents/1", nil))
+	if rec.Code != http.StatusForbidden || processed {
+		t.Errorf("got %d, processed %v, want 403 and not processed", rec.Code, processed)
+	}
+}
+
+func TestReadAuthorizesOutput(t *testing.T) {
+	processed := false
+	handler := actionHandler(&processed)
+	handler.Authorizer = OwnershipAuthorizer{}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/documents/1", nil)
+	req.Header.Set("Authorization", "mallory")
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("got %d, want 403", rec.Code)
+	}
+}
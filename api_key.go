@@ -0,0 +1,153 @@
+package resdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Error for an API key which is unknown, revoked or expired. Responds
+// with 401 Unauthorized.
+var ErrInvalidApiKey error = invalidApiKeyError{}
+
+type invalidApiKeyError struct{}
+
+func (invalidApiKeyError) Error() string {
+	return "Invalid API key"
+}
+
+func (invalidApiKeyError) StatusCode() int {
+	return http.StatusUnauthorized
+}
+
+// Metadata of an API key, the auth details returned by
+// ApiKeyAuthenticator
+type ApiKey struct {
+	// Identifies the key without revealing it, e.g. in logs
+	ID string `json:"id"`
+	// User or client the key was issued to
+	Owner string `json:"owner"`
+	// Permissions granted to the key
+	Scopes []string `json:"scopes,omitempty"`
+	// The key is rejected from then on. Never expires if zero.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Returns the Scopes of the key
+func (k *ApiKey) GrantedScopes() []string {
+	return k.Scopes
+}
+
+// Returns the Owner of the key, see Principal
+func (k *ApiKey) PrincipalID() string {
+	return k.Owner
+}
+
+// Looks up API keys for ApiKeyAuthenticator
+type KeyStore interface {
+	// Returns the metadata of key. Returns (nil, nil) if the key is
+	// unknown or revoked.
+	Lookup(ctx context.Context, key string) (*ApiKey, error)
+}
+
+// Adapts a function to a KeyStore, e.g. one querying a database:
+//
+//	resdk.KeyStoreFunc(func(ctx context.Context, key string) (*resdk.ApiKey, error) {
+//		var k resdk.ApiKey
+//		err := db.QueryRowContext(ctx,
+//			"SELECT id, owner FROM api_keys WHERE hash = $1 AND NOT revoked",
+//			resdk.HashApiKey(key)).Scan(&k.ID, &k.Owner)
+//		if errors.Is(err, sql.ErrNoRows) {
+//			return nil, nil
+//		}
+//		return &k, err
+//	})
+type KeyStoreFunc func(ctx context.Context, key string) (*ApiKey, error)
+
+func (f KeyStoreFunc) Lookup(ctx context.Context, key string) (*ApiKey, error) {
+	return f(ctx, key)
+}
+
+// Returns the hex SHA-256 hash of key. Stores should keep hashes
+// instead of the keys themselves, so a leaked table reveals no key.
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// A KeyStore keeping hashed keys in memory. Use NewMemoryKeyStore to
+// create one.
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]ApiKey
+}
+
+// Creates an empty MemoryKeyStore
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		keys: make(map[string]ApiKey),
+	}
+}
+
+// Adds key with its metadata, replacing a previous entry
+func (s *MemoryKeyStore) Add(key string, meta ApiKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[HashApiKey(key)] = meta
+}
+
+// Revokes key
+func (s *MemoryKeyStore) Remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, HashApiKey(key))
+}
+
+func (s *MemoryKeyStore) Lookup(ctx context.Context, key string) (*ApiKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	meta, ok := s.keys[HashApiKey(key)]
+	if !ok {
+		return nil, nil
+	}
+	return &meta, nil
+}
+
+// An Authenticator reading an API key from a header or a query
+// parameter and validating it with a KeyStore. The *ApiKey of a valid
+// key is returned as auth details. Requests without a key fail with
+// ErrNoCredentials, unknown and expired keys with ErrInvalidApiKey.
+type ApiKeyAuthenticator struct {
+	// Required. Looks up the keys.
+	Store KeyStore
+	// Header carrying the key. Defaults to "X-API-Key".
+	Header string
+	// Query parameter carrying the key, used if the header is
+	// missing. Not read if empty, since URLs tend to end up in logs.
+	QueryParam string
+}
+
+func (a ApiKeyAuthenticator) Authenticate(r *http.Request) (interface{}, error) {
+	header := a.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+	key := r.Header.Get(header)
+	if key == "" && a.QueryParam != "" {
+		key = r.URL.Query().Get(a.QueryParam)
+	}
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+	meta, err := a.Store.Lookup(r.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil || (!meta.ExpiresAt.IsZero() && !time.Now().Before(meta.ExpiresAt)) {
+		return nil, ErrInvalidApiKey
+	}
+	return meta, nil
+}
@@ -0,0 +1,226 @@
+package resdk
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// State of an asynchronous Job
+type JobStatus string
+
+const (
+	// Job was accepted but has not started yet
+	JobPending JobStatus = "pending"
+	// Job is being processed
+	JobRunning JobStatus = "running"
+	// Job finished and Result holds its output
+	JobSucceeded JobStatus = "succeeded"
+	// Job finished and Error holds the error message
+	JobFailed JobStatus = "failed"
+)
+
+// An asynchronous unit of work started by an AsyncProcessor
+type Job struct {
+	ID        string     `json:"id"`
+	Status    JobStatus  `json:"status"`
+	Result    Outputable `json:"result,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Reports whether the job has finished, successfully or not
+func (j *Job) Done() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
+
+// Storage for Jobs started by an AsyncProcessor
+type JobStore interface {
+	// Saves job, replacing a stored job with the same ID
+	Save(ctx context.Context, job *Job) error
+	// Returns the job with id. Returns (nil, nil) if there is none.
+	Get(ctx context.Context, id string) (*Job, error)
+}
+
+// A JobStore keeping jobs in memory. Use NewMemoryJobStore to create one.
+type MemoryJobStore struct {
+	// Finished jobs older than Retention are removed. Jobs are kept
+	// forever if zero.
+	Retention time.Duration
+
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+// Creates an empty MemoryJobStore keeping finished jobs for retention
+func NewMemoryJobStore(retention time.Duration) *MemoryJobStore {
+	return &MemoryJobStore{
+		Retention: retention,
+		jobs:      make(map[string]*Job),
+	}
+}
+
+func (s *MemoryJobStore) Save(ctx context.Context, job *Job) error {
+	stored := *job
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = &stored
+	if s.Retention > 0 {
+		cutoff := time.Now().Add(-s.Retention)
+		for id, j := range s.jobs {
+			if j.Done() && j.UpdatedAt.Before(cutoff) {
+				delete(s.jobs, id)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *MemoryJobStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *job
+	return &found, nil
+}
+
+// Processes an Inputable in the background. Implemented by the
+// Processor wrapped by an AsyncProcessor.
+type AsyncProcessable interface {
+	// Same as AuthAwareProcessable.ProcessAuth. ctx carries the
+	// values of the request context but is not cancelled when the
+	// request ends.
+	ProcessAsync(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error)
+}
+
+// A Processor which runs an AsyncProcessable in a background goroutine
+// and immediately responds with 202 Accepted, the Job and a Location
+// header pointing to its status endpoint (see NewJobStatusHandler).
+type AsyncProcessor struct {
+	// Required. Does the actual processing.
+	Processor AsyncProcessable
+	// Required. Keeps track of started jobs.
+	Store JobStore
+	// Required. Returns the URL of the status endpoint of a job
+	StatusURL func(id string) string
+}
+
+func (a *AsyncProcessor) Process(in Inputable) (Outputable, error) {
+	return a.ProcessAuth(context.Background(), in, nil)
+}
+
+func (a *AsyncProcessor) ProcessAuth(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error) {
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	job := &Job{
+		ID:        id,
+		Status:    JobPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err = a.Store.Save(ctx, job); err != nil {
+		return nil, err
+	}
+	go a.run(context.WithoutCancel(ctx), *job, in, auth_details)
+	return &acceptedJob{Job: job, location: a.StatusURL(job.ID)}, nil
+}
+
+// Runs job and stores its outcome
+func (a *AsyncProcessor) run(ctx context.Context, job Job, in Inputable, auth_details interface{}) {
+	job.Status = JobRunning
+	job.UpdatedAt = time.Now()
+	a.Store.Save(ctx, &job)
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			job.Result = nil
+			job.Status = JobFailed
+			job.Error = fmt.Sprintf("panic: %v", rec)
+		}
+		job.UpdatedAt = time.Now()
+		a.Store.Save(ctx, &job)
+	}()
+	out, err := a.Processor.ProcessAsync(ctx, in, auth_details)
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+		return
+	}
+	job.Status = JobSucceeded
+	job.Result = out
+}
+
+// Output of an AsyncProcessor
+type acceptedJob struct {
+	*Job
+	location string
+}
+
+func (a *acceptedJob) StatusCode() int {
+	return http.StatusAccepted
+}
+
+func (a *acceptedJob) Headers() http.Header {
+	return http.Header{"Location": {a.location}}
+}
+
+// Inputable identifying a Job
+type JobInput struct {
+	ID string
+}
+
+func (j *JobInput) Validate() error {
+	if j.ID == "" {
+		return errors.New("job id is required")
+	}
+	return nil
+}
+
+// Creates a handler serving the status of jobs in store. The job id is
+// read from the path wildcard named ResourceIDParam, so the handler
+// should be registered on a pattern like "/jobs/{id}". Unknown jobs
+// result in Not found.
+func NewJobStatusHandler(base BaseHandler, store JobStore) BaseHandler {
+	base.Deserializer = DeserializerFunc(func(r *http.Request) (Inputable, error) {
+		return &JobInput{ID: r.PathValue(ResourceIDParam)}, nil
+	})
+	base.Processor = jobStatusProcessor{store: store}
+	return base
+}
+
+type jobStatusProcessor struct {
+	store JobStore
+}
+
+func (j jobStatusProcessor) Process(in Inputable) (Outputable, error) {
+	return j.ProcessCtx(context.Background(), in)
+}
+
+func (j jobStatusProcessor) ProcessCtx(ctx context.Context, in Inputable) (Outputable, error) {
+	job, err := j.store.Get(ctx, in.(*JobInput).ID)
+	if err != nil || job == nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Returns a random 128 bit identifier in hex
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
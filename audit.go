@@ -0,0 +1,294 @@
+package resdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// An entry of the audit log, see AuditLogger
+type AuditRecord struct {
+	// Time the request started
+	Time time.Time `json:"time"`
+	// PrincipalID of the caller, "" for anonymous callers
+	Principal string `json:"principal,omitempty"`
+	Tenant    string `json:"tenant,omitempty"`
+	Action    Action `json:"action"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	// Identifiers of the resources touched, see AuditIdentifiable
+	Resource map[string]string `json:"resource,omitempty"`
+	// The Inputable with redact tagged fields removed (see Redact),
+	// nil if deserialization failed or AuditLogger.RecordInput is not
+	// set
+	Input interface{} `json:"input,omitempty"`
+	// "success" or "failure"
+	Outcome string `json:"outcome"`
+	// Status code of the response
+	Status int `json:"status"`
+	// Message of the error which ended the request
+	Error   string        `json:"error,omitempty"`
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// Outcomes of an AuditRecord
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// Set of functions which can be implemented by Inputables and
+// Outputables to name the resources a request touches in the audit
+// log, e.g. {"order": "42"}. Identifiers of the Outputable are added
+// to those of the Inputable.
+type AuditIdentifiable interface {
+	AuditResource() map[string]string
+}
+
+// Destination of AuditRecords
+type AuditSink interface {
+	WriteAudit(ctx context.Context, record *AuditRecord) error
+}
+
+// The AuditSinkFunc type is an adapter to allow the use of ordinary
+// functions as an AuditSink.
+type AuditSinkFunc func(ctx context.Context, record *AuditRecord) error
+
+// WriteAudit calls f(ctx, record)
+func (f AuditSinkFunc) WriteAudit(ctx context.Context, record *AuditRecord) error {
+	return f(ctx, record)
+}
+
+// Writes every AuditRecord to all of several AuditSinks. Failures of
+// one sink do not stop the others; their errors are joined.
+type AuditSinks []AuditSink
+
+func (a AuditSinks) WriteAudit(ctx context.Context, record *AuditRecord) error {
+	var errs []error
+	for _, sink := range a {
+		if err := sink.WriteAudit(ctx, record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// An AuditSink writing records as Json lines, e.g. to a file opened
+// with OpenAuditFile or to os.Stdout. Safe for concurrent use.
+type AuditWriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Creates an AuditWriterSink writing to w
+func NewAuditWriterSink(w io.Writer) *AuditWriterSink {
+	return &AuditWriterSink{w: w}
+}
+
+// Creates an AuditWriterSink appending to the file at path, which is
+// created if it does not exist
+func OpenAuditFile(path string) (*AuditWriterSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return NewAuditWriterSink(f), nil
+}
+
+func (s *AuditWriterSink) WriteAudit(ctx context.Context, record *AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// Closes the underlying writer if it is an io.Closer
+func (s *AuditWriterSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if closer, ok := s.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// An AuditSink posting every record as Json to a collector
+type AuditHttpSink struct {
+	// Required
+	URL string
+	// Defaults to http.DefaultClient
+	Client *http.Client
+	// Additional request headers, e.g. Authorization
+	Header http.Header
+}
+
+func (s AuditHttpSink) WriteAudit(ctx context.Context, record *AuditRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range s.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending audit record: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sending audit record: %s", resp.Status)
+	}
+	return nil
+}
+
+// Publishes messages to a topic of a message broker such as Kafka.
+// Adapting a client library takes a few lines, e.g. for
+// github.com/segmentio/kafka-go:
+//
+//	type kafkaProducer struct{ w *kafka.Writer }
+//
+//	func (p kafkaProducer) Produce(ctx context.Context, topic string, key, value []byte) error {
+//		return p.w.WriteMessages(ctx, kafka.Message{Topic: topic, Key: key, Value: value})
+//	}
+type AuditProducer interface {
+	Produce(ctx context.Context, topic string, key, value []byte) error
+}
+
+// An AuditSink publishing records as Json through an AuditProducer.
+// Messages are keyed by principal, so the records of a caller stay in
+// order within a partition.
+type AuditKafkaSink struct {
+	// Required
+	Producer AuditProducer
+	// Required
+	Topic string
+}
+
+func (s AuditKafkaSink) WriteAudit(ctx context.Context, record *AuditRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.Producer.Produce(ctx, s.Topic, []byte(record.Principal), value)
+}
+
+// An Interceptor writing an AuditRecord to Sink for every request which
+// passed authentication, whether it succeeded or not:
+//
+//	resdk.BaseHandler{
+//		...
+//		Interceptors: []resdk.Interceptor{
+//			&resdk.AuditLogger{Sink: resdk.AuditHttpSink{URL: "https://audit.example.com/records"}},
+//		},
+//	}
+//
+// Requests rejected by the Authenticator are not audited, including
+// those let through by AllowAnonymous. Inputs are only recorded with
+// RecordInput, with all redact tagged fields removed; secrets in fields
+// without a redact tag would reach the log.
+type AuditLogger struct {
+	// Required
+	Sink AuditSink
+	// Record the input, see AuditRecord.Input. Only set this if all
+	// secret fields of the inputs are tagged for redaction.
+	RecordInput bool
+	// Write records from a goroutine, so slow sinks do not hold up
+	// the request
+	Async bool
+	// Optional callback receiving errors of Sink
+	OnError func(r *http.Request, err error)
+}
+
+// Marks an Exchange whose authentication failed
+type auditRejectedKey struct{}
+
+func (a *AuditLogger) Before(ctx context.Context, phase Phase, r *http.Request) context.Context {
+	return ctx
+}
+
+func (a *AuditLogger) After(ctx context.Context, phase Phase, r *http.Request, err error) {
+	x := ExchangeFromContext(ctx)
+	if x == nil {
+		return
+	}
+	switch phase {
+	case PhaseAuthenticate:
+		if err != nil {
+			x.Set(auditRejectedKey{}, true)
+		}
+	case PhaseRequest:
+		if _, rejected := x.Get(auditRejectedKey{}); rejected {
+			return
+		}
+		record := a.record(x, r, err)
+		ctx = context.WithoutCancel(ctx)
+		if a.Async {
+			go a.write(ctx, r, record)
+		} else {
+			a.write(ctx, r, record)
+		}
+	}
+}
+
+// Builds the AuditRecord of the request served with x
+func (a *AuditLogger) record(x *Exchange, r *http.Request, err error) *AuditRecord {
+	record := &AuditRecord{
+		Time:      x.Started,
+		Principal: PrincipalID(x.AuthDetails),
+		Tenant:    x.Tenant,
+		Action:    x.Action,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Outcome:   AuditSuccess,
+		Status:    x.Status(),
+		Latency:   time.Since(x.Started),
+	}
+	if err != nil {
+		record.Outcome = AuditFailure
+		record.Error = err.Error()
+	}
+	for _, v := range []interface{}{x.Input, x.Output} {
+		identifiable, ok := v.(AuditIdentifiable)
+		if !ok {
+			continue
+		}
+		for kind, id := range identifiable.AuditResource() {
+			if record.Resource == nil {
+				record.Resource = make(map[string]string)
+			}
+			record.Resource[kind] = id
+		}
+	}
+	if a.RecordInput && x.Input != nil {
+		record.Input = Redact(x.Input, nil)
+	}
+	return record
+}
+
+// Writes record to Sink and reports failures
+func (a *AuditLogger) write(ctx context.Context, r *http.Request, record *AuditRecord) {
+	if err := a.Sink.WriteAudit(ctx, record); err != nil && a.OnError != nil {
+		a.OnError(r, err)
+	}
+}
@@ -1,8 +1,11 @@
 package resdk
 
 import (
+	"context"
 	"errors"
+	"log/slog"
 	"net/http"
+	"time"
 )
 
 // Set of functions which must be implemented by the deserialized
@@ -24,6 +27,75 @@ type Authorizable interface {
 	Authorize(auth_details interface{}) error
 }
 
+// Set of functions which can be optionally implemented by an
+// Inputable which needs authorization check before processing.
+// Unlike Authorizable it allows rejecting an operation (e.g. a create
+// or delete) before the Processor runs.
+type InputAuthorizable interface {
+	// Authorize the operation described by the input based on details
+	// of an authenticated user represented as auth_details.
+	// auth_details is the same as returned by Authenticatable.Authenticate
+	AuthorizeInput(auth_details interface{}) error
+}
+
+// Set of functions which can be optionally implemented by an
+// Inputable which needs checks against external systems, e.g.
+// uniqueness of a user name or a remote allowlist. It runs after
+// Validate succeeded and the input was authorized, so expensive checks
+// only run for well-formed, permitted requests.
+type ExternalValidatable interface {
+	// Validate the input against external systems. ctx is the request
+	// context and auth_details the same as returned by
+	// Authenticatable.Authenticate. Errors implementing StatusCoder
+	// choose their status, e.g. 409 Conflict for a name already taken.
+	ValidateExternal(ctx context.Context, auth_details interface{}) error
+}
+
+// Authorizes the outputs of a handler based on the whole request, e.g.
+// by asking a policy engine such as OpaAuthorizer. Unlike Authorizable
+// it is configured on the handler and sees the request.
+type RequestAuthorizable interface {
+	// Authorize access to out, the Outputable of the Processor for r.
+	// auth_details is the same as returned by
+	// Authenticatable.Authenticate.
+	AuthorizeRequest(r *http.Request, out Outputable, auth_details interface{}) error
+}
+
+// Set of functions which can be optionally implemented by a
+// BaseHandler.Authorizer to authorize writes before the Processor
+// performs them, seeing the Inputable instead of an Outputable
+type InputRequestAuthorizable interface {
+	// Authorize the write r with input in. auth_details is the same as
+	// returned by Authenticatable.Authenticate.
+	AuthorizeInputRequest(r *http.Request, in Inputable, auth_details interface{}) error
+}
+
+// A RequestAuthorizable requiring all of several RequestAuthorizables
+// to allow the request, e.g. TenantScopedAuthorizer and
+// OwnershipAuthorizer. The first failure is returned.
+type RequestAuthorizers []RequestAuthorizable
+
+func (a RequestAuthorizers) AuthorizeRequest(r *http.Request, out Outputable, auth_details interface{}) error {
+	for _, authorizer := range a {
+		if err := authorizer.AuthorizeRequest(r, out, auth_details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Calls the InputRequestAuthorizable ones of the authorizers
+func (a RequestAuthorizers) AuthorizeInputRequest(r *http.Request, in Inputable, auth_details interface{}) error {
+	for _, authorizer := range a {
+		if input_authorizer, ok := authorizer.(InputRequestAuthorizable); ok {
+			if err := input_authorizer.AuthorizeInputRequest(r, in, auth_details); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // Set of functions which must be implemented by the object
 // being sent to the response serializer
 type Outputable interface {
@@ -37,14 +109,18 @@ type Authenticatable interface {
 	// Returns authentication details if user was successfully
 	// authenticated. Otherwise returns an error to be sent as
 	// the response.
+	// The request context is available as r.Context().
 	Authenticate(r *http.Request) (interface{}, error)
 }
 
 // Manages second phase of the request lifecycle responsible for
 // deserialization.
 type Deserializable interface {
-	// Deserilaizes a request returns an object which should be
+	// Deserializes a request and returns an object which should be
 	// an implementation of Inputable.
+	// Returns an error if the request is malformed. The error is
+	// sent as the response by DeserializationErrorSerializer.
+	// The request context is available as r.Context().
 	Deserialize(r *http.Request) (Inputable, error)
 }
 
@@ -58,23 +134,85 @@ type Processable interface {
 	Process(in Inputable) (Outputable, error)
 }
 
+// Context aware variant of Processable. If a Processor implements
+// ProcessableCtx, BaseHandler calls ProcessCtx with the request
+// context instead of Process, so that deadlines and cancellation
+// (e.g. a disconnected client) reach the business logic.
+type ProcessableCtx interface {
+	// Same as Processable.Process but receives the request context
+	ProcessCtx(ctx context.Context, in Inputable) (Outputable, error)
+}
+
+// Variant of Processable for Processors which need to know who the
+// caller is (e.g. for tenant scoping or ownership checks). If a
+// Processor implements AuthAwareProcessable, BaseHandler prefers
+// ProcessAuth over ProcessCtx and Process.
+type AuthAwareProcessable interface {
+	// Same as ProcessableCtx.ProcessCtx but also receives the
+	// authentication details returned by Authenticatable.Authenticate.
+	// auth_details is nil if the handler has no Authenticator.
+	ProcessAuth(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error)
+}
+
 // Manages fourth phase of the request lifecycle responsible for
 // serialization and writing response.
 type Serializable interface {
 	// Serializes an Outputable object onto the response writer
+	// The request context is available as r.Context().
 	Serialize(out Outputable, w http.ResponseWriter, r *http.Request)
 }
 
+// Variant of Serializable which reports failures to marshal or write
+// the response (e.g. broken pipes) instead of swallowing them.
+// BaseHandler prefers SerializeChecked when a serializer implements it
+// and passes the error to OnSerializeError.
+type CheckedSerializable interface {
+	// Same as Serializable.Serialize but returns the error which
+	// prevented the response from being written completely
+	SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error
+}
+
 // Checks whether an Outputable implements Authorizable interface
 // and returns the Authorizor. Returns nil if it does not.
 func GetAuthorizer(o Outputable) Authorizable {
-	_ = o.(Authorizable)
 	if authorizer, ok := o.(Authorizable); ok {
 		return authorizer
 	}
 	return nil
 }
 
+// Checks whether an Inputable implements InputAuthorizable interface
+// and returns the authorizer. Returns nil if it does not.
+func GetInputAuthorizer(i Inputable) InputAuthorizable {
+	if authorizer, ok := i.(InputAuthorizable); ok {
+		return authorizer
+	}
+	return nil
+}
+
+// Runs the Processor p on in. Prefers AuthAwareProcessable, then
+// ProcessableCtx and finally Processable depending on what p implements.
+func callProcessor(ctx context.Context, p Processable, in Inputable, auth_details interface{}) (Outputable, error) {
+	if pauth, ok := p.(AuthAwareProcessable); ok {
+		return pauth.ProcessAuth(ctx, in, auth_details)
+	}
+	if pctx, ok := p.(ProcessableCtx); ok {
+		return pctx.ProcessCtx(ctx, in)
+	}
+	return p.Process(in)
+}
+
+// Runs the Serializer s on out. Prefers CheckedSerializable over
+// Serializable when s implements it. Returns nil for serializers which
+// cannot report errors.
+func callSerializer(s Serializable, out Outputable, w http.ResponseWriter, r *http.Request) error {
+	if checked, ok := s.(CheckedSerializable); ok {
+		return checked.SerializeChecked(out, w, r)
+	}
+	s.Serialize(out, w, r)
+	return nil
+}
+
 // A net/http Handler implementation which sets up the basic request
 // lifecycle.
 type BaseHandler struct {
@@ -82,6 +220,32 @@ type BaseHandler struct {
 	// Performs authentication of incoming request.
 	// Set it to nil if no authentication is needed.
 	Authenticator Authenticatable
+	// If set, a failed authentication does not end the request.
+	// Processing continues with nil authentication details and the
+	// Processor and Authorizers decide what anonymous callers may do.
+	AllowAnonymous bool
+	// Scopes the authentication details must grant (see Scoped) for
+	// any request. Failures are *InsufficientScopeError sent through
+	// AuthorizationErrorSerializer.
+	RequiredScopes []string
+	// Optional. Authorizes the Outputable of reads after its own
+	// Authorize (see Authorizable), and the Inputable (if it is
+	// InputRequestAuthorizable) and the resource of Loader before
+	// writes. Failures are sent through AuthorizationErrorSerializer.
+	Authorizer RequestAuthorizable
+	// Optional. Loads the resource a write acts on, so it is
+	// authorized like the Outputable of a read before the Processor
+	// changes it. Failures are sent through ProcessingErrorSerializer.
+	Loader Loadable
+	// Optional. Determines the tenant after authentication, see
+	// TenantFromContext. Requests without a tenant fail with
+	// ErrTenantRequired. Failures are sent through
+	// AuthenticationErrorSerializer.
+	TenantResolver TenantResolvable
+	// Action passed to ActionAuthorizable outputs, for handlers whose
+	// method does not tell, e.g. a POST which deletes. Derived from
+	// the request method with ActionFromMethod if empty.
+	Action Action
 
 	// Phase II
 	// Performs deserialization of incoming request.
@@ -105,6 +269,10 @@ type BaseHandler struct {
 	DeserializationErrorSerializer Serializable
 	// Error response serializer in case of validation failure
 	ValidationErrorSerializer Serializable
+	// Error response serializer in case of external validation
+	// failure (see ExternalValidatable). Falls back to
+	// ValidationErrorSerializer if nil.
+	ExternalValidationErrorSerializer Serializable
 	// Error response serializer in case of processing failure
 	ProcessingErrorSerializer Serializable
 	// Error response serializer in case no output from Processor
@@ -112,55 +280,330 @@ type BaseHandler struct {
 	// Error response serializer in case authenticated user has
 	// no authority over processor output for this operation
 	AuthorizationErrorSerializer Serializable
+	// Error response serializer in case the request method is not
+	// handled. Used by MethodHandler.
+	MethodNotAllowedSerializer Serializable
+
+	// Maximum duration of the deserialization phase. The phase
+	// context is cancelled and TimeoutSerializer is used when it is
+	// exceeded. The request body cannot be read anymore then. No
+	// timeout if zero.
+	DeserializeTimeout time.Duration
+	// Maximum duration of the processing phase. The phase context
+	// is cancelled and TimeoutSerializer is used when it is
+	// exceeded. The Processor keeps running in the background until
+	// it returns, so it should implement ProcessableCtx (or
+	// ProcessableAuth) and return once the context is done. No
+	// timeout if zero.
+	ProcessTimeout time.Duration
+	// Error response serializer in case a phase exceeds its timeout.
+	// The serializer receives a *TimeoutError. Falls back to the
+	// error serializer of the phase if nil.
+	TimeoutSerializer Serializable
+
+	// Maximum size of the request body after decompression. Larger
+	// bodies fail with a *BodyTooLargeError (413) sent through
+	// DeserializationErrorSerializer. No limit if zero.
+	MaxBodyBytes int64
+	// Transparently decompress request bodies with Content-Encoding
+	// gzip (or x-gzip) before deserialization. Other encodings fail
+	// with an *UnsupportedEncodingError (415).
+	DecompressBody bool
+
+	// Error response serializer in case of a panic in any phase.
+	// The serializer receives a *PanicError. Panics are not
+	// recovered if it is nil.
+	RecoverySerializer Serializable
+	// Optional callback invoked with the recovered panic (including
+	// its stack trace) before RecoverySerializer is called
+	OnPanic func(r *http.Request, perr *PanicError)
+	// Optional. Receives panics recovered through RecoverySerializer
+	// and errors which ended in a 5xx response.
+	ErrorReporter ErrorReporter
+
+	// Optional. Errors registered in ErrorMapper are sent through
+	// the mapped serializer instead of the error serializer of the
+	// phase which failed.
+	ErrorMapper *ErrorMapper
+
+	// Optional callback receiving the errors reported by
+	// CheckedSerializable serializers
+	OnSerializeError func(r *http.Request, err error)
+
+	// Hooks run in order after a success response was serialized.
+	// They run synchronously; start a goroutine in the hook for
+	// slow side effects.
+	AfterSuccess []SuccessHook
+	// Optional callback receiving errors of AfterSuccess hooks.
+	// Panicking hooks are reported as *PanicError.
+	OnHookError func(r *http.Request, err error)
+
+	// Interceptors called before and after every phase of the
+	// request lifecycle. See Interceptor.
+	Interceptors []Interceptor
+	// Optional. Logs every request with its status, duration,
+	// principal and, if it failed, the error and failed phase.
+	Logger *slog.Logger
+	// Requests taking longer than SlowRequestThreshold are passed to
+	// OnSlowRequest with the duration of every phase, PhaseRequest
+	// being the whole request. The Exchange of r tells the rest, e.g.
+	// the status. Disabled if zero.
+	SlowRequestThreshold time.Duration
+	OnSlowRequest        func(r *http.Request, timings map[Phase]time.Duration)
+	// Send a Server-Timing header with the duration of every phase,
+	// so clients can see where latency comes from. Reveals timings of
+	// e.g. authentication, so enable it for trusted clients only.
+	ServerTiming bool
+
+	// Optional. Security headers set on every response.
+	SecurityHeaders *SecurityHeaders
+	// Optional. Cross-Origin Resource Sharing configuration.
+	Cors *CorsConfig
+	// Optional. Idempotency-Key support for unsafe methods.
+	Idempotency *IdempotencyConfig
+	// Optional. Rate limiting after (and optionally before)
+	// authentication.
+	RateLimit *RateLimitConfig
+	// Optional. Monthly quotas checked after rate limiting.
+	Quota *QuotaConfig
+	// Optional. Bounds simultaneous Process invocations, see
+	// ConcurrencyLimiter. Waiting for a slot counts towards
+	// ProcessTimeout.
+	MaxConcurrent *ConcurrencyLimiter
 }
 
 func (m *BaseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.SecurityHeaders != nil {
+		m.SecurityHeaders.apply(w.Header())
+	}
+	if m.Cors != nil && m.Cors.handle(w, r) {
+		return
+	}
+	x := newExchange(w, r)
+	x.serverTiming = m.ServerTiming
+	r = r.WithContext(contextWithExchange(r.Context(), x))
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = &exchangeBody{ReadCloser: r.Body, x: x}
+	}
+	r = m.before(r, PhaseRequest)
+	err := m.serveRecover(x.Writer, r)
+	m.after(r, PhaseRequest, err)
+	if m.Logger != nil {
+		m.logRequest(r, x, err)
+	}
+	if m.ErrorReporter != nil && err != nil {
+		m.reportError(r, x, err)
+	}
+	if m.SlowRequestThreshold > 0 && m.OnSlowRequest != nil {
+		if timings := x.Timings(); timings[PhaseRequest] > m.SlowRequestThreshold {
+			m.OnSlowRequest(r, timings)
+		}
+	}
+}
+
+// Runs the request lifecycle. Returns the error which ended the
+// request or nil if it succeeded.
+func (m *BaseHandler) serve(w http.ResponseWriter, r *http.Request) error {
 	var err error
-	var auth_details interface{} = nil
+	x := ExchangeFromRequest(r)
+	x.Action = m.action(r)
+	if m.RateLimit != nil && m.RateLimit.ClientLimiter != nil {
+		if err = m.RateLimit.allow(w, r, m.RateLimit.ClientLimiter, nil); err != nil {
+			m.serializeError(m.rateLimitErrorSerializer(), err, w, r)
+			return err
+		}
+	}
 	// Authenticate if Authenticator was set
 	if m.Authenticator != nil {
-		auth_details, err = m.Authenticator.Authenticate(r)
+		pr := m.before(r, PhaseAuthenticate)
+		x.AuthDetails, err = m.Authenticator.Authenticate(pr)
+		m.after(pr, PhaseAuthenticate, err)
+		if err != nil && m.AllowAnonymous {
+			x.AuthDetails, err = nil, nil
+			x.forgiveFailure()
+		}
 		if err != nil {
-			m.AuthenticationErrorSerializer.Serialize(err, w, r)
-			return
+			m.serializeError(m.AuthenticationErrorSerializer, err, w, r)
+			return err
+		}
+	}
+	if len(m.RequiredScopes) > 0 {
+		if err = RequireScopes(x.AuthDetails, m.RequiredScopes...); err != nil {
+			m.serializeError(m.AuthorizationErrorSerializer, err, w, r)
+			return err
+		}
+	}
+	if m.TenantResolver != nil {
+		x.Tenant, err = m.TenantResolver.ResolveTenant(r, x.AuthDetails)
+		if err == nil && x.Tenant == "" {
+			err = ErrTenantRequired
+		}
+		if err != nil {
+			m.serializeError(m.AuthenticationErrorSerializer, err, w, r)
+			return err
+		}
+	}
+
+	if m.RateLimit != nil {
+		if err = m.RateLimit.allow(w, r, m.RateLimit.Limiter, x.AuthDetails); err != nil {
+			m.serializeError(m.rateLimitErrorSerializer(), err, w, r)
+			return err
+		}
+	}
+
+	if m.Quota != nil {
+		if err = m.Quota.check(r, x.AuthDetails); err != nil {
+			s := m.Quota.ErrorSerializer
+			if s == nil {
+				s = m.AuthorizationErrorSerializer
+			}
+			m.serializeError(s, err, w, r)
+			return err
 		}
 	}
 
 	// Deserialize and validate the request
-	in, err := m.Deserializer.Deserialize(r)
+	if err = m.prepareBody(r); err != nil {
+		m.serializeError(m.DeserializationErrorSerializer, err, w, r)
+		return err
+	}
+	if m.Idempotency != nil {
+		var finish func()
+		var replayed bool
+		w, finish, replayed, err = m.Idempotency.begin(w, r, x.AuthDetails, m.MaxBodyBytes)
+		if err != nil {
+			m.serializeError(m.DeserializationErrorSerializer, err, w, r)
+			return err
+		}
+		if replayed {
+			return nil
+		}
+		defer finish()
+	}
+	pr := m.before(r, PhaseDeserialize)
+	x.Input, err = m.deserialize(w, pr)
+	m.after(pr, PhaseDeserialize, err)
 	if err != nil {
-		m.DeserializationErrorSerializer.Serialize(err, w, r)
-		return
+		m.serializeError(m.timeoutSerializer(err, m.DeserializationErrorSerializer), err, w, r)
+		return err
+	}
+	if sanitizable, ok := x.Input.(Sanitizable); ok {
+		sanitizable.Sanitize()
 	}
-	verrors := in.Validate()
+	pr = m.before(r, PhaseValidate)
+	verrors := x.Input.Validate()
+	m.after(pr, PhaseValidate, verrors)
 	if verrors != nil {
-		m.ValidationErrorSerializer.Serialize(verrors, w, r)
-		return
+		m.serializeError(m.ValidationErrorSerializer, verrors, w, r)
+		return verrors
+	}
+
+	// If Inputable is also InputAuthorizable then Authorize it
+	// before it reaches the Processor
+	if input_authorizer := GetInputAuthorizer(x.Input); input_authorizer != nil {
+		err = input_authorizer.AuthorizeInput(x.AuthDetails)
+		if err != nil {
+			m.serializeError(m.AuthorizationErrorSerializer, err, w, r)
+			return err
+		}
+	}
+
+	// Writes are authorized before the Processor performs them
+	if x.Action != ActionRead {
+		var resource Outputable
+		if m.Loader != nil {
+			if resource, err = m.Loader.Load(r.Context(), x.Input, x.AuthDetails); err != nil {
+				m.serializeError(m.ProcessingErrorSerializer, err, w, r)
+				return err
+			}
+		}
+		if err = m.authorizeWrite(r, x, resource); err != nil {
+			m.serializeError(m.AuthorizationErrorSerializer, err, w, r)
+			return err
+		}
+	}
+
+	// Validate against external systems if Inputable asks for it
+	if external, ok := x.Input.(ExternalValidatable); ok {
+		pr = m.before(r, PhaseValidateExternal)
+		err = external.ValidateExternal(pr.Context(), x.AuthDetails)
+		m.after(pr, PhaseValidateExternal, err)
+		if err != nil {
+			s := m.ExternalValidationErrorSerializer
+			if s == nil {
+				s = m.ValidationErrorSerializer
+			}
+			m.serializeError(s, err, w, r)
+			return err
+		}
 	}
 
 	// Process the request to get an Outputable
-	out, err := m.Processor.Process(in)
+	pr = m.before(r, PhaseProcess)
+	in, auth_details := x.Input, x.AuthDetails
+	out, err := withTimeout(pr, PhaseProcess, m.ProcessTimeout, func(pr *http.Request) (Outputable, error) {
+		if m.MaxConcurrent != nil {
+			if err := m.MaxConcurrent.acquire(pr.Context()); err != nil {
+				return nil, err
+			}
+			// Held until the Processor returns, even after a timeout
+			defer m.MaxConcurrent.release()
+		}
+		return callProcessor(pr.Context(), m.Processor, in, auth_details)
+	})
+	m.after(pr, PhaseProcess, err)
 	if err != nil {
-		m.ProcessingErrorSerializer.Serialize(err, w, r)
-		return
+		m.serializeError(m.timeoutSerializer(err, m.ProcessingErrorSerializer), err, w, r)
+		return err
 	}
 	if out == nil {
 		// No output is treated as NotFound
 		err = errors.New("Not found")
-		m.NotFoundSerializer.Serialize(err, w, r)
-		return
+		m.serializeError(m.NotFoundSerializer, err, w, r)
+		return err
 	}
+	x.Output = out
 
-	// If Outputable is also Authorizable then Authorize it
-	authorizer := GetAuthorizer(out)
-	if authorizer != nil {
-		err = authorizer.Authorize(auth_details)
-		if err != nil {
-			m.AuthorizationErrorSerializer.Serialize(err, w, r)
-			return
+	// If the Outputable of a read is also (Action)Authorizable then
+	// Authorize it
+	if x.Action == ActionRead {
+		if err = m.authorizeResource(r, x.Output, x.Action, x.AuthDetails); err != nil {
+			m.serializeError(m.AuthorizationErrorSerializer, err, w, r)
+			return err
 		}
 	}
 
-	m.SuccessSerializer.Serialize(out, w, r)
-	return
+	if !writeNotModifiedSince(w, r, x.Output) {
+		m.serialize(m.SuccessSerializer, x.Output, w, r)
+	}
+	m.runSuccessHooks(x.Output, x.AuthDetails, r)
+	return nil
+}
+
+// Runs the serialization phase for err with the Serializer mapped by
+// ErrorMapper or with fallback if there is none
+func (m *BaseHandler) serializeError(fallback Serializable, err error, w http.ResponseWriter, r *http.Request) {
+	s := m.ErrorMapper.Serializer(err)
+	if s == nil {
+		s = fallback
+	}
+	m.serialize(s, err, w, r)
+}
+
+// Returns the serializer of rate limiting errors
+func (m *BaseHandler) rateLimitErrorSerializer() Serializable {
+	if m.RateLimit.ErrorSerializer != nil {
+		return m.RateLimit.ErrorSerializer
+	}
+	return m.AuthorizationErrorSerializer
+}
+
+// Runs the serialization phase with Serializer s
+func (m *BaseHandler) serialize(s Serializable, out Outputable, w http.ResponseWriter, r *http.Request) {
+	r = m.before(r, PhaseSerialize)
+	err := callSerializer(s, out, w, r)
+	m.after(r, PhaseSerialize, err)
+	if err != nil && m.OnSerializeError != nil {
+		m.OnSerializeError(r, err)
+	}
 }
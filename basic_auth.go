@@ -0,0 +1,97 @@
+package resdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error for missing or wrong Basic credentials. Responds with 401
+// Unauthorized and a WWW-Authenticate challenge, so browsers prompt for
+// credentials. Wraps ErrNoCredentials if the request had none.
+type BasicAuthError struct {
+	// Realm of the challenge
+	Realm string
+	// Cause of the failure
+	Err error
+}
+
+func (e *BasicAuthError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BasicAuthError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BasicAuthError) StatusCode() int {
+	return http.StatusUnauthorized
+}
+
+func (e *BasicAuthError) Headers() http.Header {
+	return http.Header{"Www-Authenticate": {fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, e.Realm)}}
+}
+
+// Reports whether a and b are equal in time independent of their
+// contents and lengths, for comparing secrets
+func SecureCompare(a, b string) bool {
+	// Hashing first hides the length of the secret
+	ha := sha256.Sum256([]byte(a))
+	hb := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// Fixed user names and passwords checked with SecureCompare, for use as
+// BasicAuthenticator.Verify. Users with an empty password, e.g. from an
+// unset environment variable, cannot log in.
+type BasicUsers map[string]string
+
+// Returns the user name as auth details if password is the one of user
+func (b BasicUsers) Verify(ctx context.Context, user, password string) (interface{}, error) {
+	expected, ok := b[user]
+	// Compare anyway so unknown users take as long as known ones
+	if !SecureCompare(password, expected) || !ok || expected == "" {
+		return nil, nil
+	}
+	return user, nil
+}
+
+// An Authenticator for HTTP Basic authentication (RFC 7617), meant for
+// internal and admin endpoints:
+//
+//	resdk.BasicAuthenticator{
+//		Realm:  "admin",
+//		Verify: resdk.BasicUsers{"ops": os.Getenv("OPS_PASSWORD")}.Verify,
+//	}
+//
+// Failures are *BasicAuthError.
+type BasicAuthenticator struct {
+	// Realm sent in the challenge. Defaults to "restricted".
+	Realm string
+	// Required. Checks the credentials and returns the auth details,
+	// or nil auth details if they are wrong. Errors are returned as
+	// is. Implementations should compare secrets with SecureCompare.
+	Verify func(ctx context.Context, user, password string) (interface{}, error)
+}
+
+func (b BasicAuthenticator) Authenticate(r *http.Request) (interface{}, error) {
+	realm := b.Realm
+	if realm == "" {
+		realm = "restricted"
+	}
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, &BasicAuthError{Realm: realm, Err: ErrNoCredentials}
+	}
+	auth_details, err := b.Verify(r.Context(), user, password)
+	if err != nil {
+		return nil, err
+	}
+	if auth_details == nil {
+		return nil, &BasicAuthError{Realm: realm, Err: errors.New("Invalid credentials")}
+	}
+	return auth_details, nil
+}
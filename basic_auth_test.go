@@ -0,0 +1,32 @@
+package resdk
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBasicUsersVerify(t *testing.T) {
+	users := BasicUsers{"ops": "s3cret", "unset": ""}
+	tests := []struct {
+		user     string
+		password string
+		ok       bool
+	}{
+		{"ops", "s3cret", true},
+		{"ops", "wrong", false},
+		{"ops", "", false},
+		{"unset", "", false},
+		{"unset", "anything", false},
+		{"unknown", "", false},
+		{"unknown", "s3cret", false},
+	}
+	for _, test := range tests {
+		details, err := users.Verify(context.Background(), test.user, test.password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok := details != nil; ok != test.ok {
+			t.Errorf("%s/%q: got %v, want ok %v", test.user, test.password, details, test.ok)
+		}
+	}
+}
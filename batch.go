@@ -0,0 +1,201 @@
+package resdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Result of one item of a batch request
+type BatchResult struct {
+	// HTTP status code of the item
+	Status int `json:"status"`
+	// Output of the item if it succeeded
+	Body interface{} `json:"body,omitempty"`
+	// Error of the item if it failed. The error message unless the
+	// error is a json.Marshaler.
+	Error interface{} `json:"error,omitempty"`
+}
+
+// Creates the BatchResult for the output or error of an item. The
+// status is chosen the same way serializers choose it: by StatusCoder
+// or def_error for errors and 200 for outputs. A nil out without error
+// results in 404.
+func NewBatchResult(out Outputable, err error, def_error int) BatchResult {
+	if err != nil {
+		var body interface{} = err.Error()
+		if _, ok := err.(json.Marshaler); ok {
+			body = err
+		}
+		return BatchResult{Status: statusCode(err, def_error), Error: body}
+	}
+	if out == nil {
+		return BatchResult{Status: http.StatusNotFound, Error: "Not found"}
+	}
+	if out == NoContent {
+		return BatchResult{Status: http.StatusNoContent}
+	}
+	return BatchResult{Status: statusCode(out, http.StatusOK), Body: out}
+}
+
+// Outputable of a batch request, one result per item in the order of
+// the request
+type BatchResults []BatchResult
+
+// A serializer for BatchResults as a multi-status response:
+// {"results": [{"status": 200, "body": ...}, {"status": 422, "error": ...}]}
+type BatchResultSerializer struct {
+	// HTTP Status Code of the response. Defaults to 207 Multi-Status.
+	StatusCode int
+	// Formatting options
+	JsonOptions
+}
+
+// Serializes Outputable to a ResponseWriter
+func (b BatchResultSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	b.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors
+func (b BatchResultSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	status := b.StatusCode
+	if status == 0 {
+		status = http.StatusMultiStatus
+	}
+	results, ok := out.(BatchResults)
+	if !ok {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return fmt.Errorf("resdk: batch result serializer received %T", out)
+	}
+	body := map[string]interface{}{"results": results}
+	return writeMarshaled(w, out, "application/json", status, marshalFailureBody, func(buf *bytes.Buffer) error {
+		return b.JsonOptions.encode(buf, body, r)
+	})
+}
+
+// Inputable of a batch request. Items are validated one by one by
+// BatchProcessor, so an invalid item does not fail the whole batch.
+type BatchInput struct {
+	Items []Inputable
+}
+
+func (b *BatchInput) Validate() error {
+	return nil
+}
+
+// Error for a batch with more items than allowed. Responds with 413
+// Content Too Large.
+type BatchTooLargeError struct {
+	// Maximum number of items
+	Limit int
+}
+
+func (e *BatchTooLargeError) Error() string {
+	return fmt.Sprintf("batch has more than %d items", e.Limit)
+}
+
+func (e *BatchTooLargeError) StatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+// A Deserializer for a Json array of items into a *BatchInput
+type BatchDeserializer struct {
+	// Required. Returns an empty item to unmarshal into, usually a
+	// pointer to a struct.
+	New func() Inputable
+	// Maximum number of items. No limit if zero.
+	MaxItems int
+	// Maximum size of the request body. No limit if zero.
+	MaxBytes int64
+}
+
+func (b BatchDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	body, err := readBody(r, b.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	var raw []json.RawMessage
+	if err = JsonEngine.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	if b.MaxItems > 0 && len(raw) > b.MaxItems {
+		return nil, &BatchTooLargeError{Limit: b.MaxItems}
+	}
+	in := &BatchInput{Items: make([]Inputable, len(raw))}
+	for i, item := range raw {
+		in.Items[i] = b.New()
+		if err = applyDefaults(in.Items[i]); err != nil {
+			return nil, err
+		}
+		if err = JsonEngine.Unmarshal(item, in.Items[i]); err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	return in, nil
+}
+
+// A Processor for *BatchInput which validates and processes every item
+// with Processor and returns BatchResults. Failing items get a result
+// with their error instead of failing the batch.
+type BatchProcessor struct {
+	// Required. Processes a single item.
+	Processor Processable
+}
+
+func (b BatchProcessor) Process(in Inputable) (Outputable, error) {
+	return b.ProcessAuth(context.Background(), in, nil)
+}
+
+func (b BatchProcessor) ProcessAuth(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error) {
+	batch, ok := in.(*BatchInput)
+	if !ok {
+		return nil, fmt.Errorf("resdk: batch processor received %T", in)
+	}
+	results := make(BatchResults, len(batch.Items))
+	action := ActionCreate
+	if x := ExchangeFromContext(ctx); x != nil {
+		action = x.Action
+	}
+	for i, item := range batch.Items {
+		if sanitizable, ok := item.(Sanitizable); ok {
+			sanitizable.Sanitize()
+		}
+		if err := item.Validate(); err != nil {
+			results[i] = NewBatchResult(nil, err, http.StatusBadRequest)
+			continue
+		}
+		if input_authorizer := GetInputAuthorizer(item); input_authorizer != nil {
+			if err := input_authorizer.AuthorizeInput(auth_details); err != nil {
+				results[i] = NewBatchResult(nil, err, http.StatusForbidden)
+				continue
+			}
+		}
+		if authorizer, ok := item.(ActionAuthorizable); ok && action != ActionRead {
+			if err := authorizer.AuthorizeAction(action, auth_details); err != nil {
+				results[i] = NewBatchResult(nil, err, http.StatusForbidden)
+				continue
+			}
+		}
+		if external, ok := item.(ExternalValidatable); ok {
+			if err := external.ValidateExternal(ctx, auth_details); err != nil {
+				results[i] = NewBatchResult(nil, err, http.StatusUnprocessableEntity)
+				continue
+			}
+		}
+		out, err := callProcessor(ctx, b.Processor, item, auth_details)
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		if err == nil && out != nil && action == ActionRead {
+			if err = authorizeOutput(out, action, auth_details); err != nil {
+				results[i] = NewBatchResult(nil, err, http.StatusForbidden)
+				continue
+			}
+		}
+		results[i] = NewBatchResult(out, err, http.StatusInternalServerError)
+	}
+	return results, nil
+}
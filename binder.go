@@ -0,0 +1,267 @@
+package resdk
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Error for one input field whose value could not be bound
+type BindingError struct {
+	// Where the value came from, e.g. "query"
+	Source string
+	// Name of the parameter
+	Field string
+	// The offending value
+	Value string
+	Err   error
+}
+
+func (b *BindingError) Error() string {
+	return fmt.Sprintf("%s parameter %q: %v", b.Source, b.Field, b.Err)
+}
+
+func (b *BindingError) Unwrap() error {
+	return b.Err
+}
+
+// Errors of all fields which could not be bound. Responds with 400 Bad
+// Request and renders in Json as
+// {"error": "Invalid parameters", "fields": {"page": "invalid syntax"}}.
+type BindingErrors []*BindingError
+
+func (b BindingErrors) Error() string {
+	messages := make([]string, len(b))
+	for i, err := range b {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (b BindingErrors) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (b BindingErrors) Unwrap() []error {
+	errs := make([]error, len(b))
+	for i, err := range b {
+		errs[i] = err
+	}
+	return errs
+}
+
+func (b BindingErrors) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]string, len(b))
+	for _, err := range b {
+		fields[err.Field] = err.Err.Error()
+	}
+	return JsonEngine.Marshal(map[string]interface{}{
+		"error":  "Invalid parameters",
+		"fields": fields,
+	})
+}
+
+// Binds string values into the fields of the struct v points to which
+// have a struct tag named source, e.g. `query:"page"`. values returns
+// the values of a parameter and whether it is present.
+//
+// A field is left alone if its parameter is missing, so defaults set
+// with applyDefaults beforehand survive. The tag option "comma" splits
+// values of slice fields at commas, e.g. `query:"ids,comma"` for
+// ?ids=1,2,3; without it slices are bound from repeated parameters.
+// Embedded structs are bound recursively.
+//
+// Supported field types are strings, bools, integers, floats, types
+// with a registered FieldCodec (time.Time and time.Duration built in),
+// types implementing encoding.TextUnmarshaler (e.g. UUID), and pointers
+// and slices of those. The layout tag of a field is passed to its
+// FieldCodec.
+func bindValues(v interface{}, source string, values func(name string) ([]string, bool)) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("resdk: cannot bind %s parameters into %T", source, v)
+	}
+	var errs BindingErrors
+	bindStruct(rv.Elem(), source, values, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// A struct field with a tag of a binding source
+type taggedField struct {
+	reflect.StructField
+	// Value of the field
+	value reflect.Value
+	// Parameter name and options from the tag
+	name, options string
+}
+
+// Calls fn for every exported field of the struct value sv with a tag
+// named source, descending into untagged embedded structs
+func eachTaggedField(sv reflect.Value, source string, fn func(field taggedField)) {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		tag, tagged := field.Tag.Lookup(source)
+		if !tagged && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			eachTaggedField(sv.Field(i), source, fn)
+			continue
+		}
+		if !tagged || !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fn(taggedField{StructField: field, value: sv.Field(i), name: name, options: options})
+	}
+}
+
+// Binds the fields of the struct value sv, see bindValues
+func bindStruct(sv reflect.Value, source string, values func(name string) ([]string, bool), errs *BindingErrors) {
+	eachTaggedField(sv, source, func(field taggedField) {
+		vals, ok := values(field.name)
+		if !ok || len(vals) == 0 {
+			return
+		}
+		if field.options == "comma" {
+			var split []string
+			for _, val := range vals {
+				split = append(split, strings.Split(val, ",")...)
+			}
+			vals = split
+		}
+		if value, err := setField(field.value, vals, field.Tag.Get("layout")); err != nil {
+			*errs = append(*errs, &BindingError{Source: source, Field: field.name, Value: value, Err: err})
+		}
+	})
+}
+
+// Sets every field of the struct v points to which has a
+// `default:"..."` tag to its default, parsed like a parameter value
+// (slices split at commas). Nested structs are handled recursively.
+// Deserializers apply defaults to the new Inputable before decoding or
+// binding, so fields missing from the request keep their default while
+// fields present, even with a zero value, overwrite it.
+func applyDefaults(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return defaultStruct(rv.Elem())
+}
+
+func defaultStruct(sv reflect.Value) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		def, ok := field.Tag.Lookup("default")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct {
+				if err := defaultStruct(sv.Field(i)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		vals := []string{def}
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() != reflect.Uint8 {
+			vals = strings.Split(def, ",")
+		}
+		if _, err := setField(sv.Field(i), vals, field.Tag.Get("layout")); err != nil {
+			return fmt.Errorf("resdk: invalid default of field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Sets fv from vals, using layout for field codecs. Returns the
+// offending value on error.
+func setField(fv reflect.Value, vals []string, layout string) (string, error) {
+	ft := fv.Type()
+	if ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 && !reflect.PointerTo(ft).Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(ft, len(vals), len(vals))
+		for i, val := range vals {
+			if value, err := setField(slice.Index(i), []string{val}, layout); err != nil {
+				return value, err
+			}
+		}
+		fv.Set(slice)
+		return "", nil
+	}
+	// Single valued field: the last value wins
+	val := vals[len(vals)-1]
+	return val, setScalar(fv, val, layout)
+}
+
+// Parses val into fv
+func setScalar(fv reflect.Value, val string, layout string) error {
+	ft := fv.Type()
+	if ft.Kind() == reflect.Pointer {
+		ptr := reflect.New(ft.Elem())
+		if err := setScalar(ptr.Elem(), val, layout); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+	if codec := fieldCodec(ft); codec != nil {
+		v, err := codec(val, layout)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(v).Convert(ft))
+		return nil
+	}
+	if reflect.PointerTo(ft).Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+	switch ft.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Slice:
+		// []byte, others are handled by setField
+		fv.SetBytes([]byte(val))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("invalid boolean")
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, ft.Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer")
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, ft.Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer")
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, ft.Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number")
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", ft)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package resdk
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error for a request body larger than the limit of a Deserializer.
+// Responds with 413 Content Too Large and unwraps to an
+// *http.MaxBytesError.
+type BodyTooLargeError struct {
+	// Limit in bytes
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("request body larger than %d bytes", e.Limit)
+}
+
+func (e *BodyTooLargeError) StatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+func (e *BodyTooLargeError) Unwrap() error {
+	return &http.MaxBytesError{Limit: e.Limit}
+}
+
+// Reads the complete body of r. Returns a *BodyTooLargeError if the
+// body is larger than max_bytes. No limit if max_bytes is not positive.
+func readBody(r *http.Request, max_bytes int64) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body := io.Reader(r.Body)
+	if max_bytes > 0 {
+		body = io.LimitReader(body, max_bytes+1)
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if max_bytes > 0 && int64(len(b)) > max_bytes {
+		return nil, &BodyTooLargeError{Limit: max_bytes}
+	}
+	return b, nil
+}
+
+// Limits the body of r to max_bytes for parsers reading it directly.
+// No limit if max_bytes is not positive.
+func limitBody(r *http.Request, max_bytes int64) {
+	if max_bytes > 0 && r.Body != nil {
+		r.Body = http.MaxBytesReader(nil, r.Body, max_bytes)
+	}
+}
+
+// Converts an *http.MaxBytesError of a limited body into a
+// *BodyTooLargeError
+func bodyError(err error) error {
+	var max_bytes_err *http.MaxBytesError
+	if errors.As(err, &max_bytes_err) {
+		return &BodyTooLargeError{Limit: max_bytes_err.Limit}
+	}
+	return err
+}
+
+// Error for a request body in a Content-Encoding which cannot be
+// decompressed. Responds with 415 Unsupported Media Type and an
+// Accept-Encoding header listing the supported encodings.
+type UnsupportedEncodingError struct {
+	// Content-Encoding of the request
+	Encoding string
+}
+
+func (e *UnsupportedEncodingError) Error() string {
+	return fmt.Sprintf("unsupported content encoding %q", e.Encoding)
+}
+
+func (e *UnsupportedEncodingError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+func (e *UnsupportedEncodingError) Headers() http.Header {
+	return http.Header{"Accept-Encoding": {"gzip"}}
+}
+
+// Applies MaxBodyBytes and DecompressBody to the body of r
+func (m *BaseHandler) prepareBody(r *http.Request) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	if m.DecompressBody {
+		switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				return err
+			}
+			r.Body = &decompressedBody{Reader: zr, zr: zr, body: r.Body}
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+		default:
+			return &UnsupportedEncodingError{Encoding: encoding}
+		}
+	}
+	if m.MaxBodyBytes > 0 {
+		if r.ContentLength > m.MaxBodyBytes {
+			return &BodyTooLargeError{Limit: m.MaxBodyBytes}
+		}
+		r.Body = &limitedBody{body: r.Body, remaining: m.MaxBodyBytes, limit: m.MaxBodyBytes}
+	}
+	return nil
+}
+
+// A gzip decompressed request body closing the original body
+type decompressedBody struct {
+	io.Reader
+	zr   *gzip.Reader
+	body io.ReadCloser
+}
+
+func (d *decompressedBody) Close() error {
+	return errors.Join(d.zr.Close(), d.body.Close())
+}
+
+// A request body failing with a *BodyTooLargeError once more than
+// limit bytes were read
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+	limit     int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, &BodyTooLargeError{Limit: l.limit}
+	}
+	// Read one byte more than allowed to detect larger bodies
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.body.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), &BodyTooLargeError{Limit: l.limit}
+	}
+	return n, err
+}
+
+func (l *limitedBody) Close() error {
+	return l.body.Close()
+}
@@ -0,0 +1,166 @@
+package resdk
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Fluent builder for handlers which validates that all required phases
+// are set. Use NewHandler to create one:
+//
+//	h, err := resdk.NewHandler().
+//		WithAuth(auth).
+//		WithDeserializer(d).
+//		WithProcessor(p).
+//		Json()
+type HandlerBuilder struct {
+	base BaseHandler
+}
+
+// Creates an empty HandlerBuilder
+func NewHandler() *HandlerBuilder {
+	return &HandlerBuilder{}
+}
+
+// Sets the Authenticator
+func (b *HandlerBuilder) WithAuth(a Authenticatable) *HandlerBuilder {
+	b.base.Authenticator = a
+	return b
+}
+
+// Sets the scopes required for every request
+func (b *HandlerBuilder) WithScopes(scopes ...string) *HandlerBuilder {
+	b.base.RequiredScopes = scopes
+	return b
+}
+
+// Sets the Deserializer
+func (b *HandlerBuilder) WithDeserializer(d Deserializable) *HandlerBuilder {
+	b.base.Deserializer = d
+	return b
+}
+
+// Sets the Processor
+func (b *HandlerBuilder) WithProcessor(p Processable) *HandlerBuilder {
+	b.base.Processor = p
+	return b
+}
+
+// Sets the SuccessSerializer
+func (b *HandlerBuilder) WithSerializer(s Serializable) *HandlerBuilder {
+	b.base.SuccessSerializer = s
+	return b
+}
+
+// Sets the AuthenticationErrorSerializer
+func (b *HandlerBuilder) WithAuthenticationErrorSerializer(s Serializable) *HandlerBuilder {
+	b.base.AuthenticationErrorSerializer = s
+	return b
+}
+
+// Sets the DeserializationErrorSerializer
+func (b *HandlerBuilder) WithDeserializationErrorSerializer(s Serializable) *HandlerBuilder {
+	b.base.DeserializationErrorSerializer = s
+	return b
+}
+
+// Sets the ValidationErrorSerializer
+func (b *HandlerBuilder) WithValidationErrorSerializer(s Serializable) *HandlerBuilder {
+	b.base.ValidationErrorSerializer = s
+	return b
+}
+
+// Sets the ExternalValidationErrorSerializer
+func (b *HandlerBuilder) WithExternalValidationErrorSerializer(s Serializable) *HandlerBuilder {
+	b.base.ExternalValidationErrorSerializer = s
+	return b
+}
+
+// Sets the ProcessingErrorSerializer
+func (b *HandlerBuilder) WithProcessingErrorSerializer(s Serializable) *HandlerBuilder {
+	b.base.ProcessingErrorSerializer = s
+	return b
+}
+
+// Sets the NotFoundSerializer
+func (b *HandlerBuilder) WithNotFoundSerializer(s Serializable) *HandlerBuilder {
+	b.base.NotFoundSerializer = s
+	return b
+}
+
+// Sets the AuthorizationErrorSerializer
+func (b *HandlerBuilder) WithAuthorizationErrorSerializer(s Serializable) *HandlerBuilder {
+	b.base.AuthorizationErrorSerializer = s
+	return b
+}
+
+// Sets the RecoverySerializer and the optional OnPanic callback
+func (b *HandlerBuilder) WithRecovery(s Serializable, on_panic func(r *http.Request, perr *PanicError)) *HandlerBuilder {
+	b.base.RecoverySerializer = s
+	b.base.OnPanic = on_panic
+	return b
+}
+
+// Appends Interceptors
+func (b *HandlerBuilder) WithInterceptors(i ...Interceptor) *HandlerBuilder {
+	b.base.Interceptors = append(b.base.Interceptors, i...)
+	return b
+}
+
+// Builds a BaseHandler. Returns an error if a required phase or
+// serializer is missing.
+func (b *HandlerBuilder) Build() (*BaseHandler, error) {
+	h := b.base
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// Builds a JsonHandler. Serializers which were not set get the
+// JsonHandler defaults. Returns an error if a required phase is
+// missing.
+func (b *HandlerBuilder) Json() (*JsonHandler, error) {
+	h := NewJsonHandler(b.base)
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// Builds an XmlHandler. Serializers which were not set get the
+// XmlHandler defaults. Returns an error if a required phase is
+// missing.
+func (b *HandlerBuilder) Xml() (*XmlHandler, error) {
+	h := NewXmlHandler(b.base)
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// Returns an error listing the required fields which are not set
+func (m *BaseHandler) validate() error {
+	var missing []string
+	require := func(set bool, name string) {
+		if !set {
+			missing = append(missing, name)
+		}
+	}
+	require(m.Deserializer != nil, "Deserializer")
+	require(m.Processor != nil, "Processor")
+	require(m.SuccessSerializer != nil, "SuccessSerializer")
+	if m.Authenticator != nil {
+		require(m.AuthenticationErrorSerializer != nil, "AuthenticationErrorSerializer")
+	}
+	require(m.DeserializationErrorSerializer != nil, "DeserializationErrorSerializer")
+	require(m.ValidationErrorSerializer != nil, "ValidationErrorSerializer")
+	require(m.ProcessingErrorSerializer != nil, "ProcessingErrorSerializer")
+	require(m.NotFoundSerializer != nil, "NotFoundSerializer")
+	require(m.AuthorizationErrorSerializer != nil, "AuthorizationErrorSerializer")
+	if len(missing) > 0 {
+		return fmt.Errorf("resdk: handler is missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
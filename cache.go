@@ -0,0 +1,66 @@
+package resdk
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Set of functions which can be optionally implemented by an
+// Outputable to make its response cacheable. Serializers emit
+// Cache-Control, Expires and Last-Modified headers for it and handlers
+// respond to GET and HEAD requests with 304 Not Modified if
+// If-Modified-Since shows the client copy is still fresh.
+type Cacheable interface {
+	// Returns how long the response may be cached. 0 results in
+	// Cache-Control: no-cache, a negative duration in no-store.
+	MaxAge() time.Duration
+	// Returns when the resource was last modified or the zero Time if
+	// unknown
+	LastModified() time.Time
+}
+
+// Sets the caching headers of out on header if it is Cacheable
+func setCacheHeaders(header http.Header, out Outputable) {
+	c, ok := out.(Cacheable)
+	if !ok {
+		return
+	}
+	switch max_age := c.MaxAge(); {
+	case max_age > 0:
+		header.Set("Cache-Control", "max-age="+strconv.FormatInt(int64(max_age/time.Second), 10))
+		header.Set("Expires", time.Now().Add(max_age).UTC().Format(http.TimeFormat))
+	case max_age == 0:
+		header.Set("Cache-Control", "no-cache")
+	default:
+		header.Set("Cache-Control", "no-store")
+	}
+	if modified := c.LastModified(); !modified.IsZero() {
+		header.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// Responds with 304 Not Modified if out is Cacheable and was not
+// modified since the If-Modified-Since time of a GET or HEAD request.
+// Reports whether it did.
+func writeNotModifiedSince(w http.ResponseWriter, r *http.Request, out Outputable) bool {
+	c, ok := out.(Cacheable)
+	if !ok || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+	// If-None-Match takes precedence (RFC 9110 section 13.1.3)
+	if r.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	if status := statusCode(out, http.StatusOK); status < 200 || status >= 300 {
+		return false
+	}
+	modified := c.LastModified()
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if modified.IsZero() || err != nil || modified.Truncate(time.Second).After(since) {
+		return false
+	}
+	setCacheHeaders(w.Header(), out)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
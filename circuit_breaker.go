@@ -0,0 +1,210 @@
+package resdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Error for a request rejected by an open CircuitBreakerProcessor.
+// Responds with 503 Service Unavailable.
+var ErrCircuitOpen error = circuitOpenError{}
+
+type circuitOpenError struct{}
+
+func (circuitOpenError) Error() string {
+	return "Service unavailable"
+}
+
+func (circuitOpenError) StatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+// State of a CircuitBreakerProcessor
+type CircuitState int
+
+const (
+	// Requests reach the Processor
+	CircuitClosed CircuitState = iota
+	// Requests are rejected without calling the Processor
+	CircuitOpen
+	// A single trial request reaches the Processor to probe whether
+	// it recovered
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// A Processor wrapping another one with a circuit breaker, so a failing
+// downstream dependency fails requests fast instead of letting every
+// one of them wait for it. Use NewCircuitBreakerProcessor to create
+// one.
+//
+// After FailureThreshold consecutive failures the circuit opens and
+// requests get Fallback or ErrCircuitOpen. After OpenTimeout a single
+// trial request is let through; its success closes the circuit, its
+// failure opens it again. Set Timeout so slow calls count as failures
+// too; BaseHandler.ProcessTimeout does not reach the breaker, which
+// only sees the result of the wrapped Processor.
+type CircuitBreakerProcessor struct {
+	// Consecutive failures opening the circuit. Defaults to 5.
+	FailureThreshold int
+	// How long the circuit stays open. Defaults to 30 seconds.
+	OpenTimeout time.Duration
+	// Deadline of the context passed to the wrapped Processor. Calls
+	// taking longer count as failures, whatever they return. No
+	// deadline if zero.
+	Timeout time.Duration
+	// Reports whether err counts as a failure. Defaults to errors
+	// other than client errors (StatusCoder below 500) and
+	// cancellations by the client.
+	IsFailure func(err error) bool
+	// Returned instead of ErrCircuitOpen while the circuit is open,
+	// e.g. cached or degraded content
+	Fallback Outputable
+	// Optional callback invoked when the state changes, e.g. for
+	// alerting. It must not block.
+	OnStateChange func(from, to CircuitState)
+
+	processor Processable
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	// Whether the trial request of the half-open state is running
+	probing bool
+}
+
+// Creates a closed CircuitBreakerProcessor around p
+func NewCircuitBreakerProcessor(p Processable) *CircuitBreakerProcessor {
+	return &CircuitBreakerProcessor{processor: p}
+}
+
+// Returns the current state
+func (c *CircuitBreakerProcessor) State() CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+func (c *CircuitBreakerProcessor) Process(in Inputable) (Outputable, error) {
+	return c.ProcessAuth(context.Background(), in, nil)
+}
+
+func (c *CircuitBreakerProcessor) ProcessAuth(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error) {
+	probe, ok := c.acquire()
+	if !ok {
+		if c.Fallback != nil {
+			return c.Fallback, nil
+		}
+		return nil, ErrCircuitOpen
+	}
+	// A panic counts as a failure and keeps propagating
+	failed := true
+	defer func() {
+		c.release(probe, failed)
+	}()
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	out, err := callProcessor(ctx, c.processor, in, auth_details)
+	slow := c.Timeout > 0 && time.Since(start) >= c.Timeout
+	failed = slow || err != nil && c.isFailure(err)
+	return out, err
+}
+
+// Reports whether a request may reach the Processor and whether it is
+// the trial request of the half-open state
+func (c *CircuitBreakerProcessor) acquire() (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.state {
+	case CircuitClosed:
+		return false, true
+	case CircuitOpen:
+		timeout := c.OpenTimeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		if time.Since(c.openedAt) < timeout {
+			return false, false
+		}
+		c.setState(CircuitHalfOpen)
+	}
+	if c.probing {
+		return false, false
+	}
+	c.probing = true
+	return true, true
+}
+
+// Records the outcome of a request let through by acquire
+func (c *CircuitBreakerProcessor) release(probe, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if probe {
+		c.probing = false
+	} else if c.state != CircuitClosed {
+		// Started before the circuit opened
+		return
+	}
+	if !failed {
+		c.failures = 0
+		c.setState(CircuitClosed)
+		return
+	}
+	c.failures++
+	threshold := c.FailureThreshold
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if probe || c.failures >= threshold {
+		c.openedAt = time.Now()
+		c.setState(CircuitOpen)
+	}
+}
+
+// Switches to state. c.mu must be held.
+func (c *CircuitBreakerProcessor) setState(state CircuitState) {
+	if state == c.state {
+		return
+	}
+	from := c.state
+	c.state = state
+	if state == CircuitClosed {
+		c.failures = 0
+	}
+	if c.OnStateChange != nil {
+		c.OnStateChange(from, state)
+	}
+}
+
+// Applies IsFailure or the default described there
+func (c *CircuitBreakerProcessor) isFailure(err error) bool {
+	if c.IsFailure != nil {
+		return c.IsFailure(err)
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var coder StatusCoder
+	if errors.As(err, &coder) {
+		return coder.StatusCode() >= 500
+	}
+	return true
+}
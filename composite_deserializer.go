@@ -0,0 +1,117 @@
+package resdk
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+// A Deserializer filling one Inputable from several parts of the
+// request, chosen per field by struct tag:
+//
+//	type UpdateUser struct {
+//		ID        int    `path:"id"`
+//		DryRun    bool   `query:"dry_run"`
+//		RequestID string `header:"X-Request-ID"`
+//		Name      string `json:"name"`
+//	}
+//
+// The Json body (if not empty) is decoded first, then header, query and
+// path parameters are bound in this order, so for fields with several
+// tags the later source takes precedence: path over query over header
+// over body. Fields tagged for parameters only (without a json tag)
+// cannot be set by the body. Binding errors of all parameter sources
+// are reported together as BindingErrors.
+type CompositeDeserializer struct {
+	// Required. Returns an empty Inputable to fill, a pointer to a
+	// struct.
+	New func() Inputable
+	// Maximum size of the request body. Larger bodies fail with a
+	// *BodyTooLargeError. No limit if zero.
+	MaxBytes int64
+	// Fail on body object members which do not match a field
+	DisallowUnknownFields bool
+	// Reads path parameters from the router. Defaults to
+	// StdPathParam.
+	Param PathParamFunc
+}
+
+func (c CompositeDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	in := c.New()
+	if err := applyDefaults(in); err != nil {
+		return nil, err
+	}
+	b, err := readBody(r, c.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(b)) > 0 {
+		dec := JsonEngine.NewDecoder(bytes.NewReader(b))
+		if c.DisallowUnknownFields {
+			dec.DisallowUnknownFields()
+		}
+		restore := holdParamFields(in)
+		err = dec.Decode(in)
+		restore()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var errs BindingErrors
+	collect := func(err error) error {
+		var binding_errs BindingErrors
+		if errors.As(err, &binding_errs) {
+			errs = append(errs, binding_errs...)
+			return nil
+		}
+		return err
+	}
+	header_err := bindValues(in, "header", func(name string) ([]string, bool) {
+		values := r.Header.Values(name)
+		return values, len(values) > 0
+	})
+	if err = collect(header_err); err != nil {
+		return nil, err
+	}
+	if err = collect(bindQuery(r, in)); err != nil {
+		return nil, err
+	}
+	if err = collect(PathBinder{Param: c.Param}.Bind(r, in)); err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return in, nil
+}
+
+// Zeroes the fields of in tagged for header, query or path parameters
+// but not for Json, and returns a func restoring their values, so the
+// body cannot set them. Zeroing keeps the decoder from writing into
+// saved slices and maps.
+func holdParamFields(in Inputable) func() {
+	rv := reflect.ValueOf(in)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return func() {}
+	}
+	var fields, saved []reflect.Value
+	for _, source := range []string{"header", "query", "path"} {
+		eachTaggedField(rv.Elem(), source, func(field taggedField) {
+			if _, body := field.Tag.Lookup("json"); body {
+				return
+			}
+			value := reflect.New(field.Type).Elem()
+			value.Set(field.value)
+			field.value.SetZero()
+			fields = append(fields, field.value)
+			saved = append(saved, value)
+		})
+	}
+	return func() {
+		for i, field := range fields {
+			field.Set(saved[i])
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package resdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type compositeInput struct {
+	ID     int    `path:"id"`
+	DryRun bool   `query:"dry_run"`
+	Tenant string `header:"X-Tenant" default:"public"`
+	Name   string `json:"name"`
+}
+
+func (*compositeInput) Validate() error {
+	return nil
+}
+
+func TestCompositeDeserializerIgnoresParamFieldsInBody(t *testing.T) {
+	body := `{"ID": 7, "DryRun": true, "Tenant": "other", "name": "bob"}`
+	r := httptest.NewRequest(http.MethodPut, "/users/42", strings.NewReader(body))
+	r.SetPathValue("id", "42")
+	in, err := CompositeDeserializer{New: func() Inputable { return &compositeInput{} }}.Deserialize(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := compositeInput{ID: 42, Tenant: "public", Name: "bob"}
+	if got := *in.(*compositeInput); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
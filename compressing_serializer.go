@@ -0,0 +1,142 @@
+package resdk
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// A content coding offered by CompressingSerializer
+type ContentEncoder struct {
+	// Name of the coding in Accept-Encoding and Content-Encoding,
+	// e.g. "gzip" or "br"
+	Name string
+	// Returns a writer compressing into w
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+}
+
+// Gzip content coding
+var GzipEncoder = ContentEncoder{
+	Name: "gzip",
+	NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	},
+}
+
+// Deflate content coding
+var DeflateEncoder = ContentEncoder{
+	Name: "deflate",
+	NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flate.DefaultCompression)
+	},
+}
+
+// A serializer compressing the response of another Serializer with a
+// content coding negotiated from the Accept-Encoding header. Responses
+// smaller than MinSize, without a body or already encoded are sent
+// as is. Vary: Accept-Encoding is always set.
+//
+// Brotli (or any other coding) can be plugged in through Encoders, e.g.
+// with github.com/andybalholm/brotli:
+//
+//	resdk.ContentEncoder{Name: "br", NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+//		return brotli.NewWriter(w), nil
+//	}}
+type CompressingSerializer struct {
+	// Required. Serializer producing the response.
+	Serializer Serializable
+	// Minimum body size in bytes for compression. Defaults to 1024.
+	MinSize int
+	// Codings in order of server preference, which breaks ties
+	// between equally acceptable codings. Defaults to gzip and
+	// deflate.
+	Encoders []ContentEncoder
+}
+
+// Serializes Outputable to a ResponseWriter
+func (c CompressingSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	c.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns the errors of the wrapped Serializer,
+// compression and writing
+func (c CompressingSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	buf := newResponseBuffer()
+	serr := callSerializer(c.Serializer, out, buf, r)
+	buf.header.Add("Vary", "Accept-Encoding")
+
+	body := buf.body.Bytes()
+	min_size := c.MinSize
+	if min_size <= 0 {
+		min_size = 1024
+	}
+	encoder := c.encoder(r)
+	if encoder == nil || len(body) < min_size || !bodyAllowed(buf.statusCode()) || buf.header.Get("Content-Encoding") != "" {
+		return errors.Join(serr, buf.sendTo(w, body))
+	}
+
+	var compressed bytes.Buffer
+	zw, err := encoder.NewWriter(&compressed)
+	if err == nil {
+		_, err = zw.Write(body)
+		err = errors.Join(err, zw.Close())
+	}
+	if err != nil {
+		// Fall back to the uncompressed response
+		return errors.Join(serr, err, buf.sendTo(w, body))
+	}
+	buf.header.Set("Content-Encoding", encoder.Name)
+	buf.header.Del("Content-Length")
+	return errors.Join(serr, buf.sendTo(w, compressed.Bytes()))
+}
+
+// Returns the coding to use for r or nil if the response should not be
+// compressed
+func (c CompressingSerializer) encoder(r *http.Request) *ContentEncoder {
+	encoders := c.Encoders
+	if encoders == nil {
+		encoders = []ContentEncoder{GzipEncoder, DeflateEncoder}
+	}
+	accepted := parseAcceptEncoding(r.Header.Values("Accept-Encoding"))
+	var best *ContentEncoder
+	best_q := 0.0
+	for i := range encoders {
+		q, ok := accepted[encoders[i].Name]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > best_q {
+			best, best_q = &encoders[i], q
+		}
+	}
+	return best
+}
+
+// Parses Accept-Encoding header values into coding -> quality
+func parseAcceptEncoding(values []string) map[string]float64 {
+	accepted := make(map[string]float64)
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding == "" {
+				continue
+			}
+			q := 1.0
+			if name, qs, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(qs), 64)
+				if err != nil {
+					continue
+				}
+				q = parsed
+			}
+			accepted[coding] = q
+		}
+	}
+	return accepted
+}
@@ -0,0 +1,78 @@
+package resdk
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Error for a request which found no free Processor slot within the
+// queue timeout of a ConcurrencyLimiter. Responds with 503 Service
+// Unavailable.
+var ErrOverloaded error = overloadedError{}
+
+type overloadedError struct{}
+
+func (overloadedError) Error() string {
+	return "Server overloaded"
+}
+
+func (overloadedError) StatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+// Bounds the number of simultaneous Process invocations, protecting
+// scarce resources such as database connection pools from stampedes.
+// Use NewConcurrencyLimiter to create one and set it as
+// BaseHandler.MaxConcurrent. Handlers sharing a ConcurrencyLimiter
+// share its bound.
+//
+// Requests beyond the bound wait up to the queue timeout for a slot and
+// then fail with ErrOverloaded, sent through ProcessingErrorSerializer.
+type ConcurrencyLimiter struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+// Creates a ConcurrencyLimiter allowing max simultaneous invocations.
+// Requests wait up to queue_timeout for a slot; zero rejects them
+// right away.
+func NewConcurrencyLimiter(max int, queue_timeout time.Duration) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		slots:        make(chan struct{}, max),
+		queueTimeout: queue_timeout,
+	}
+}
+
+// Returns the number of invocations running
+func (l *ConcurrencyLimiter) InFlight() int {
+	return len(l.slots)
+}
+
+// Waits for a free slot. Fails with ErrOverloaded after the queue
+// timeout or with the error of ctx if it is done first.
+func (l *ConcurrencyLimiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if l.queueTimeout <= 0 {
+		return ErrOverloaded
+	}
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrOverloaded
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Frees the slot taken by acquire
+func (l *ConcurrencyLimiter) release() {
+	<-l.slots
+}
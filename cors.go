@@ -0,0 +1,116 @@
+package resdk
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cross-Origin Resource Sharing configuration of a BaseHandler (and the
+// routes of a MethodHandler), so browser clients of other origins need
+// no external middleware. Preflight requests are answered with 204 No
+// Content before authentication; actual requests get the CORS headers
+// on their response, errors included.
+//
+//	Cors: &resdk.CorsConfig{
+//		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.com"},
+//		AllowCredentials: true,
+//		MaxAge:           time.Hour,
+//	}
+type CorsConfig struct {
+	// Origins allowed, e.g. "https://app.example.com". "*" allows
+	// any origin, though not with AllowCredentials, and a "*." after
+	// the scheme any subdomain.
+	AllowedOrigins []string
+	// Optional. Decides about origins not in AllowedOrigins.
+	AllowOriginFunc func(origin string) bool
+	// Methods allowed in preflights. Defaults to GET, HEAD, POST, PUT,
+	// PATCH and DELETE.
+	AllowedMethods []string
+	// Request headers allowed in preflights. Any requested header is
+	// allowed if empty.
+	AllowedHeaders []string
+	// Response headers scripts may read beyond the safelisted ones,
+	// e.g. "ETag" or "Link"
+	ExposedHeaders []string
+	// Allow cookies and Authorization headers. The origin is echoed
+	// instead of "*" then, as browsers require.
+	AllowCredentials bool
+	// How long browsers may cache preflight results. Not sent if zero.
+	MaxAge time.Duration
+}
+
+// Reports whether origin is allowed
+func (c *CorsConfig) allowed(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			// Echoing any origin with credentials would let every
+			// site read responses on behalf of the user
+			if !c.AllowCredentials {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if scheme, domain, ok := strings.Cut(allowed, "://*."); ok {
+			host, found := strings.CutPrefix(strings.ToLower(origin), strings.ToLower(scheme)+"://")
+			if found && strings.HasSuffix(host, "."+strings.ToLower(domain)) {
+				return true
+			}
+		}
+	}
+	return c.AllowOriginFunc != nil && c.AllowOriginFunc(origin)
+}
+
+// Sets the CORS headers for r on w. Answers preflight requests and
+// returns true for them, in which case nothing else must be written.
+func (c *CorsConfig) handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && origin != "" && r.Header.Get("Access-Control-Request-Method") != ""
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	if preflight {
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+	}
+	if origin == "" || !c.allowed(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return preflight
+	}
+
+	if containsString(c.AllowedOrigins, "*") && !c.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if len(c.ExposedHeaders) > 0 {
+			header.Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+		}
+		return false
+	}
+
+	methods := c.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(c.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(c.AllowedHeaders, ", "))
+	} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+	}
+	if c.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.FormatInt(int64(c.MaxAge/time.Second), 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
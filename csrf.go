@@ -0,0 +1,134 @@
+package resdk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Error for an unsafe request without a matching CSRF token. Responds
+// with 403 Forbidden.
+var ErrCsrfToken error = csrfTokenError{}
+
+type csrfTokenError struct{}
+
+func (csrfTokenError) Error() string {
+	return "Invalid CSRF token"
+}
+
+func (csrfTokenError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+// Protects handlers with cookie based authentication, such as
+// SessionAuthenticator, against cross site request forgery using the
+// double submit cookie pattern. It wraps the Authenticator, so the
+// check runs before deserialization:
+//
+//	resdk.BaseHandler{
+//		Authenticator: resdk.CsrfProtector{Next: sessions, Key: csrf_key},
+//		...
+//	}
+//
+// Safe requests (GET, HEAD, OPTIONS) pass and receive a token cookie
+// if they have none. Other requests must echo the cookie value in the
+// header, which scripts of other sites cannot read, or fail with
+// ErrCsrfToken. With Key the token is also bound to the session cookie,
+// so a token planted by a sibling subdomain is useless; the next safe
+// request after sign in receives a fresh one.
+type CsrfProtector struct {
+	// Required. Authenticates the request after the check passed.
+	Next Authenticatable
+	// Secret binding tokens to the session cookie, at least 32
+	// random bytes. Plain double submit if nil.
+	Key []byte
+	// Session cookie tokens are bound to. Defaults to "session".
+	SessionCookie string
+	// Token cookie, readable by scripts. Defaults to "csrf_token".
+	CookieName string
+	// Header carrying the token. Defaults to "X-CSRF-Token".
+	HeaderName string
+	// Cookie attributes. Path defaults to "/", SameSite to Lax.
+	Path     string
+	Domain   string
+	SameSite http.SameSite
+	// Send the cookie over plain HTTP too. Only for development.
+	Insecure bool
+}
+
+func (c CsrfProtector) Authenticate(r *http.Request) (interface{}, error) {
+	cookie, err := r.Cookie(orDefault(c.CookieName, "csrf_token"))
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		if err != nil || !c.valid(r, cookie.Value) {
+			if _, err = c.Issue(r.Context()); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		header := r.Header.Get(orDefault(c.HeaderName, "X-CSRF-Token"))
+		if err != nil || header == "" || !hmac.Equal([]byte(header), []byte(cookie.Value)) || !c.valid(r, cookie.Value) {
+			return nil, ErrCsrfToken
+		}
+	}
+	return c.Next.Authenticate(r)
+}
+
+// Creates a new token, sets its cookie on the response and returns it,
+// e.g. for embedding into a page. ctx must be the context of a request
+// served by a BaseHandler.
+func (c CsrfProtector) Issue(ctx context.Context) (string, error) {
+	x := ExchangeFromContext(ctx)
+	if x == nil {
+		return "", errors.New("resdk: no exchange in context")
+	}
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(nonce)
+	if c.Key != nil {
+		token += "." + c.sign(x.Request, token)
+	}
+	same_site := c.SameSite
+	if same_site == 0 {
+		same_site = http.SameSiteLaxMode
+	}
+	http.SetCookie(x.Writer, &http.Cookie{
+		Name:     orDefault(c.CookieName, "csrf_token"),
+		Value:    token,
+		Path:     orDefault(c.Path, "/"),
+		Domain:   c.Domain,
+		Secure:   !c.Insecure,
+		SameSite: same_site,
+	})
+	return token, nil
+}
+
+// Reports whether token is bound to the session of r. All tokens are
+// valid without Key.
+func (c CsrfProtector) valid(r *http.Request, token string) bool {
+	if c.Key == nil {
+		return token != ""
+	}
+	nonce, mac, ok := strings.Cut(token, ".")
+	return ok && hmac.Equal([]byte(mac), []byte(c.sign(r, nonce)))
+}
+
+// Returns the signature of nonce for the session of r
+func (c CsrfProtector) sign(r *http.Request, nonce string) string {
+	session := ""
+	if cookie, err := r.Cookie(orDefault(c.SessionCookie, "session")); err == nil {
+		session = cookie.Value
+	}
+	mac := hmac.New(sha256.New, c.Key)
+	mac.Write([]byte(session))
+	mac.Write([]byte{0})
+	mac.Write([]byte(nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,188 @@
+package resdk
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Set of functions which can be implemented by an Outputable to control
+// how CsvSerializer renders it
+type CsvTable interface {
+	// Returns the header row. Return nil to omit it.
+	CsvHeader() []string
+	// Returns the data rows
+	CsvRows() [][]string
+}
+
+// A serializer for response in csv, e.g. for export endpoints. out may
+// be a CsvTable, a slice of structs (or pointers to structs) or a
+// single struct.
+//
+// Struct columns are named after the csv tag of each exported field,
+// falling back to the json tag and then the field name. Fields tagged
+// csv:"-" are skipped. Times are written in RFC 3339, values
+// implementing encoding.TextMarshaler or fmt.Stringer use those.
+type CsvSerializer struct {
+	// HTTP Status Code to be returned unless out implements
+	// StatusCoder
+	StatusCode int
+	// If set the response is sent as an attachment with this file
+	// name (Content-Disposition header)
+	Filename string
+	// Field delimiter. Defaults to ','.
+	Comma rune
+	// Omit the header row of struct slices
+	NoHeader bool
+}
+
+// Serializes Outputable to a ResponseWriter
+func (c CsvSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	c.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors. Responds
+// with a 500 error if out cannot be rendered as csv.
+func (c CsvSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	if c.Filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": c.Filename}))
+	}
+	return writeMarshaled(w, out, "text/csv; charset=utf-8", c.StatusCode, []byte("Internal server error\n"), func(buf *bytes.Buffer) error {
+		header, rows, err := c.table(out)
+		if err != nil {
+			return err
+		}
+		cw := csv.NewWriter(buf)
+		if c.Comma != 0 {
+			cw.Comma = c.Comma
+		}
+		if header != nil {
+			cw.Write(header)
+		}
+		cw.WriteAll(rows)
+		return cw.Error()
+	})
+}
+
+// Returns the header and rows of out
+func (c CsvSerializer) table(out Outputable) ([]string, [][]string, error) {
+	if table, ok := out.(CsvTable); ok {
+		return table.CsvHeader(), table.CsvRows(), nil
+	}
+
+	v := reflect.ValueOf(out)
+	var items []reflect.Value
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, v.Index(i))
+		}
+	default:
+		items = []reflect.Value{v}
+	}
+
+	elem := reflect.TypeOf(out)
+	if elem.Kind() == reflect.Slice || elem.Kind() == reflect.Array {
+		elem = elem.Elem()
+	}
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("resdk: cannot render %T as csv", out)
+	}
+
+	columns := csvColumns(elem)
+	var header []string
+	if !c.NoHeader {
+		header = make([]string, len(columns))
+		for i, col := range columns {
+			header[i] = col.name
+		}
+	}
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		for item.Kind() == reflect.Pointer || item.Kind() == reflect.Interface {
+			item = item.Elem()
+		}
+		row := make([]string, len(columns))
+		if item.IsValid() {
+			for i, col := range columns {
+				row[i] = csvValue(item.Field(col.index))
+			}
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+type csvColumn struct {
+	name  string
+	index int
+}
+
+// Returns the columns for the exported fields of struct type t
+func csvColumns(t reflect.Type) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("csv"); ok {
+			name = tag
+		} else if tag, ok := f.Tag.Lookup("json"); ok {
+			if tag_name, _, _ := strings.Cut(tag, ","); tag_name != "" {
+				name = tag_name
+			}
+		}
+		if name == "-" {
+			continue
+		}
+		columns = append(columns, csvColumn{name: name, index: i})
+	}
+	return columns
+}
+
+// Renders a single field value
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case encoding.TextMarshaler:
+		b, err := value.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	case fmt.Stringer:
+		return value.String()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	}
+	return fmt.Sprint(v.Interface())
+}
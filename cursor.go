@@ -0,0 +1,166 @@
+package resdk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Error for a cursor which was tampered with or is malformed. Responds
+// with 400 Bad Request.
+var ErrInvalidCursor error = invalidCursorError{}
+
+type invalidCursorError struct{}
+
+func (invalidCursorError) Error() string {
+	return "Invalid cursor"
+}
+
+func (invalidCursorError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// Encodes keyset pagination positions (e.g. the sort key and id of the
+// last item of a page) into opaque, tamper proof cursors: base64url
+// Json signed with HMAC-SHA256.
+//
+//	type position struct {
+//		CreatedAt time.Time `json:"c"`
+//		ID        int64     `json:"i"`
+//	}
+//
+//	codec := resdk.CursorCodec{Key: secret}
+//	next, err := codec.Encode(position{last.CreatedAt, last.ID})
+type CursorCodec struct {
+	// Required. Secret key of the signature, at least 32 random
+	// bytes. Encode and Decode fail with shorter keys.
+	Key []byte
+}
+
+// Error of a CursorCodec whose Key is too short to keep its signatures
+// secret
+var errShortCursorKey = errors.New("resdk: cursor key shorter than 32 bytes")
+
+// Returns the cursor for the position v, which is marshaled with
+// JsonEngine
+func (c CursorCodec) Encode(v interface{}) (string, error) {
+	if len(c.Key) < 32 {
+		return "", errShortCursorKey
+	}
+	payload, err := JsonEngine.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(c.sign(encoded)), nil
+}
+
+// Verifies cursor and unmarshals its position into v. Fails with
+// ErrInvalidCursor if the cursor was not created with the same Key.
+func (c CursorCodec) Decode(cursor string, v interface{}) error {
+	if len(c.Key) < 32 {
+		return errShortCursorKey
+	}
+	encoded, signature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, c.sign(encoded)) {
+		return ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || JsonEngine.Unmarshal(payload, v) != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+func (c CursorCodec) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, c.Key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// Outputable of one page of a cursor paginated list. Json serializers
+// render it as
+//
+//	{"items": [...], "meta": {"next_cursor": "...", "next": "/events?cursor=...&limit=50"}}
+//
+// and all serializers add a Link header with the next and prev links
+// (RFC 8288). Create it with NewCursorPage.
+type CursorPage struct {
+	// The items of the page, usually a slice
+	Items interface{}
+	// Cursor of the next page, empty on the last page
+	NextCursor string
+	// Cursor of the previous page, empty on the first page
+	PrevCursor string
+	// URL of the next page
+	Next string
+	// URL of the previous page
+	Prev string
+}
+
+// Creates the CursorPage for items with the cursors of the next and
+// previous pages (empty if there is none). Next and Prev are links
+// relative to the request URL, which is taken from the Exchange in ctx,
+// with the cursor query parameter replaced.
+func NewCursorPage(ctx context.Context, items interface{}, next_cursor, prev_cursor string) *CursorPage {
+	page := &CursorPage{Items: items, NextCursor: next_cursor, PrevCursor: prev_cursor}
+	x := ExchangeFromContext(ctx)
+	if x == nil || x.Request == nil {
+		return page
+	}
+	if next_cursor != "" {
+		page.Next = cursorURL(x.Request.URL, next_cursor)
+	}
+	if prev_cursor != "" {
+		page.Prev = cursorURL(x.Request.URL, prev_cursor)
+	}
+	return page
+}
+
+// Returns u with its cursor query parameter replaced
+func cursorURL(u *url.URL, cursor string) string {
+	query := u.Query()
+	query.Set("cursor", cursor)
+	query.Del("page")
+	cursor_url := url.URL{Path: u.Path, RawQuery: query.Encode()}
+	return cursor_url.String()
+}
+
+func (c *CursorPage) Headers() http.Header {
+	var links []string
+	if c.Next != "" {
+		links = append(links, "<"+c.Next+`>; rel="next"`)
+	}
+	if c.Prev != "" {
+		links = append(links, "<"+c.Prev+`>; rel="prev"`)
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return http.Header{"Link": {strings.Join(links, ", ")}}
+}
+
+func (c *CursorPage) MarshalJSON() ([]byte, error) {
+	meta := map[string]interface{}{}
+	if c.NextCursor != "" {
+		meta["next_cursor"] = c.NextCursor
+		meta["next"] = c.Next
+	}
+	if c.PrevCursor != "" {
+		meta["prev_cursor"] = c.PrevCursor
+		meta["prev"] = c.Prev
+	}
+	return JsonEngine.Marshal(map[string]interface{}{
+		"items": c.Items,
+		"meta":  meta,
+	})
+}
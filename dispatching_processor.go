@@ -0,0 +1,98 @@
+package resdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Set of functions which can be optionally implemented by an Inputable
+// to name its kind, e.g. the "type" field of a polymorphic request body.
+// Used by DispatchingProcessor.
+type Discriminated interface {
+	// Returns the kind of the input
+	Discriminator() string
+}
+
+// A Processor routing each Inputable to the Processor registered for
+// its kind (see Discriminated) or its dynamic type, e.g. for webhook
+// events of many kinds on one endpoint. Use NewDispatchingProcessor to
+// create one and register everything before serving.
+type DispatchingProcessor struct {
+	// Optional. Processes inputs no other Processor is registered for
+	Default Processable
+
+	by_kind map[string]Processable
+	by_type map[reflect.Type]Processable
+}
+
+// Error returned by DispatchingProcessor when no Processor is
+// registered for an input. Responds with 422 Unprocessable Entity.
+type UnsupportedInputError struct {
+	// Kind of the input if it is Discriminated
+	Kind string
+	// Dynamic type of the input
+	Type reflect.Type
+}
+
+func (u *UnsupportedInputError) Error() string {
+	if u.Kind != "" {
+		return fmt.Sprintf("unsupported input kind %q", u.Kind)
+	}
+	return fmt.Sprintf("unsupported input type %s", u.Type)
+}
+
+func (u *UnsupportedInputError) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// Creates an empty DispatchingProcessor
+func NewDispatchingProcessor() *DispatchingProcessor {
+	return &DispatchingProcessor{
+		by_kind: make(map[string]Processable),
+		by_type: make(map[reflect.Type]Processable),
+	}
+}
+
+// Routes Discriminated inputs of kind to p
+func (d *DispatchingProcessor) HandleKind(kind string, p Processable) *DispatchingProcessor {
+	d.by_kind[kind] = p
+	return d
+}
+
+// Routes inputs with the same dynamic type as example to p
+func (d *DispatchingProcessor) HandleType(example Inputable, p Processable) *DispatchingProcessor {
+	d.by_type[reflect.TypeOf(example)] = p
+	return d
+}
+
+// Returns the Processor for in. Kinds take precedence over types.
+func (d *DispatchingProcessor) processor(in Inputable) (Processable, error) {
+	kind := ""
+	if discriminated, ok := in.(Discriminated); ok {
+		kind = discriminated.Discriminator()
+		if p, ok := d.by_kind[kind]; ok {
+			return p, nil
+		}
+	}
+	if p, ok := d.by_type[reflect.TypeOf(in)]; ok {
+		return p, nil
+	}
+	if d.Default != nil {
+		return d.Default, nil
+	}
+	return nil, &UnsupportedInputError{Kind: kind, Type: reflect.TypeOf(in)}
+}
+
+func (d *DispatchingProcessor) Process(in Inputable) (Outputable, error) {
+	return d.ProcessAuth(context.Background(), in, nil)
+}
+
+func (d *DispatchingProcessor) ProcessAuth(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error) {
+	p, err := d.processor(in)
+	if err != nil {
+		return nil, err
+	}
+	return callProcessor(ctx, p, in, auth_details)
+}
@@ -0,0 +1,95 @@
+package resdk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint of string types usable with Enum: the type declares its
+// allowed values with a Values method.
+//
+//	type Status string
+//
+//	const (
+//		StatusActive Status = "active"
+//		StatusClosed Status = "closed"
+//	)
+//
+//	func (Status) Values() []Status { return []Status{StatusActive, StatusClosed} }
+type EnumType[T any] interface {
+	~string
+	Values() []T
+}
+
+// A value restricted to the declared values of T. It binds from strings
+// (query, path, form and header parameters as well as Json bodies),
+// rejecting anything not declared, and serializes back as the string.
+// The zero Enum is unset and marshals to null.
+//
+//	type ListOrders struct {
+//		Status resdk.Enum[Status] `query:"status" default:"active"`
+//	}
+type Enum[T EnumType[T]] struct {
+	value T
+	set   bool
+}
+
+// Creates an Enum holding value. Fails if value is not declared by T.
+func NewEnum[T EnumType[T]](value T) (Enum[T], error) {
+	var e Enum[T]
+	err := e.UnmarshalText([]byte(value))
+	return e, err
+}
+
+// Returns the value, the zero T if unset
+func (e Enum[T]) Value() T {
+	return e.value
+}
+
+// Reports whether a value was set
+func (e Enum[T]) IsSet() bool {
+	return e.set
+}
+
+func (e Enum[T]) String() string {
+	return string(e.value)
+}
+
+func (e Enum[T]) MarshalText() ([]byte, error) {
+	return []byte(e.value), nil
+}
+
+func (e Enum[T]) MarshalJSON() ([]byte, error) {
+	if !e.set {
+		return []byte("null"), nil
+	}
+	return JsonEngine.Marshal(string(e.value))
+}
+
+func (e *Enum[T]) UnmarshalText(text []byte) error {
+	var zero T
+	values := zero.Values()
+	for _, value := range values {
+		if string(value) == string(text) {
+			e.value, e.set = value, true
+			return nil
+		}
+	}
+	names := make([]string, len(values))
+	for i, value := range values {
+		names[i] = string(value)
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(names, ", "))
+}
+
+func (e *Enum[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*e = Enum[T]{}
+		return nil
+	}
+	var s string
+	if err := JsonEngine.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return e.UnmarshalText([]byte(s))
+}
@@ -0,0 +1,55 @@
+package resdk
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// Set of functions which can be optionally implemented by an
+// Outputable to provide the meta member of an EnvelopeSerializer
+// response, e.g. pagination totals
+type Metaer interface {
+	// Returns the meta data of the response
+	Meta() interface{}
+}
+
+// A serializer wrapping Json responses in an envelope:
+// {"data": <out>, "meta": <meta>}. meta is only present if out
+// implements Metaer.
+type EnvelopeSerializer struct {
+	// HTTP Status Code to be returned unless out implements
+	// StatusCoder
+	StatusCode int
+	// Name of the member holding the Outputable. Defaults to "data".
+	DataKey string
+	// Name of the member holding the meta data. Defaults to "meta".
+	MetaKey string
+	// Formatting options
+	JsonOptions
+}
+
+// Serializes Outputable to a ResponseWriter
+func (e EnvelopeSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	e.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors. Responds
+// with a 500 error if out cannot be marshaled.
+func (e EnvelopeSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	data_key, meta_key := e.DataKey, e.MetaKey
+	if data_key == "" {
+		data_key = "data"
+	}
+	if meta_key == "" {
+		meta_key = "meta"
+	}
+	envelope := map[string]interface{}{
+		data_key: out,
+	}
+	if metaer, ok := out.(Metaer); ok {
+		envelope[meta_key] = metaer.Meta()
+	}
+	return writeMarshaled(w, out, "application/json", e.StatusCode, marshalFailureBody, func(buf *bytes.Buffer) error {
+		return e.JsonOptions.encode(buf, envelope, r)
+	})
+}
@@ -0,0 +1,71 @@
+package resdk
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Registry mapping errors to the Serializers which should send them as
+// the response. BaseHandler consults its ErrorMapper for every error
+// before falling back to the error serializer of the failing phase, so
+// e.g. a conflict returned by the Processor can become a 409 instead
+// of a 500.
+//
+// Errors are matched in registration order. Register everything before
+// the handler starts serving, an ErrorMapper is not safe for concurrent
+// registration.
+type ErrorMapper struct {
+	mappings []errorMapping
+}
+
+type errorMapping struct {
+	// Matches err using errors.Is or errors.As
+	match      func(err error) bool
+	serializer Serializable
+}
+
+// Creates an empty ErrorMapper
+func NewErrorMapper() *ErrorMapper {
+	return &ErrorMapper{}
+}
+
+// Maps errors matching target (according to errors.Is) to s
+//
+//	mapper.Register(ErrConflict, &JsonErrorSerializer{StatusCode: http.StatusConflict})
+func (e *ErrorMapper) Register(target error, s Serializable) *ErrorMapper {
+	e.mappings = append(e.mappings, errorMapping{
+		match: func(err error) bool {
+			return errors.Is(err, target)
+		},
+		serializer: s,
+	})
+	return e
+}
+
+// Maps errors of the same type as example (according to errors.As) to s.
+// example is only used for its type, e.g.
+//
+//	mapper.RegisterType(&RateLimitError{}, &JsonErrorSerializer{StatusCode: http.StatusTooManyRequests})
+func (e *ErrorMapper) RegisterType(example error, s Serializable) *ErrorMapper {
+	typ := reflect.TypeOf(example)
+	e.mappings = append(e.mappings, errorMapping{
+		match: func(err error) bool {
+			return errors.As(err, reflect.New(typ).Interface())
+		},
+		serializer: s,
+	})
+	return e
+}
+
+// Returns the Serializer registered for err or nil if there is none
+func (e *ErrorMapper) Serializer(err error) Serializable {
+	if e == nil {
+		return nil
+	}
+	for _, mapping := range e.mappings {
+		if mapping.match(err) {
+			return mapping.serializer
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package resdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// An error reported to an ErrorReporter
+type ErrorReport struct {
+	// The error which ended the request, a *PanicError for panics
+	Err error
+	// Stack trace of the panic, nil for errors
+	Stack []byte
+	// The request, whose context is still available
+	Request *http.Request
+	// Phase which failed, see Exchange.FailedPhase
+	Phase Phase
+	// Status code of the response
+	Status int
+	// PrincipalID of the caller, "" for anonymous callers
+	Principal string
+	// The Inputable with redact tagged fields removed (see Redact),
+	// nil if deserialization did not succeed. Secrets in fields
+	// without a redact tag are kept.
+	Input interface{}
+	Time  time.Time
+}
+
+// Receives the server errors and panics of BaseHandlers, e.g. to send
+// them to an error tracker such as SentryReporter
+type ErrorReporter interface {
+	// Called after the response was written. ctx is the request
+	// context without its cancellation.
+	ReportError(ctx context.Context, report *ErrorReport)
+}
+
+// The ErrorReporterFunc type is an adapter to allow the use of
+// ordinary functions as an ErrorReporter.
+type ErrorReporterFunc func(ctx context.Context, report *ErrorReport)
+
+// ReportError calls f(ctx, report)
+func (f ErrorReporterFunc) ReportError(ctx context.Context, report *ErrorReport) {
+	f(ctx, report)
+}
+
+// Reports err to ErrorReporter if the request ended with a panic
+// (recovered through RecoverySerializer) or a 5xx response
+func (m *BaseHandler) reportError(r *http.Request, x *Exchange, err error) {
+	var perr *PanicError
+	panicked := errors.As(err, &perr)
+	if !panicked && x.Status() < 500 {
+		return
+	}
+	report := &ErrorReport{
+		Err:       err,
+		Request:   r,
+		Phase:     x.FailedPhase(),
+		Status:    x.Status(),
+		Principal: PrincipalID(x.AuthDetails),
+		Time:      time.Now(),
+	}
+	if panicked {
+		report.Stack = perr.Stack
+	}
+	if x.Input != nil {
+		report.Input = Redact(x.Input, nil)
+	}
+	m.ErrorReporter.ReportError(context.WithoutCancel(r.Context()), report)
+}
+
+// An ErrorReporter sending reports as events to Sentry, or any service
+// implementing its store API, without the Sentry SDK:
+//
+//	reporter, err := resdk.NewSentryReporter(os.Getenv("SENTRY_DSN"))
+//
+// Events carry the request method and path, the principal as user id,
+// the failed phase as tag, and the panic stack as extra data. Request
+// headers are left out since they hold credentials, as are the input
+// and query string unless enabled.
+type SentryReporter struct {
+	// Required. Store endpoint derived from the DSN.
+	StoreURL string
+	// Required. Public key of the DSN.
+	Key         string
+	Environment string
+	Release     string
+	// Send the input as extra data. Only set this if all secret
+	// fields of the inputs are tagged for redaction.
+	SendInput bool
+	// Send the query string, which may carry tokens
+	SendQueryString bool
+	// Defaults to http.DefaultClient. Sending an event times out
+	// after 5 seconds.
+	Client *http.Client
+	// Optional callback receiving failures to send events
+	OnError func(err error)
+}
+
+// Creates a SentryReporter for a DSN such as
+// "https://<key>@o0.ingest.sentry.io/<project>"
+func NewSentryReporter(dsn string) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parsing sentry dsn: %w", err)
+	}
+	i := strings.LastIndex(u.Path, "/")
+	if i < 0 || u.Path[i+1:] == "" || u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("parsing sentry dsn: key or project missing")
+	}
+	// Sentry may be served below a path prefix
+	store := url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path[:i] + "/api/" + u.Path[i+1:] + "/store/"}
+	return &SentryReporter{StoreURL: store.String(), Key: u.User.Username()}, nil
+}
+
+func (s *SentryReporter) ReportError(ctx context.Context, report *ErrorReport) {
+	if err := s.send(ctx, report); err != nil && s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// Sends report as a Sentry event
+func (s *SentryReporter) send(ctx context.Context, report *ErrorReport) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	level := "error"
+	if report.Stack != nil {
+		level = "fatal"
+	}
+	extra := map[string]interface{}{"status": report.Status}
+	if s.SendInput && report.Input != nil {
+		extra["input"] = report.Input
+	}
+	if report.Stack != nil {
+		extra["stack"] = string(report.Stack)
+	}
+	request := map[string]string{
+		"method": report.Request.Method,
+		"url":    report.Request.URL.Path,
+	}
+	if s.SendQueryString {
+		request["query_string"] = report.Request.URL.RawQuery
+	}
+	event := map[string]interface{}{
+		"event_id":    hex.EncodeToString(id),
+		"timestamp":   report.Time.UTC().Format(time.RFC3339Nano),
+		"platform":    "go",
+		"level":       level,
+		"environment": s.Environment,
+		"release":     s.Release,
+		"exception": map[string]interface{}{
+			"values": []map[string]string{{
+				"type":  fmt.Sprintf("%T", report.Err),
+				"value": report.Err.Error(),
+			}},
+		},
+		"request": request,
+		"tags":    map[string]string{"phase": string(report.Phase)},
+		"extra":   extra,
+	}
+	if report.Principal != "" {
+		event["user"] = map[string]string{"id": report.Principal}
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.StoreURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_client=resdk/1.0, sentry_key="+s.Key)
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending sentry event: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sending sentry event: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package resdk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Outputable which provides its own version, e.g. a revision number or
+// an updated timestamp, used as ETag instead of hashing the body
+type Etagger interface {
+	// Returns the entity tag, quoted or not. An empty tag falls back to
+	// hashing the body.
+	ETag() string
+}
+
+// A serializer adding an ETag to successful GET and HEAD responses of
+// another Serializer and responding with 304 Not Modified and no body if
+// it matches the If-None-Match header of the request.
+//
+// The ETag is taken from out if it implements Etagger, in which case
+// fresh responses are not serialized at all. Otherwise it is a hash of
+// the serialized body.
+type EtagSerializer struct {
+	// Required. Serializer producing the response.
+	Serializer Serializable
+	// Produce weak ETags (W/"...") instead of strong ones
+	Weak bool
+}
+
+// Serializes Outputable to a ResponseWriter
+func (e EtagSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	e.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns the errors of the wrapped Serializer and
+// writing
+func (e EtagSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return callSerializer(e.Serializer, out, w, r)
+	}
+
+	if etagger, ok := out.(Etagger); ok {
+		if tag := etagger.ETag(); tag != "" {
+			etag := e.format(tag)
+			if etagMatches(r.Header.Values("If-None-Match"), etag) {
+				return notModified(w, etag)
+			}
+			w.Header().Set("ETag", etag)
+			return callSerializer(e.Serializer, out, w, r)
+		}
+	}
+
+	buf := newResponseBuffer()
+	serr := callSerializer(e.Serializer, out, buf, r)
+	status := buf.statusCode()
+	body := buf.body.Bytes()
+	if serr != nil || status < 200 || status >= 300 || !bodyAllowed(status) {
+		return errors.Join(serr, buf.sendTo(w, body))
+	}
+	sum := sha256.Sum256(body)
+	etag := e.format(hex.EncodeToString(sum[:16]))
+	if etagMatches(r.Header.Values("If-None-Match"), etag) {
+		return notModified(w, etag)
+	}
+	buf.header.Set("ETag", etag)
+	return buf.sendTo(w, body)
+}
+
+// Quotes tag and marks it weak if needed
+func (e EtagSerializer) format(tag string) string {
+	if !strings.HasPrefix(tag, `"`) && !strings.HasPrefix(tag, `W/"`) {
+		tag = `"` + tag + `"`
+	}
+	if e.Weak && !strings.HasPrefix(tag, "W/") {
+		tag = "W/" + tag
+	}
+	return tag
+}
+
+// Reports whether any tag in If-None-Match header values matches etag
+// using weak comparison
+func etagMatches(values []string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Responds with 304 Not Modified for etag
+func notModified(w http.ResponseWriter, etag string) error {
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+	return nil
+}
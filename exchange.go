@@ -0,0 +1,229 @@
+package resdk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Request scoped state shared by all phases of a request. BaseHandler
+// creates an Exchange for every request and stores it in the request
+// context, so any phase (or Interceptor, hook, ...) with access to the
+// request or its context can read and write it through
+// ExchangeFromRequest or ExchangeFromContext, without new parameters
+// on the lifecycle interfaces.
+//
+// BaseHandler fills AuthDetails, Input and Output as the phases
+// complete and reads them back before the next phase, so a phase may
+// replace them.
+type Exchange struct {
+	// The request as received by the handler
+	Request *http.Request
+	// The response writer of the request
+	Writer http.ResponseWriter
+	// Time the handler started serving the request
+	Started time.Time
+	// Action of the request, see BaseHandler.Action
+	Action Action
+
+	// Authentication details returned by the Authenticator
+	AuthDetails interface{}
+	// Tenant found by the TenantResolver, "" if the handler has none
+	Tenant string
+	// Deserialized input
+	Input Inputable
+	// Output returned by the Processor
+	Output Outputable
+
+	mu      sync.Mutex
+	status  int
+	written int64
+	read    int64
+	failed  Phase
+	// Send the Server-Timing header, see BaseHandler.ServerTiming
+	serverTiming bool
+	values       map[interface{}]interface{}
+	starts       map[Phase]time.Time
+	timings      map[Phase]time.Duration
+}
+
+type exchangeKey struct{}
+
+// Creates an Exchange for a request. Writer records the status and
+// size of the response written through it.
+func newExchange(w http.ResponseWriter, r *http.Request) *Exchange {
+	x := &Exchange{
+		Request: r,
+		Started: time.Now(),
+	}
+	x.Writer = &exchangeWriter{ResponseWriter: w, x: x}
+	return x
+}
+
+// Returns the Exchange stored in ctx or nil if there is none
+func ExchangeFromContext(ctx context.Context) *Exchange {
+	x, _ := ctx.Value(exchangeKey{}).(*Exchange)
+	return x
+}
+
+// Returns the Exchange of r or nil if r is not served by a BaseHandler
+func ExchangeFromRequest(r *http.Request) *Exchange {
+	return ExchangeFromContext(r.Context())
+}
+
+// Returns a copy of ctx carrying x
+func contextWithExchange(ctx context.Context, x *Exchange) context.Context {
+	return context.WithValue(ctx, exchangeKey{}, x)
+}
+
+// Stores value under key. Keys should be of an unexported type, like
+// context keys, to avoid collisions.
+func (x *Exchange) Set(key, value interface{}) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.values == nil {
+		x.values = make(map[interface{}]interface{})
+	}
+	x.values[key] = value
+}
+
+// Returns the value stored under key and whether there was one
+func (x *Exchange) Get(key interface{}) (interface{}, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	value, ok := x.values[key]
+	return value, ok
+}
+
+// Returns how long each completed phase took
+func (x *Exchange) Timings() map[Phase]time.Duration {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	timings := make(map[Phase]time.Duration, len(x.timings))
+	for phase, d := range x.timings {
+		timings[phase] = d
+	}
+	return timings
+}
+
+// Records the start of phase
+func (x *Exchange) startPhase(phase Phase) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.starts == nil {
+		x.starts = make(map[Phase]time.Time)
+	}
+	x.starts[phase] = time.Now()
+}
+
+// Records the end of phase and whether it failed with err. Durations
+// of phases which run several times (e.g. serialization) add up.
+func (x *Exchange) endPhase(phase Phase, err error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err != nil && x.failed == "" {
+		x.failed = phase
+	}
+	start, ok := x.starts[phase]
+	if !ok {
+		return
+	}
+	if x.timings == nil {
+		x.timings = make(map[Phase]time.Duration)
+	}
+	x.timings[phase] += time.Since(start)
+	delete(x.starts, phase)
+}
+
+// Returns the phase whose error ended the request, "" if it succeeded.
+// PhaseRequest stands for failures outside of the other phases, e.g.
+// of RequiredScopes.
+func (x *Exchange) FailedPhase() Phase {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.failed
+}
+
+// Returns the status code of the response, 0 if nothing was written
+// yet
+func (x *Exchange) Status() int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.status
+}
+
+// Returns the number of body bytes written to the response
+func (x *Exchange) BytesWritten() int64 {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.written
+}
+
+// Returns the number of request body bytes read so far, before
+// decompression
+func (x *Exchange) BytesRead() int64 {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.read
+}
+
+// Request body counting the bytes read on its Exchange
+type exchangeBody struct {
+	io.ReadCloser
+	x *Exchange
+}
+
+func (e *exchangeBody) Read(p []byte) (int, error) {
+	n, err := e.ReadCloser.Read(p)
+	e.x.mu.Lock()
+	e.x.read += int64(n)
+	e.x.mu.Unlock()
+	return n, err
+}
+
+// ResponseWriter recording the status and size of the response on its
+// Exchange
+type exchangeWriter struct {
+	http.ResponseWriter
+	x *Exchange
+}
+
+func (e *exchangeWriter) WriteHeader(code int) {
+	e.x.mu.Lock()
+	// Informational responses precede the final one
+	final := e.x.status == 0 && code >= 200
+	if final {
+		e.x.status = code
+	}
+	e.x.mu.Unlock()
+	if final && e.x.serverTiming {
+		e.Header().Set("Server-Timing", e.x.serverTimingHeader())
+	}
+	e.ResponseWriter.WriteHeader(code)
+}
+
+func (e *exchangeWriter) Write(p []byte) (int, error) {
+	if e.x.Status() == 0 {
+		e.WriteHeader(http.StatusOK)
+	}
+	n, err := e.ResponseWriter.Write(p)
+	e.x.mu.Lock()
+	e.x.written += int64(n)
+	e.x.mu.Unlock()
+	return n, err
+}
+
+// Allows http.ResponseController to reach the wrapped ResponseWriter
+func (e *exchangeWriter) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
+// Forgets a failed phase whose error did not end the request, e.g. an
+// authentication failure with AllowAnonymous
+func (x *Exchange) forgiveFailure() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.failed = ""
+}
@@ -0,0 +1,99 @@
+package resdk
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Parses a parameter value into a field of the type it is registered
+// for. layout is the value of the layout tag of the field, e.g.
+// `query:"since" layout:"2006-01-02"`, or "" if it has none.
+type FieldCodec func(value string, layout string) (interface{}, error)
+
+var (
+	fieldCodecsMu sync.RWMutex
+	fieldCodecs   = map[reflect.Type]FieldCodec{
+		reflect.TypeOf(time.Time{}):      parseTimeField,
+		reflect.TypeOf(time.Duration(0)): parseDurationField,
+	}
+)
+
+// Registers the FieldCodec used by the binders (query, path, form,
+// header parameters and default tags) for fields of the type of
+// example, replacing any existing one. Codecs take precedence over
+// encoding.TextUnmarshaler. For example decimals of
+// github.com/shopspring/decimal:
+//
+//	resdk.RegisterFieldCodec(decimal.Decimal{}, func(value, layout string) (interface{}, error) {
+//		return decimal.NewFromString(value)
+//	})
+func RegisterFieldCodec(example interface{}, codec FieldCodec) {
+	fieldCodecsMu.Lock()
+	defer fieldCodecsMu.Unlock()
+	fieldCodecs[reflect.TypeOf(example)] = codec
+}
+
+// Returns the FieldCodec registered for t or nil
+func fieldCodec(t reflect.Type) FieldCodec {
+	fieldCodecsMu.RLock()
+	defer fieldCodecsMu.RUnlock()
+	return fieldCodecs[t]
+}
+
+// Parses a time.Time. Without layout RFC 3339 and Unix timestamps in
+// seconds are accepted. The layouts "unix", "unixmilli" and "unixmicro"
+// parse Unix timestamps in that unit; any other layout is a
+// time.Parse layout.
+func parseTimeField(value, layout string) (interface{}, error) {
+	switch layout {
+	case "":
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, nil
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.Unix(n, 0).UTC(), nil
+		}
+		return nil, fmt.Errorf("invalid time, expected RFC 3339 or Unix timestamp")
+	case "unix", "unixmilli", "unixmicro":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Unix timestamp")
+		}
+		switch layout {
+		case "unixmilli":
+			return time.UnixMilli(n).UTC(), nil
+		case "unixmicro":
+			return time.UnixMicro(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time, expected layout %s", layout)
+	}
+	return t, nil
+}
+
+// Parses a time.Duration in Go syntax, e.g. "1h30m". With layout set to
+// a unit ("ns", "us", "ms", "s", "m" or "h") plain numbers are accepted
+// in that unit as well.
+func parseDurationField(value, layout string) (interface{}, error) {
+	if layout != "" {
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			unit, err := time.ParseDuration("1" + layout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid duration unit %q", layout)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration")
+	}
+	return d, nil
+}
@@ -0,0 +1,258 @@
+package resdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// A serializer projecting the Json response of another Serializer onto
+// the fields requested by the client, e.g. ?fields=id,name,email, to
+// cut the payload for clients which need only a few fields.
+//
+// Only top level fields are selected. A response which is an array has
+// each of its objects projected, as does the array under ItemsKey of an
+// envelope such as PaginatedOutput. Responses without the parameter,
+// error responses and non Json responses are sent unchanged. Field
+// order of the original response is kept.
+//
+// Wrap it with EtagSerializer or CompressingSerializer, not the other
+// way around, so those see the projected body.
+type FieldFilteringSerializer struct {
+	// Required. Serializer producing the Json response.
+	Serializer Serializable
+	// Name of the query parameter. Defaults to "fields".
+	Param string
+	// Fields which may be requested. Any field may be requested if
+	// empty. Requests for other fields fail with *ValidationErrors.
+	Allowed []string
+	// Fields always included, e.g. "id"
+	Always []string
+	// Key of the array to project in envelope responses, e.g. "items"
+	ItemsKey string
+	// Serializer for the *ValidationErrors of fields not allowed. A
+	// plain text 400 error if nil.
+	ErrorSerializer Serializable
+}
+
+// Serializes Outputable to a ResponseWriter
+func (f FieldFilteringSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	f.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns the errors of the wrapped Serializer and
+// writing
+func (f FieldFilteringSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	param := f.Param
+	if param == "" {
+		param = "fields"
+	}
+	if !r.URL.Query().Has(param) {
+		return callSerializer(f.Serializer, out, w, r)
+	}
+	fields, err := f.fields(param, r.URL.Query().Get(param))
+	if err != nil {
+		if f.ErrorSerializer == nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil
+		}
+		return callSerializer(f.ErrorSerializer, err, w, r)
+	}
+
+	buf := newResponseBuffer()
+	serr := callSerializer(f.Serializer, out, buf, r)
+	status := buf.statusCode()
+	body := buf.body.Bytes()
+	if serr != nil || status < 200 || status >= 300 || !isJsonMediaType(buf.header.Get("Content-Type")) {
+		return errors.Join(serr, buf.sendTo(w, body))
+	}
+	projected, err := f.project(body, fields)
+	if err != nil {
+		// Not an object or array of objects, leave it alone
+		return buf.sendTo(w, body)
+	}
+	buf.header.Del("Content-Length")
+	return buf.sendTo(w, projected)
+}
+
+// Parses the requested fields, adding Always
+func (f FieldFilteringSerializer) fields(param, value string) (map[string]bool, error) {
+	fields := make(map[string]bool)
+	errs := NewValidationErrors()
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if len(f.Allowed) > 0 && !containsString(f.Allowed, name) {
+			errs.Add(param, "field", fmt.Sprintf("cannot select field %q, allowed are %s", name, strings.Join(f.Allowed, ", ")))
+			continue
+		}
+		fields[name] = true
+	}
+	for _, name := range f.Always {
+		fields[name] = true
+	}
+	return fields, errs.Err()
+}
+
+// Projects an array of objects, the array under ItemsKey of an envelope
+// or else an object
+func (f FieldFilteringSerializer) project(body []byte, fields map[string]bool) ([]byte, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		return projectArray(body, fields)
+	}
+	var members map[string]json.RawMessage
+	if err := JsonEngine.Unmarshal(body, &members); err != nil {
+		return nil, err
+	}
+	if _, envelope := members[f.ItemsKey]; f.ItemsKey == "" || !envelope {
+		return projectObject(body, func(key string, value json.RawMessage) (json.RawMessage, bool, error) {
+			return value, fields[key], nil
+		})
+	}
+	return projectObject(body, func(key string, value json.RawMessage) (json.RawMessage, bool, error) {
+		if key != f.ItemsKey {
+			return value, true, nil
+		}
+		projected, err := projectArray(value, fields)
+		return projected, true, err
+	})
+}
+
+// Projects each object of a Json array
+func projectArray(body []byte, fields map[string]bool) ([]byte, error) {
+	var items []json.RawMessage
+	if err := JsonEngine.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	out.WriteByte('[')
+	for i, item := range items {
+		projected, err := projectObject(item, func(key string, value json.RawMessage) (json.RawMessage, bool, error) {
+			return value, fields[key], nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		out.Write(projected)
+	}
+	out.WriteByte(']')
+	return out.Bytes(), nil
+}
+
+// Rewrites a Json object keeping the order of its members. keep returns
+// the new value of a member and whether to keep it. body must be valid
+// Json, as checked by the callers unmarshaling it with JsonEngine.
+func projectObject(body []byte, keep func(key string, value json.RawMessage) (json.RawMessage, bool, error)) ([]byte, error) {
+	rest := bytes.TrimSpace(body)
+	if len(rest) == 0 || rest[0] != '{' {
+		return nil, errors.New("not a json object")
+	}
+	rest = bytes.TrimLeft(rest[1:], jsonSpace)
+	var out bytes.Buffer
+	out.WriteByte('{')
+	first := true
+	for len(rest) > 0 && rest[0] != '}' {
+		n := jsonValueLength(rest)
+		var key string
+		if err := JsonEngine.Unmarshal(rest[:n], &key); err != nil {
+			return nil, err
+		}
+		rest = bytes.TrimLeft(rest[n:], jsonSpace)
+		if len(rest) == 0 || rest[0] != ':' {
+			return nil, errors.New("not a json object")
+		}
+		rest = bytes.TrimLeft(rest[1:], jsonSpace)
+		n = jsonValueLength(rest)
+		value := json.RawMessage(rest[:n])
+		rest = bytes.TrimLeft(rest[n:], jsonSpace)
+		if len(rest) > 0 && rest[0] == ',' {
+			rest = bytes.TrimLeft(rest[1:], jsonSpace)
+		}
+		value, ok, err := keep(key, value)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if !first {
+			out.WriteByte(',')
+		}
+		first = false
+		encoded_key, _ := JsonEngine.Marshal(key)
+		out.Write(encoded_key)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// Whitespace between Json tokens
+const jsonSpace = " \t\r\n"
+
+// Returns the length of the valid Json value at the start of b
+func jsonValueLength(b []byte) int {
+	depth := 0
+	in_string := false
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if in_string {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				in_string = false
+				if depth == 0 {
+					return i + 1
+				}
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			in_string = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return i
+			}
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case ',', ' ', '\t', '\r', '\n':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(b)
+}
+
+// Reports whether content_type is application/json or a +json type
+func isJsonMediaType(content_type string) bool {
+	media_type, _, err := mime.ParseMediaType(content_type)
+	return err == nil && (media_type == "application/json" || strings.HasSuffix(media_type, "+json"))
+}
+
+// Reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package resdk
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// Outputable for a downloadable file served by FileSerializer
+type File struct {
+	// File name used for Content-Disposition and to detect the MIME
+	// type from its extension
+	Name string
+	// File content. Closed after serving if it is an io.Closer.
+	Content io.ReadSeeker
+	// Content type. Detected from Name or the content if empty.
+	ContentType string
+	// Modification time for Last-Modified and If-Modified-Since. Not
+	// used if zero.
+	ModTime time.Time
+	// Display the file in the browser instead of downloading it
+	Inline bool
+}
+
+// A serializer for binary responses based on http.ServeContent. It
+// serves *File Outputables, or any Outputable which is an
+// io.ReadSeeker, with Accept-Ranges and Range (206 Partial Content)
+// support, conditional requests, Content-Disposition and MIME type
+// detection.
+type FileSerializer struct {
+	// Modification time used for io.ReadSeeker Outputables which are
+	// not a *File. Not used if zero.
+	ModTime time.Time
+}
+
+func (f FileSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	f.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns an error if out is not a file. Errors
+// writing the content are handled by http.ServeContent and not
+// reported.
+func (f FileSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	file, ok := out.(*File)
+	if !ok {
+		content, ok := out.(io.ReadSeeker)
+		if !ok {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return fmt.Errorf("resdk: file serializer received %T", out)
+		}
+		file = &File{Content: content, ModTime: f.ModTime}
+	}
+	if closer, ok := file.Content.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	if headerer, ok := out.(Headerer); ok {
+		for key, values := range headerer.Headers() {
+			w.Header()[http.CanonicalHeaderKey(key)] = values
+		}
+	}
+	if file.ContentType != "" {
+		w.Header().Set("Content-Type", file.ContentType)
+	}
+	disposition := "attachment"
+	if file.Inline {
+		disposition = "inline"
+	}
+	var params map[string]string
+	if file.Name != "" {
+		params = map[string]string{"filename": file.Name}
+	}
+	if value := mime.FormatMediaType(disposition, params); value != "" {
+		w.Header().Set("Content-Disposition", value)
+	}
+	http.ServeContent(w, r, file.Name, file.ModTime, file.Content)
+	return nil
+}
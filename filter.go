@@ -0,0 +1,205 @@
+package resdk
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Comparison operator of a FilterCondition
+type FilterOp string
+
+const (
+	FilterEq  FilterOp = "eq"
+	FilterNe  FilterOp = "ne"
+	FilterGt  FilterOp = "gt"
+	FilterGte FilterOp = "gte"
+	FilterLt  FilterOp = "lt"
+	FilterLte FilterOp = "lte"
+	// Matches any of several values separated by "|"
+	FilterIn FilterOp = "in"
+	// Substring match
+	FilterContains FilterOp = "contains"
+)
+
+// SQL operators of the FilterOps
+var filterSQL = map[FilterOp]string{
+	FilterEq:       "=",
+	FilterNe:       "<>",
+	FilterGt:       ">",
+	FilterGte:      ">=",
+	FilterLt:       "<",
+	FilterLte:      "<=",
+	FilterIn:       "IN",
+	FilterContains: "LIKE",
+}
+
+// One condition of a Filter, e.g. age:gte:18
+type FilterCondition struct {
+	Field string
+	Op    FilterOp
+	// The value, several for FilterIn
+	Values []string
+}
+
+// Filter expression parsed from a parameter such as
+// ?filter=status:eq:active,age:gte:18 — a comma separated list of
+// field:operator:value conditions which must all hold. FilterIn takes
+// values separated by "|", e.g. status:in:active|pending. It binds from
+// query parameters:
+//
+//	type ListUsers struct {
+//		Filter resdk.Filter `query:"filter"`
+//	}
+//
+//	var userFilters = map[string]resdk.FilterField{
+//		"status": {Column: "u.status", Ops: []resdk.FilterOp{resdk.FilterEq, resdk.FilterIn}},
+//		"age":    {Column: "u.age", Ops: []resdk.FilterOp{resdk.FilterGte, resdk.FilterLte}, Parse: parseInt},
+//	}
+//
+//	func (l *ListUsers) Validate() error {
+//		return l.Filter.Check("filter", userFilters)
+//	}
+type Filter []FilterCondition
+
+// A field which may be filtered on
+type FilterField struct {
+	// Column the field maps to in SQL
+	Column string
+	// Allowed operators
+	Ops []FilterOp
+	// Optional. Converts a value to its typed form, e.g. an int.
+	// Errors fail Check.
+	Parse func(value string) (interface{}, error)
+}
+
+func (f *Filter) UnmarshalText(text []byte) error {
+	var parsed Filter
+	for _, part := range strings.Split(string(text), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, rest, ok := strings.Cut(part, ":")
+		op, value, ok2 := strings.Cut(rest, ":")
+		if !ok || !ok2 || field == "" {
+			return fmt.Errorf("invalid filter condition %q, expected field:operator:value", part)
+		}
+		if _, known := filterSQL[FilterOp(op)]; !known {
+			return fmt.Errorf("unknown filter operator %q", op)
+		}
+		values := []string{value}
+		if FilterOp(op) == FilterIn {
+			values = strings.Split(value, "|")
+		}
+		parsed = append(parsed, FilterCondition{Field: field, Op: FilterOp(op), Values: values})
+	}
+	*f = parsed
+	return nil
+}
+
+func (f Filter) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// Returns the filter in parameter syntax
+func (f Filter) String() string {
+	parts := make([]string, len(f))
+	for i, c := range f {
+		parts[i] = c.Field + ":" + string(c.Op) + ":" + strings.Join(c.Values, "|")
+	}
+	return strings.Join(parts, ",")
+}
+
+// Checks that every condition uses a field of fields with one of its
+// operators and values its Parse accepts. Failures are
+// *ValidationErrors for param, the name of the parameter.
+func (f Filter) Check(param string, fields map[string]FilterField) error {
+	errs := NewValidationErrors()
+	for _, c := range f {
+		spec, ok := fields[c.Field]
+		if !ok {
+			names := make([]string, 0, len(fields))
+			for name := range fields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			errs.Add(param, "filter_field", fmt.Sprintf("cannot filter by %q, allowed are %s", c.Field, strings.Join(names, ", ")))
+			continue
+		}
+		if !spec.allows(c.Op) {
+			errs.Add(param, "filter_op", fmt.Sprintf("operator %q is not allowed for %q", c.Op, c.Field))
+			continue
+		}
+		if _, err := spec.parse(c.Values); err != nil {
+			errs.Add(param, "filter_value", fmt.Sprintf("invalid value for %q: %v", c.Field, err))
+		}
+	}
+	return errs.Err()
+}
+
+func (s FilterField) allows(op FilterOp) bool {
+	for _, allowed := range s.Ops {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the typed values
+func (s FilterField) parse(values []string) ([]interface{}, error) {
+	typed := make([]interface{}, len(values))
+	for i, value := range values {
+		typed[i] = value
+		if s.Parse != nil {
+			v, err := s.Parse(value)
+			if err != nil {
+				return nil, err
+			}
+			typed[i] = v
+		}
+	}
+	return typed, nil
+}
+
+// Returns a WHERE condition such as "u.status IN (?, ?) AND u.age >= ?"
+// with "?" placeholders and its arguments, typed by FilterField.Parse.
+// Returns "" if there is no condition. Fails with the error of Check
+// for the "filter" parameter if a condition does not pass it, so the
+// result never contains client input and never matches more rows than
+// asked for.
+func (f Filter) SQL(fields map[string]FilterField) (string, []interface{}, error) {
+	if err := f.Check("filter", fields); err != nil {
+		return "", nil, err
+	}
+	var clauses []string
+	var args []interface{}
+	for _, c := range f {
+		spec := fields[c.Field]
+		values, err := spec.parse(c.Values)
+		if err != nil {
+			return "", nil, err
+		}
+		switch c.Op {
+		case FilterIn:
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+			clauses = append(clauses, spec.Column+" IN ("+placeholders+")")
+			args = append(args, values...)
+		case FilterContains:
+			clauses = append(clauses, spec.Column+" LIKE ? ESCAPE '!'")
+			args = append(args, "%"+escapeLike(fmt.Sprint(values[0]))+"%")
+		default:
+			clauses = append(clauses, spec.Column+" "+filterSQL[c.Op]+" ?")
+			args = append(args, values[0])
+		}
+	}
+	return strings.Join(clauses, " AND "), args, nil
+}
+
+// Escapes the wildcards of a LIKE pattern with "!", declared by an
+// ESCAPE clause. Unlike a backslash it needs no escaping in the string
+// literals of any dialect.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
@@ -0,0 +1,117 @@
+package resdk
+
+import (
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// Returns a lookup function over url.Values for bindValues
+func valuesLookup(values url.Values) func(name string) ([]string, bool) {
+	return func(name string) ([]string, bool) {
+		vals, ok := values[name]
+		return vals, ok
+	}
+}
+
+// A Deserializer binding an application/x-www-form-urlencoded request
+// body into the fields of the Inputable returned by New which have a
+// form tag, e.g. `form:"email"`. Parsing follows the same rules as
+// QueryDeserializer, see bindValues. Query parameters are not bound.
+type FormDeserializer struct {
+	// Required. Returns an empty Inputable to bind into, a pointer to
+	// a struct.
+	New func() Inputable
+	// Maximum size of the request body. Larger bodies fail with a
+	// *BodyTooLargeError. Defaults to the 10MB limit of
+	// http.Request.ParseForm.
+	MaxBytes int64
+}
+
+func (f FormDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	limitBody(r, f.MaxBytes)
+	if err := r.ParseForm(); err != nil {
+		return nil, bodyError(err)
+	}
+	in := f.New()
+	if err := applyDefaults(in); err != nil {
+		return nil, err
+	}
+	if err := bindValues(in, "form", valuesLookup(r.PostForm)); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+var (
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// A Deserializer binding a multipart/form-data request body into the
+// fields of the Inputable returned by New which have a form tag. Value
+// parts are bound like in FormDeserializer; file parts are bound to
+// fields of type *multipart.FileHeader or []*multipart.FileHeader:
+//
+//	type Upload struct {
+//		Title       string                  `form:"title"`
+//		Document    *multipart.FileHeader   `form:"document"`
+//		Attachments []*multipart.FileHeader `form:"attachments"`
+//	}
+//
+// Temporary files of the upload are removed when the request ends.
+// Requests which are not multipart fail with an
+// *UnsupportedMediaTypeError.
+type MultipartDeserializer struct {
+	// Required. Returns an empty Inputable to bind into, a pointer to
+	// a struct.
+	New func() Inputable
+	// Maximum size of the request body. Larger bodies fail with a
+	// *BodyTooLargeError. No limit if zero.
+	MaxBytes int64
+	// Maximum size of the file parts kept in memory, the rest is
+	// stored in temporary files. Defaults to 32MB.
+	MaxMemory int64
+}
+
+func (m MultipartDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	max_memory := m.MaxMemory
+	if max_memory <= 0 {
+		max_memory = 32 << 20
+	}
+	limitBody(r, m.MaxBytes)
+	if err := r.ParseMultipartForm(max_memory); err != nil {
+		if errors.Is(err, http.ErrNotMultipart) {
+			return nil, &UnsupportedMediaTypeError{MediaType: r.Header.Get("Content-Type")}
+		}
+		return nil, bodyError(err)
+	}
+	form := r.MultipartForm
+	context.AfterFunc(r.Context(), func() {
+		form.RemoveAll()
+	})
+
+	in := m.New()
+	if err := applyDefaults(in); err != nil {
+		return nil, err
+	}
+	if err := bindValues(in, "form", valuesLookup(form.Value)); err != nil {
+		return nil, err
+	}
+	eachTaggedField(reflect.ValueOf(in).Elem(), "form", func(field taggedField) {
+		files := form.File[field.name]
+		if len(files) == 0 {
+			return
+		}
+		switch field.Type {
+		case fileHeaderType:
+			field.value.Set(reflect.ValueOf(files[0]))
+		case fileHeadersType:
+			field.value.Set(reflect.ValueOf(files))
+		}
+	})
+	return in, nil
+}
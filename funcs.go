@@ -0,0 +1,41 @@
+package resdk
+
+import (
+	"net/http"
+)
+
+// The AuthenticatorFunc type is an adapter to allow the use of
+// ordinary functions as an Authenticator.
+type AuthenticatorFunc func(r *http.Request) (interface{}, error)
+
+// Authenticate calls f(r)
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (interface{}, error) {
+	return f(r)
+}
+
+// The DeserializerFunc type is an adapter to allow the use of
+// ordinary functions as a Deserializer.
+type DeserializerFunc func(r *http.Request) (Inputable, error)
+
+// Deserialize calls f(r)
+func (f DeserializerFunc) Deserialize(r *http.Request) (Inputable, error) {
+	return f(r)
+}
+
+// The ProcessorFunc type is an adapter to allow the use of
+// ordinary functions as a Processor.
+type ProcessorFunc func(in Inputable) (Outputable, error)
+
+// Process calls f(in)
+func (f ProcessorFunc) Process(in Inputable) (Outputable, error) {
+	return f(in)
+}
+
+// The SerializerFunc type is an adapter to allow the use of
+// ordinary functions as a Serializer.
+type SerializerFunc func(out Outputable, w http.ResponseWriter, r *http.Request)
+
+// Serialize calls f(out, w, r)
+func (f SerializerFunc) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	f(out, w, r)
+}
@@ -0,0 +1,132 @@
+package resdk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var (
+	_ Authenticatable = AuthenticatorFunc(nil)
+	_ Deserializable  = DeserializerFunc(nil)
+	_ Processable     = ProcessorFunc(nil)
+	_ Serializable    = SerializerFunc(nil)
+)
+
+type funcsInput struct {
+	Name string
+}
+
+func (funcsInput) Validate() error {
+	return nil
+}
+
+func TestAuthenticatorFunc(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	want_err := errors.New("denied")
+	var got *http.Request
+	f := AuthenticatorFunc(func(r *http.Request) (interface{}, error) {
+		got = r
+		return "alice", want_err
+	})
+	details, err := f.Authenticate(req)
+	if got != req {
+		t.Errorf("request not forwarded")
+	}
+	if details != "alice" || err != want_err {
+		t.Errorf("got (%v, %v), want (alice, %v)", details, err, want_err)
+	}
+}
+
+func TestDeserializerFunc(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	want_err := errors.New("malformed")
+	var got *http.Request
+	f := DeserializerFunc(func(r *http.Request) (Inputable, error) {
+		got = r
+		return funcsInput{Name: "x"}, want_err
+	})
+	in, err := f.Deserialize(req)
+	if got != req {
+		t.Errorf("request not forwarded")
+	}
+	if in != (funcsInput{Name: "x"}) || err != want_err {
+		t.Errorf("got (%v, %v), want ({x}, %v)", in, err, want_err)
+	}
+}
+
+func TestProcessorFunc(t *testing.T) {
+	want_err := errors.New("failed")
+	var got Inputable
+	f := ProcessorFunc(func(in Inputable) (Outputable, error) {
+		got = in
+		return "out", want_err
+	})
+	out, err := f.Process(funcsInput{Name: "x"})
+	if got != (funcsInput{Name: "x"}) {
+		t.Errorf("input not forwarded, got %v", got)
+	}
+	if out != "out" || err != want_err {
+		t.Errorf("got (%v, %v), want (out, %v)", out, err, want_err)
+	}
+}
+
+func TestSerializerFunc(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	var got_out Outputable
+	var got_w http.ResponseWriter
+	var got_r *http.Request
+	f := SerializerFunc(func(out Outputable, w http.ResponseWriter, r *http.Request) {
+		got_out, got_w, got_r = out, w, r
+	})
+	f.Serialize("out", rec, req)
+	if got_out != "out" || got_w != rec || got_r != req {
+		t.Errorf("arguments not forwarded, got (%v, %v, %v)", got_out, got_w, got_r)
+	}
+}
+
+func TestFuncAdaptersInBaseHandler(t *testing.T) {
+	var auth_details interface{}
+	handler := &BaseHandler{
+		Authenticator: AuthenticatorFunc(func(r *http.Request) (interface{}, error) {
+			if r.Header.Get("Authorization") == "" {
+				return nil, errors.New("unauthenticated")
+			}
+			return r.Header.Get("Authorization"), nil
+		}),
+		Deserializer: DeserializerFunc(func(r *http.Request) (Inputable, error) {
+			auth_details = ExchangeFromRequest(r).AuthDetails
+			return funcsInput{Name: r.URL.Query().Get("name")}, nil
+		}),
+		Processor: ProcessorFunc(func(in Inputable) (Outputable, error) {
+			return "hello " + in.(funcsInput).Name, nil
+		}),
+		SuccessSerializer: SerializerFunc(func(out Outputable, w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(out.(string)))
+		}),
+		AuthenticationErrorSerializer: SerializerFunc(func(out Outputable, w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(out.(error).Error()))
+		}),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?name=bob", nil)
+	req.Header.Set("Authorization", "alice")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "hello bob" {
+		t.Errorf("got %d %q, want 200 %q", rec.Code, rec.Body.String(), "hello bob")
+	}
+	if auth_details != "alice" {
+		t.Errorf("got auth details %v, want alice", auth_details)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?name=bob", nil))
+	if rec.Code != http.StatusUnauthorized || rec.Body.String() != "unauthenticated" {
+		t.Errorf("got %d %q, want 401 %q", rec.Code, rec.Body.String(), "unauthenticated")
+	}
+}
@@ -0,0 +1,123 @@
+package resdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+)
+
+// Media type of HAL documents
+const HalMediaType = "application/hal+json"
+
+// Set of functions which can be optionally implemented by an
+// Outputable to advertise hypermedia links, e.g. self, related or
+// pagination links
+type Linkable interface {
+	// Returns link URLs by relation, e.g. {"self": "/users/1"}
+	Links() map[string]string
+}
+
+// A serializer for response as HAL (application/hal+json). Links of
+// Linkable Outputables are added as "_links". Slices are sent as an
+// object embedding the items (each with their own links) under
+// "_embedded", and the links of the slice type itself, if any.
+type HalSerializer struct {
+	// HTTP Status Code to be returned unless out implements
+	// StatusCoder
+	StatusCode int
+	// Name of the embedded relation for slices. Defaults to "items".
+	EmbeddedKey string
+}
+
+// Serializes Outputable to a ResponseWriter
+func (h HalSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	h.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors. Responds
+// with a 500 error if out cannot be marshaled.
+func (h HalSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	return writeMarshaled(w, out, HalMediaType, h.StatusCode, marshalFailureBody, func(buf *bytes.Buffer) error {
+		doc, err := h.document(out)
+		if err != nil {
+			return err
+		}
+		return encodeJson(buf, doc)
+	})
+}
+
+// Converts v into a HAL document
+func (h HalSerializer) document(v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			item, err := h.document(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		key := h.EmbeddedKey
+		if key == "" {
+			key = "items"
+		}
+		doc := map[string]interface{}{
+			"_embedded": map[string]interface{}{key: items},
+		}
+		addHalLinks(doc, v)
+		return doc, nil
+	}
+
+	links := halLinks(v)
+	if links == nil {
+		return v, nil
+	}
+	b, err := JsonEngine.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) < 2 || b[0] != '{' {
+		// Not an object, links cannot be added
+		return v, nil
+	}
+	links_json, err := JsonEngine.Marshal(links)
+	if err != nil {
+		return nil, err
+	}
+	// Spliced into the marshaled object, so its members keep their
+	// order and numbers their precision
+	doc := append([]byte(`{"_links":`), links_json...)
+	if members := bytes.TrimSpace(b[1 : len(b)-1]); len(members) > 0 {
+		doc = append(doc, ',')
+		doc = append(doc, members...)
+	}
+	return json.RawMessage(append(doc, '}')), nil
+}
+
+// Adds the links of v (if it is Linkable) to doc as "_links"
+func addHalLinks(doc map[string]interface{}, v interface{}) {
+	if links := halLinks(v); links != nil {
+		doc["_links"] = links
+	}
+}
+
+// Returns the links of v as HAL link objects, nil if v is not Linkable
+// or has no links
+func halLinks(v interface{}) map[string]interface{} {
+	linkable, ok := v.(Linkable)
+	if !ok {
+		return nil
+	}
+	links := linkable.Links()
+	if len(links) == 0 {
+		return nil
+	}
+	hal_links := make(map[string]interface{}, len(links))
+	for rel, href := range links {
+		hal_links[rel] = map[string]string{"href": href}
+	}
+	return hal_links
+}
@@ -0,0 +1,67 @@
+package resdk
+
+import (
+	"mime"
+	"net/http"
+)
+
+// A serializer adjusting the response headers of another Serializer,
+// for small tweaks which should not require a custom Serializable:
+//
+//	s := resdk.HeaderSerializer{
+//		Serializer: &resdk.JsonSerializer{StatusCode: http.StatusOK},
+//		Charset:    "utf-8",
+//		OnHeader: func(header http.Header, out resdk.Outputable, r *http.Request) {
+//			header.Set("X-Request-ID", r.Header.Get("X-Request-ID"))
+//		},
+//	}
+//
+// The adjustments are applied right before the header is written, after
+// the wrapped Serializer set its own headers.
+type HeaderSerializer struct {
+	// Required. Serializer producing the response.
+	Serializer Serializable
+	// Replaces the Content-Type of responses with a body, e.g.
+	// "application/vnd.api+json"
+	ContentType string
+	// Sets the charset parameter of the Content-Type, e.g. "utf-8"
+	Charset string
+	// Called with the response header to add, change or remove
+	// headers
+	OnHeader func(header http.Header, out Outputable, r *http.Request)
+}
+
+// Serializes Outputable to a ResponseWriter
+func (h HeaderSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	h.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns the error reported by the wrapped
+// Serializer if it is a CheckedSerializable
+func (h HeaderSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	hw := &headerWriter{ResponseWriter: w, onHeader: func(header http.Header) {
+		if content_type := header.Get("Content-Type"); content_type != "" {
+			header.Set("Content-Type", h.contentType(content_type))
+		}
+		if h.OnHeader != nil {
+			h.OnHeader(header, out, r)
+		}
+	}}
+	return callSerializer(h.Serializer, out, hw, r)
+}
+
+// Returns the Content-Type replacing content_type
+func (h HeaderSerializer) contentType(content_type string) string {
+	if h.ContentType != "" {
+		content_type = h.ContentType
+	}
+	if h.Charset == "" {
+		return content_type
+	}
+	media_type, params, err := mime.ParseMediaType(content_type)
+	if err != nil {
+		return content_type
+	}
+	params["charset"] = h.Charset
+	return mime.FormatMediaType(media_type, params)
+}
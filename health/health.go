@@ -0,0 +1,258 @@
+// Package health provides liveness and readiness endpoints aggregating
+// checks of the dependencies of a service, such as databases, caches
+// and downstream APIs:
+//
+//	checks := health.New()
+//	checks.Register("db", health.Pinger(db))
+//	checks.Register("billing", health.HTTPGet("http://billing/healthz"))
+//	checks.RegisterOptional("cache", health.Pinger(cache))
+//	http.Handle("/livez", checks.LivenessHandler())
+//	http.Handle("/readyz", checks.ReadinessHandler())
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Checks a dependency of the service
+type Checker interface {
+	// Returns nil if the dependency is usable. ctx carries the
+	// timeout of the check.
+	Check(ctx context.Context) error
+}
+
+// The CheckerFunc type is an adapter to allow the use of ordinary
+// functions as a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx)
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// A client which can be pinged, such as *sql.DB
+type Pingable interface {
+	PingContext(ctx context.Context) error
+}
+
+// A Checker pinging p
+func Pinger(p Pingable) Checker {
+	return CheckerFunc(p.PingContext)
+}
+
+// A Checker requesting url, which must respond with a 2xx status
+func HTTPGet(url string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("%s responded %s", url, resp.Status)
+		}
+		return nil
+	})
+}
+
+// Status of a check or of the whole service
+type Status string
+
+const (
+	StatusUp Status = "up"
+	// Optional checks failed, the service still works
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+	// The service is shutting down, see Health.SetReady
+	StatusShuttingDown Status = "shutting_down"
+)
+
+// Outcome of a single check
+type Result struct {
+	Status Status `json:"status"`
+	// Message of the failure
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_ms"`
+	Optional bool    `json:"optional,omitempty"`
+}
+
+// Outcome of all checks, the body of the readiness response:
+//
+//	{"status": "down", "checks": {"db": {"status": "down",
+//		"error": "dial tcp: connection refused", "duration_ms": 1.2}}}
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// A set of named checks. Use New to create one.
+type Health struct {
+	// Maximum duration of each check. Defaults to 5 seconds.
+	Timeout time.Duration
+
+	mu     sync.RWMutex
+	checks []check
+	// Set until SetReady(false) is called
+	ready atomic.Bool
+}
+
+type check struct {
+	name     string
+	checker  Checker
+	optional bool
+}
+
+// Creates a Health without checks which is ready
+func New() *Health {
+	h := &Health{}
+	h.ready.Store(true)
+	return h
+}
+
+// Adds a check which makes the service unready when it fails
+func (h *Health) Register(name string, c Checker) {
+	h.register(check{name: name, checker: c})
+}
+
+// Adds a check which only degrades the service when it fails, e.g. of
+// a cache the service can work without
+func (h *Health) RegisterOptional(name string, c Checker) {
+	h.register(check{name: name, checker: c, optional: true})
+}
+
+func (h *Health) register(c check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, c)
+}
+
+// Marks the service as (not) ready. Call SetReady(false) at the start
+// of a graceful shutdown, so load balancers stop sending requests
+// before the server closes.
+func (h *Health) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// Runs all checks concurrently and returns their outcome
+func (h *Health) Run(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := h.checks
+	h.mu.RUnlock()
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c, timeout)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status == StatusUp {
+			continue
+		}
+		if !c.optional {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	if !h.ready.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+// Runs c with timeout. A panicking checker fails.
+func runCheck(ctx context.Context, c check, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		done <- c.checker.Check(ctx)
+	}()
+	// Checkers ignoring ctx must not hold up the report
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := Result{
+		Status:   StatusUp,
+		Duration: float64(time.Since(start)) / float64(time.Millisecond),
+		Optional: c.optional,
+	}
+	if err != nil {
+		result.Status, result.Error = StatusDown, err.Error()
+	}
+	return result
+}
+
+// Returns a handler telling whether the process is alive. It always
+// responds 200 with {"status": "up"} and runs no checks, since
+// restarting the service does not fix its dependencies.
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, r, Report{Status: StatusUp})
+	})
+}
+
+// Returns a handler running all checks. It responds with the Report
+// and 200 if the service is up or degraded, 503 Service Unavailable if
+// a required check failed or the service is shutting down.
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, r, h.Run(r.Context()))
+	})
+}
+
+// Marshals the Json reports of the handlers. Set it to the Marshal of
+// resdk.JsonEngine during initialization, before any handler serves
+// requests, so reports use the same Json implementation as the API.
+var JsonMarshal = json.Marshal
+
+// Writes report with the status code matching its Status
+func writeReport(w http.ResponseWriter, r *http.Request, report Report) {
+	status := http.StatusOK
+	if report.Status == StatusDown || report.Status == StatusShuttingDown {
+		status = http.StatusServiceUnavailable
+	}
+	body, err := JsonMarshal(report)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write(append(body, '\n'))
+	}
+}
@@ -0,0 +1,194 @@
+package resdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Error for a request whose HMAC signature is missing parts, wrong,
+// too old or replayed. Responds with 401 Unauthorized.
+type InvalidSignatureError struct {
+	// Why the signature was rejected
+	Reason string
+}
+
+func (e *InvalidSignatureError) Error() string {
+	return "invalid signature: " + e.Reason
+}
+
+func (e *InvalidSignatureError) StatusCode() int {
+	return http.StatusUnauthorized
+}
+
+// Returns the bytes an HMAC request signature is computed over
+type HmacCanonicalizer func(r *http.Request, timestamp string, body []byte) []byte
+
+// Signs the method, the path with query, the timestamp and the body,
+// separated by newlines
+func DefaultHmacCanonicalizer(r *http.Request, timestamp string, body []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(r.Method)
+	buf.WriteByte('\n')
+	buf.WriteString(r.URL.RequestURI())
+	buf.WriteByte('\n')
+	buf.WriteString(timestamp)
+	buf.WriteByte('\n')
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// An Authenticator for server to server and webhook style callers which
+// sign each request with a shared secret. Callers send
+//
+//	X-Key-Id: <key id>
+//	X-Timestamp: <unix seconds>
+//	X-Signature: <hex HMAC of the canonical request>
+//
+// Requests whose timestamp is more than MaxSkew away from now are
+// rejected, as are repeated signatures within that window, so captured
+// requests cannot be replayed. The key id is returned as auth details.
+// Requests without a signature fail with ErrNoCredentials, others with
+// *InvalidSignatureError.
+//
+// The body is read completely and replaced, so it can still be
+// deserialized. Use NewHmacAuthenticator to create one.
+type HmacAuthenticator struct {
+	// Required. Returns the secret of key_id, nil if it is unknown.
+	Secret func(ctx context.Context, key_id string) ([]byte, error)
+	// Defaults to DefaultHmacCanonicalizer
+	Canonicalize HmacCanonicalizer
+	// Defaults to sha256.New
+	Hash func() hash.Hash
+	// Maximum distance between timestamp and clock, also the replay
+	// window. Defaults to 5 minutes.
+	MaxSkew time.Duration
+	// Largest body read. Defaults to 10MB.
+	MaxBodyBytes int64
+	// Header names. Default to X-Key-Id, X-Timestamp and X-Signature.
+	KeyIdHeader     string
+	TimestampHeader string
+	SignatureHeader string
+	// Records used signatures. Defaults to a MemoryNonceStore of the
+	// authenticator; instances of a cluster need a shared store.
+	Nonces NonceStore
+
+	mu     sync.Mutex
+	memory *MemoryNonceStore
+}
+
+// Creates an HmacAuthenticator looking up secrets with secret
+func NewHmacAuthenticator(secret func(ctx context.Context, key_id string) ([]byte, error)) *HmacAuthenticator {
+	return &HmacAuthenticator{Secret: secret}
+}
+
+func (h *HmacAuthenticator) Authenticate(r *http.Request) (interface{}, error) {
+	key_id := r.Header.Get(orDefault(h.KeyIdHeader, "X-Key-Id"))
+	timestamp := r.Header.Get(orDefault(h.TimestampHeader, "X-Timestamp"))
+	signature := r.Header.Get(orDefault(h.SignatureHeader, "X-Signature"))
+	if signature == "" {
+		return nil, ErrNoCredentials
+	}
+	if key_id == "" || timestamp == "" {
+		return nil, &InvalidSignatureError{Reason: "missing key id or timestamp"}
+	}
+	max_skew := h.MaxSkew
+	if max_skew <= 0 {
+		max_skew = 5 * time.Minute
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, &InvalidSignatureError{Reason: "malformed timestamp"}
+	}
+	now := time.Now()
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > max_skew || skew < -max_skew {
+		return nil, &InvalidSignatureError{Reason: "timestamp outside of allowed window"}
+	}
+	mac, err := hex.DecodeString(signature)
+	if err != nil {
+		return nil, &InvalidSignatureError{Reason: "malformed signature"}
+	}
+
+	secret, err := h.Secret(r.Context(), key_id)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, &InvalidSignatureError{Reason: "unknown key"}
+	}
+	body, err := h.readBody(r)
+	if err != nil {
+		return nil, err
+	}
+	canonicalize := h.Canonicalize
+	if canonicalize == nil {
+		canonicalize = DefaultHmacCanonicalizer
+	}
+	hash_func := h.Hash
+	if hash_func == nil {
+		hash_func = sha256.New
+	}
+	expected := hmac.New(hash_func, secret)
+	expected.Write(canonicalize(r, timestamp, body))
+	if !hmac.Equal(mac, expected.Sum(nil)) {
+		return nil, &InvalidSignatureError{Reason: "signature mismatch"}
+	}
+	fresh, err := h.nonces().Use(r.Context(), "hmac:"+hex.EncodeToString(mac), now.Add(2*max_skew))
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, &InvalidSignatureError{Reason: "replayed request"}
+	}
+	return key_id, nil
+}
+
+// Reads the body and puts it back for the Deserializer
+func (h *HmacAuthenticator) readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	max_bytes := h.MaxBodyBytes
+	if max_bytes <= 0 {
+		max_bytes = 10 << 20
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, max_bytes+1))
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > max_bytes {
+		return nil, &BodyTooLargeError{Limit: max_bytes}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// Returns the NonceStore for signatures
+func (h *HmacAuthenticator) nonces() NonceStore {
+	if h.Nonces != nil {
+		return h.Nonces
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.memory == nil {
+		h.memory = NewMemoryNonceStore()
+	}
+	return h.memory
+}
+
+// Returns value, or def if it is empty
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
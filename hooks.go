@@ -0,0 +1,44 @@
+package resdk
+
+import (
+	"net/http"
+	"runtime/debug"
+)
+
+// Side effect to run after a successful response was serialized, e.g.
+// emitting domain events, enqueueing webhooks or invalidating caches.
+type SuccessHook interface {
+	// Called with the Outputable sent as the response, the
+	// authentication details and the request. The returned error is
+	// passed to BaseHandler.OnHookError and never affects the response.
+	AfterSuccess(out Outputable, auth_details interface{}, r *http.Request) error
+}
+
+// The SuccessHookFunc type is an adapter to allow the use of
+// ordinary functions as a SuccessHook.
+type SuccessHookFunc func(out Outputable, auth_details interface{}, r *http.Request) error
+
+// AfterSuccess calls f(out, auth_details, r)
+func (f SuccessHookFunc) AfterSuccess(out Outputable, auth_details interface{}, r *http.Request) error {
+	return f(out, auth_details, r)
+}
+
+// Runs the AfterSuccess hooks in order. A failing or panicking hook
+// does not stop the following ones.
+func (m *BaseHandler) runSuccessHooks(out Outputable, auth_details interface{}, r *http.Request) {
+	for _, hook := range m.AfterSuccess {
+		if err := runSuccessHook(hook, out, auth_details, r); err != nil && m.OnHookError != nil {
+			m.OnHookError(r, err)
+		}
+	}
+}
+
+// Runs a single hook converting a panic into a *PanicError
+func runSuccessHook(hook SuccessHook, out Outputable, auth_details interface{}, r *http.Request) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = &PanicError{Value: rec, Stack: debug.Stack()}
+		}
+	}()
+	return hook.AfterSuccess(out, auth_details, r)
+}
@@ -0,0 +1,287 @@
+package resdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Error for a request whose Idempotency-Key is still being processed
+// by another request. Responds with 409 Conflict.
+var ErrIdempotencyInProgress error = idempotencyInProgressError{}
+
+type idempotencyInProgressError struct{}
+
+func (idempotencyInProgressError) Error() string {
+	return "A request with this Idempotency-Key is in progress"
+}
+
+func (idempotencyInProgressError) StatusCode() int {
+	return http.StatusConflict
+}
+
+// Error for an Idempotency-Key reused with a different request.
+// Responds with 422 Unprocessable Entity.
+var ErrIdempotencyKeyReused error = idempotencyKeyReusedError{}
+
+type idempotencyKeyReusedError struct{}
+
+func (idempotencyKeyReusedError) Error() string {
+	return "Idempotency-Key was used for a different request"
+}
+
+func (idempotencyKeyReusedError) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// Error for an Idempotency-Key sent by an anonymous caller, whose
+// responses cannot be kept apart from those of other anonymous callers.
+// Responds with 400 Bad Request.
+var ErrIdempotencyAnonymous error = idempotencyAnonymousError{}
+
+type idempotencyAnonymousError struct{}
+
+func (idempotencyAnonymousError) Error() string {
+	return "Idempotency-Key requires authentication"
+}
+
+func (idempotencyAnonymousError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// A response recorded for an Idempotency-Key
+type IdempotentResponse struct {
+	// Hash of the method, path and body of the original request
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+// Storage of the responses for Idempotency-Keys. Begin must be atomic
+// across instances sharing the store.
+type IdempotencyStore interface {
+	// Reserves key for a request with fingerprint until expires.
+	// Returns the recorded response if key completed before,
+	// ErrIdempotencyInProgress if it is reserved and (nil, nil) if
+	// the caller got the reservation.
+	Begin(ctx context.Context, key, fingerprint string, expires time.Time) (*IdempotentResponse, error)
+	// Records the response for a reserved key until expires
+	Complete(ctx context.Context, key string, response *IdempotentResponse, expires time.Time) error
+	// Drops the reservation of key, so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// An IdempotencyStore keeping responses in memory. Use
+// NewMemoryIdempotencyStore to create one.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	// nil while in progress
+	response *IdempotentResponse
+	expires  time.Time
+}
+
+// Creates an empty MemoryIdempotencyStore
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+func (s *MemoryIdempotencyStore) Begin(ctx context.Context, key, fingerprint string, expires time.Time) (*IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, entry := range s.entries {
+		if now.After(entry.expires) {
+			delete(s.entries, k)
+		}
+	}
+	if entry, ok := s.entries[key]; ok {
+		if entry.response == nil {
+			return nil, ErrIdempotencyInProgress
+		}
+		if entry.fingerprint != fingerprint {
+			return nil, ErrIdempotencyKeyReused
+		}
+		return entry.response, nil
+	}
+	s.entries[key] = &idempotencyEntry{fingerprint: fingerprint, expires: expires}
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key string, response *IdempotentResponse, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = &idempotencyEntry{fingerprint: response.Fingerprint, response: response, expires: expires}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Idempotency-Key support of a BaseHandler, so clients can safely retry
+// unsafe requests such as payments. The first request with a key is
+// processed and its response recorded; retries with the same key get
+// the recorded response with an Idempotent-Replayed header. Retries
+// while the first request is still processed fail with
+// ErrIdempotencyInProgress, reusing a key for another request with
+// ErrIdempotencyKeyReused, both through
+// DeserializationErrorSerializer.
+//
+// Keys are scoped by the caller (see PrincipalID), so clients cannot
+// see each other's responses; anonymous callers sending a key fail with
+// ErrIdempotencyAnonymous. 5xx responses are not recorded, so requests
+// failing on the server can be retried with the same key. Set-Cookie
+// and hop-by-hop headers are not recorded either.
+type IdempotencyConfig struct {
+	// Required
+	Store IdempotencyStore
+	// How long responses are kept. Defaults to 24 hours.
+	TTL time.Duration
+	// Defaults to "Idempotency-Key"
+	Header string
+	// Methods supporting keys. Defaults to POST and PATCH.
+	Methods []string
+}
+
+// Starts idempotent processing of r if it carries a key, reading at
+// most max_bytes of its body (no limit if not positive). Returns the
+// writer to serve r with and a function recording its response, which
+// must be called when serving ends. Returns true if a recorded response
+// was replayed instead, in which case nothing else must be written.
+func (c *IdempotencyConfig) begin(w http.ResponseWriter, r *http.Request, auth_details interface{}, max_bytes int64) (http.ResponseWriter, func(), bool, error) {
+	methods := c.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	key := r.Header.Get(orDefault(c.Header, "Idempotency-Key"))
+	if key == "" || !containsString(methods, r.Method) {
+		return w, func() {}, false, nil
+	}
+	principal := PrincipalID(auth_details)
+	if principal == "" {
+		return w, nil, false, ErrIdempotencyAnonymous
+	}
+	key = principal + "\x00" + key
+
+	body, err := readBody(r, max_bytes)
+	if err != nil {
+		return w, nil, false, bodyError(err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	sum := sha256.New()
+	io.WriteString(sum, r.Method+" "+r.URL.RequestURI()+"\n")
+	sum.Write(body)
+	fingerprint := hex.EncodeToString(sum.Sum(nil))
+
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	recorded, err := c.Store.Begin(r.Context(), key, fingerprint, time.Now().Add(ttl))
+	if err != nil {
+		return w, nil, false, err
+	}
+	if recorded != nil {
+		if recorded.Fingerprint != fingerprint {
+			return w, nil, false, ErrIdempotencyKeyReused
+		}
+		for name, values := range recorded.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(recorded.Status)
+		w.Write(recorded.Body)
+		return w, nil, true, nil
+	}
+
+	recorder := &idempotencyRecorder{ResponseWriter: w}
+	finish := func() {
+		ctx := context.WithoutCancel(r.Context())
+		if recorder.status == 0 || recorder.status >= 500 {
+			c.Store.Release(ctx, key)
+			return
+		}
+		c.Store.Complete(ctx, key, &IdempotentResponse{
+			Fingerprint: fingerprint,
+			Status:      recorder.status,
+			Header:      replayableHeader(recorder.header),
+			Body:        recorder.body.Bytes(),
+		}, time.Now().Add(ttl))
+	}
+	return recorder, finish, false, nil
+}
+
+// Headers of a response which must not be replayed: cookies, which
+// would hand the session of the original request to the retry, and
+// hop-by-hop headers (RFC 9110)
+var unreplayableHeaders = []string{
+	"Set-Cookie",
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Transfer-Encoding",
+	"Upgrade",
+	"Trailer",
+	"Te",
+}
+
+// Returns header without the unreplayableHeaders and those named by
+// Connection
+func replayableHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			header.Del(strings.TrimSpace(name))
+		}
+	}
+	for _, name := range unreplayableHeaders {
+		header.Del(name)
+	}
+	return header
+}
+
+// A ResponseWriter recording the status, header and body written
+// through it
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (i *idempotencyRecorder) WriteHeader(code int) {
+	if i.status == 0 {
+		i.status = code
+		i.header = i.Header().Clone()
+	}
+	i.ResponseWriter.WriteHeader(code)
+}
+
+func (i *idempotencyRecorder) Write(p []byte) (int, error) {
+	if i.status == 0 {
+		i.WriteHeader(http.StatusOK)
+	}
+	i.body.Write(p)
+	return i.ResponseWriter.Write(p)
+}
+
+func (i *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return i.ResponseWriter
+}
@@ -0,0 +1,121 @@
+package resdk
+
+import (
+	"regexp"
+)
+
+// Inputable for handlers of a single resource addressed by an integer
+// id in the path, e.g. GET /users/{id}:
+//
+//	resdk.PathDeserializer{New: resdk.NewIDInput}
+type IDInput struct {
+	ID int64 `path:"id" json:"id"`
+}
+
+// Returns an empty *IDInput, for use as New of the deserializers
+func NewIDInput() Inputable {
+	return &IDInput{}
+}
+
+// Fails with *ValidationErrors unless ID is positive
+func (i *IDInput) Validate() error {
+	errs := NewValidationErrors()
+	if i.ID <= 0 {
+		errs.Add("id", "min", "must be a positive integer")
+	}
+	return errs.Err()
+}
+
+// Inputable for handlers of a single resource addressed by a UUID in
+// the path, e.g. DELETE /orders/{id}:
+//
+//	resdk.PathDeserializer{New: resdk.NewUUIDInput}
+type UUIDInput struct {
+	ID UUID `path:"id" json:"id"`
+}
+
+// Returns an empty *UUIDInput, for use as New of the deserializers
+func NewUUIDInput() Inputable {
+	return &UUIDInput{}
+}
+
+// Fails with *ValidationErrors if ID is missing or the nil UUID
+func (i *UUIDInput) Validate() error {
+	errs := NewValidationErrors()
+	if i.ID == (UUID{}) {
+		errs.Add("id", "required", "is required")
+	}
+	return errs.Err()
+}
+
+// Lowercase words separated by single dashes
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// Longest slug accepted by SlugInput
+const MaxSlugLength = 128
+
+// Inputable for handlers of a single resource addressed by a slug in
+// the path, e.g. GET /posts/{slug}:
+//
+//	resdk.PathDeserializer{New: resdk.NewSlugInput}
+type SlugInput struct {
+	Slug string `path:"slug" json:"slug"`
+}
+
+// Returns an empty *SlugInput, for use as New of the deserializers
+func NewSlugInput() Inputable {
+	return &SlugInput{}
+}
+
+// Fails with *ValidationErrors unless Slug consists of lowercase
+// letters, digits and single dashes and is at most MaxSlugLength long
+func (s *SlugInput) Validate() error {
+	errs := NewValidationErrors()
+	switch {
+	case s.Slug == "":
+		errs.Add("slug", "required", "is required")
+	case len(s.Slug) > MaxSlugLength:
+		errs.Add("slug", "max", "is too long")
+	case !slugPattern.MatchString(s.Slug):
+		errs.Add("slug", "format", "must consist of lowercase letters, digits and dashes")
+	}
+	return errs.Err()
+}
+
+// Inputable for list handlers, e.g. GET /users?page=2&sort=-created_at
+// &filter=status:eq:active:
+//
+//	resdk.QueryDeserializer{New: resdk.NewListInput}
+//
+// Validate checks only the Pagination; Sort and Filter accept any
+// field. Handlers exposing them embed ListInput and check them against
+// their allowlists:
+//
+//	type ListUsers struct {
+//		resdk.ListInput
+//	}
+//
+//	func (l *ListUsers) Validate() error {
+//		if err := l.ListInput.Validate(); err != nil {
+//			return err
+//		}
+//		if err := l.Sort.Check("sort", userSorts); err != nil {
+//			return err
+//		}
+//		return l.Filter.Check("filter", userFilters)
+//	}
+type ListInput struct {
+	Pagination
+	Sort   Sort   `query:"sort" json:"sort,omitempty"`
+	Filter Filter `query:"filter" json:"filter,omitempty"`
+}
+
+// Returns an empty *ListInput, for use as New of the deserializers
+func NewListInput() Inputable {
+	return &ListInput{}
+}
+
+// Validates the Pagination with DefaultPageLimit and MaxPageLimit
+func (l *ListInput) Validate() error {
+	return l.Pagination.Validate()
+}
@@ -0,0 +1,207 @@
+package resdk
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Deserializes a request with the path parameter name set to value
+func deserializePath(t *testing.T, new_input func() Inputable, name, value string) (Inputable, error) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetPathValue(name, value)
+	return PathDeserializer{New: new_input}.Deserialize(r)
+}
+
+// Fails t unless err is a *ValidationErrors with code for field
+func expectValidationError(t *testing.T, err error, field, code string) {
+	t.Helper()
+	var verrs *ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("got %v, want validation errors", err)
+	}
+	for _, verr := range verrs.Field(field) {
+		if verr.Code == code {
+			return
+		}
+	}
+	t.Errorf("got %v, want %s error for %s", err, code, field)
+}
+
+// Fails t unless err is a BindingErrors for field
+func expectBindingError(t *testing.T, err error, field string) {
+	t.Helper()
+	var berrs BindingErrors
+	if !errors.As(err, &berrs) {
+		t.Fatalf("got %v, want binding errors", err)
+	}
+	for _, berr := range berrs {
+		if berr.Field == field {
+			return
+		}
+	}
+	t.Errorf("got %v, want binding error for %s", err, field)
+}
+
+func TestIDInput(t *testing.T) {
+	in, err := deserializePath(t, NewIDInput, "id", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = in.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if id := in.(*IDInput).ID; id != 42 {
+		t.Errorf("got id %d, want 42", id)
+	}
+
+	for _, value := range []string{"abc", "4.2", "99999999999999999999"} {
+		_, err = deserializePath(t, NewIDInput, "id", value)
+		expectBindingError(t, err, "id")
+	}
+
+	for _, value := range []string{"", "0", "-3"} {
+		in, err = deserializePath(t, NewIDInput, "id", value)
+		if err != nil {
+			t.Fatalf("%q: %v", value, err)
+		}
+		expectValidationError(t, in.Validate(), "id", "min")
+	}
+}
+
+func TestUUIDInput(t *testing.T) {
+	const id = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	in, err := deserializePath(t, NewUUIDInput, "id", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = in.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := in.(*UUIDInput).ID.String(); got != id {
+		t.Errorf("got id %s, want %s", got, id)
+	}
+
+	for _, value := range []string{"zz", "6ba7b810-9dad-11d1-80b4", "6ba7b810-9dad-11d1-80b4-00c04fd430cg"} {
+		_, err = deserializePath(t, NewUUIDInput, "id", value)
+		expectBindingError(t, err, "id")
+	}
+
+	for _, value := range []string{"", "00000000-0000-0000-0000-000000000000"} {
+		in, err = deserializePath(t, NewUUIDInput, "id", value)
+		if err != nil {
+			t.Fatalf("%q: %v", value, err)
+		}
+		expectValidationError(t, in.Validate(), "id", "required")
+	}
+}
+
+func TestSlugInput(t *testing.T) {
+	for _, value := range []string{"hello", "hello-world-2", strings.Repeat("a", MaxSlugLength)} {
+		in, err := deserializePath(t, NewSlugInput, "slug", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = in.Validate(); err != nil {
+			t.Errorf("%q: %v", value, err)
+		}
+	}
+
+	tests := []struct {
+		slug string
+		code string
+	}{
+		{"", "required"},
+		{strings.Repeat("a", MaxSlugLength+1), "max"},
+		{"Hello", "format"},
+		{"hello--world", "format"},
+		{"-hello", "format"},
+		{"hello-", "format"},
+		{"hello world", "format"},
+		{"héllo", "format"},
+	}
+	for _, test := range tests {
+		in, err := deserializePath(t, NewSlugInput, "slug", test.slug)
+		if err != nil {
+			t.Fatalf("%q: %v", test.slug, err)
+		}
+		expectValidationError(t, in.Validate(), "slug", test.code)
+	}
+}
+
+// Deserializes and validates a ListInput from query
+func validateListInput(t *testing.T, query string) (*ListInput, error) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+	in, err := QueryDeserializer{New: NewListInput}.Deserialize(r)
+	if err != nil {
+		return nil, err
+	}
+	return in.(*ListInput), in.Validate()
+}
+
+func TestListInputDefaults(t *testing.T) {
+	in, err := validateListInput(t, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.Page != 1 || in.Limit != DefaultPageLimit || in.Offset() != 0 {
+		t.Errorf("got page %d limit %d offset %d, want 1 %d 0", in.Page, in.Limit, in.Offset(), DefaultPageLimit)
+	}
+	if len(in.Sort) != 0 || len(in.Filter) != 0 {
+		t.Errorf("got sort %v filter %v, want none", in.Sort, in.Filter)
+	}
+}
+
+func TestListInputBinding(t *testing.T) {
+	in, err := validateListInput(t, "page=3&limit=10&sort=-created_at,name&filter=status:eq:active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.Page != 3 || in.Limit != 10 || in.Offset() != 20 {
+		t.Errorf("got page %d limit %d offset %d, want 3 10 20", in.Page, in.Limit, in.Offset())
+	}
+	want_sort := Sort{{Field: "created_at", Desc: true}, {Field: "name"}}
+	if len(in.Sort) != 2 || in.Sort[0] != want_sort[0] || in.Sort[1] != want_sort[1] {
+		t.Errorf("got sort %v, want %v", in.Sort, want_sort)
+	}
+	if len(in.Filter) != 1 || in.Filter[0].Field != "status" || in.Filter[0].Op != FilterEq {
+		t.Errorf("got filter %v, want status:eq:active", in.Filter)
+	}
+
+	in, err = validateListInput(t, "limit="+strconv.Itoa(MaxPageLimit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.Limit != MaxPageLimit {
+		t.Errorf("got limit %d, want %d", in.Limit, MaxPageLimit)
+	}
+
+	for _, query := range []string{"page=abc", "limit=1.5", "limit=ten"} {
+		_, err = validateListInput(t, query)
+		expectBindingError(t, err, strings.Split(query, "=")[0])
+	}
+}
+
+func TestListInputBounds(t *testing.T) {
+	tests := []struct {
+		query string
+		field string
+		code  string
+	}{
+		{"page=-1", "page", "min"},
+		{"page=" + strconv.Itoa(math.MaxInt), "page", "max"},
+		{"limit=-1", "limit", "min"},
+		{"limit=" + strconv.Itoa(MaxPageLimit+1), "limit", "max"},
+		{"page=2&cursor=abc", "cursor", "mutually_exclusive"},
+	}
+	for _, test := range tests {
+		_, err := validateListInput(t, test.query)
+		expectValidationError(t, err, test.field, test.code)
+	}
+}
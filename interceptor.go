@@ -0,0 +1,72 @@
+package resdk
+
+import (
+	"context"
+	"net/http"
+)
+
+// A phase of the request lifecycle
+type Phase string
+
+const (
+	// Spans the complete request, all other phases run inside it
+	PhaseRequest Phase = "request"
+	// Authentication by the Authenticator
+	PhaseAuthenticate Phase = "authenticate"
+	// Deserialization by the Deserializer
+	PhaseDeserialize Phase = "deserialize"
+	// Validation of the Inputable
+	PhaseValidate Phase = "validate"
+	// Validation of an ExternalValidatable Inputable against external
+	// systems
+	PhaseValidateExternal Phase = "validate_external"
+	// Processing by the Processor
+	PhaseProcess Phase = "process"
+	// Serialization of the success or error response
+	PhaseSerialize Phase = "serialize"
+)
+
+// Hooks into every phase of the request lifecycle. Interceptors allow
+// for cross-cutting concerns like logging, metrics and tracing without
+// wrapping every phase implementation by hand.
+//
+// BaseHandler calls Before of all its Interceptors in order when a
+// phase starts and After in reverse order when it ends. PhaseRequest
+// starts before and ends after all other phases.
+type Interceptor interface {
+	// Called when phase starts. The returned context is used as the
+	// request context during the phase (and, for PhaseRequest, during
+	// all following phases). Return ctx if nothing needs to be added.
+	Before(ctx context.Context, phase Phase, r *http.Request) context.Context
+	// Called when phase ends. ctx is the context returned by Before.
+	// err is the error which ended the phase or nil if it succeeded.
+	// For PhaseRequest err is the error which ended the request.
+	After(ctx context.Context, phase Phase, r *http.Request, err error)
+}
+
+// Calls Before of all Interceptors and returns r with the resulting
+// context. Also starts timing phase on the Exchange.
+func (m *BaseHandler) before(r *http.Request, phase Phase) *http.Request {
+	if x := ExchangeFromRequest(r); x != nil {
+		x.startPhase(phase)
+	}
+	if len(m.Interceptors) == 0 {
+		return r
+	}
+	ctx := r.Context()
+	for _, i := range m.Interceptors {
+		ctx = i.Before(ctx, phase, r)
+	}
+	return r.WithContext(ctx)
+}
+
+// Calls After of all Interceptors in reverse order. r must be the
+// request returned by before. Also stops timing phase on the Exchange.
+func (m *BaseHandler) after(r *http.Request, phase Phase, err error) {
+	if x := ExchangeFromRequest(r); x != nil {
+		x.endPhase(phase, err)
+	}
+	for i := len(m.Interceptors) - 1; i >= 0; i-- {
+		m.Interceptors[i].After(r.Context(), phase, r, err)
+	}
+}
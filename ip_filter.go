@@ -0,0 +1,173 @@
+package resdk
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Error for a request from a client address which is denied or not
+// allowed. Responds with 403 Forbidden.
+var ErrIpForbidden error = ipForbiddenError{}
+
+type ipForbiddenError struct{}
+
+func (ipForbiddenError) Error() string {
+	return "Forbidden"
+}
+
+func (ipForbiddenError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+// Parses CIDR prefixes and plain addresses (which become single
+// address prefixes) and panics on invalid ones, for configuration:
+//
+//	resdk.MustParsePrefixes("10.0.0.0/8", "192.168.1.7", "fd00::/8")
+func MustParsePrefixes(values ...string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, len(values))
+	for i, value := range values {
+		if strings.Contains(value, "/") {
+			prefixes[i] = netip.MustParsePrefix(value)
+			continue
+		}
+		addr := netip.MustParseAddr(value)
+		prefixes[i] = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	return prefixes
+}
+
+// Reports whether any of prefixes contains addr
+func prefixesContain(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Forwarding header the trusted proxies of ClientIP append the address
+// of their peer to
+type ProxyHeader string
+
+const (
+	// X-Forwarded-For, a comma separated list of addresses
+	XForwardedForHeader ProxyHeader = "X-Forwarded-For"
+	// Forwarded (RFC 7239), whose for= parameters list the addresses
+	ForwardedHeader ProxyHeader = "Forwarded"
+)
+
+// Returns the address of the client of r. If the peer is one of
+// trusted_proxies, the chain of addresses in header, the one the
+// proxies append to, is followed from right to left across trusted
+// proxies: the client is the rightmost address not belonging to a
+// trusted proxy, since addresses further left may be forged by the
+// client. Other forwarding headers are ignored, as clients can send
+// them through the proxies.
+//
+// Returns the zero Addr if RemoteAddr cannot be parsed, or if the peer
+// is a trusted proxy but header is empty.
+func ClientIP(r *http.Request, trusted_proxies []netip.Prefix, header ProxyHeader) netip.Addr {
+	client := parseForwardedAddr(r.RemoteAddr)
+	if !client.IsValid() || !prefixesContain(trusted_proxies, client) {
+		return client
+	}
+	var hops []string
+	switch header {
+	case ForwardedHeader:
+		hops = forwardedFor(r.Header.Values("Forwarded"))
+	case XForwardedForHeader:
+		for _, value := range r.Header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(value, ",")...)
+		}
+	default:
+		return netip.Addr{}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseForwardedAddr(hops[i])
+		if !hop.IsValid() {
+			// Obfuscated or unknown, nothing further left can be trusted
+			break
+		}
+		client = hop
+		if !prefixesContain(trusted_proxies, hop) {
+			break
+		}
+	}
+	return client
+}
+
+// Returns the for= parameters of Forwarded header values in order
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				name, node, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(name, "for") {
+					hops = append(hops, strings.Trim(node, `"`))
+				}
+			}
+		}
+	}
+	return hops
+}
+
+// Parses an address with an optional port, e.g. "203.0.113.7",
+// "203.0.113.7:443", "[2001:db8::1]:443" or "2001:db8::1"
+func parseForwardedAddr(value string) netip.Addr {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(value, "[]"))
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+// An Authenticator admitting requests by client address, e.g. for
+// admin endpoints reachable only from the office network. Denied
+// addresses fail with ErrIpForbidden, as do addresses not in Allow if
+// it is not empty.
+//
+// Alone it returns the client netip.Addr as auth details. With Next it
+// runs in front of another Authenticator (or a MultiAuthenticator) and
+// returns its auth details instead:
+//
+//	resdk.IpFilterAuthenticator{
+//		Allow:          resdk.MustParsePrefixes("10.0.0.0/8"),
+//		TrustedProxies: resdk.MustParsePrefixes("10.0.0.1"),
+//		ProxyHeader:    resdk.XForwardedForHeader,
+//		Next:           jwt_authenticator,
+//	}
+type IpFilterAuthenticator struct {
+	// Addresses allowed. All addresses not denied if empty.
+	Allow []netip.Prefix
+	// Addresses denied, even if allowed
+	Deny []netip.Prefix
+	// Load balancers and proxies whose forwarding header is trusted,
+	// see ClientIP
+	TrustedProxies []netip.Prefix
+	// Required with TrustedProxies. The header they append to.
+	ProxyHeader ProxyHeader
+	// Authenticator run for admitted requests
+	Next Authenticatable
+}
+
+func (i IpFilterAuthenticator) Authenticate(r *http.Request) (interface{}, error) {
+	client := ClientIP(r, i.TrustedProxies, i.ProxyHeader)
+	if !client.IsValid() || prefixesContain(i.Deny, client) {
+		return nil, ErrIpForbidden
+	}
+	if len(i.Allow) > 0 && !prefixesContain(i.Allow, client) {
+		return nil, ErrIpForbidden
+	}
+	if i.Next != nil {
+		return i.Next.Authenticate(r)
+	}
+	return client, nil
+}
@@ -0,0 +1,59 @@
+package resdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies := MustParsePrefixes("10.0.0.0/8")
+	tests := []struct {
+		name    string
+		peer    string
+		headers map[string]string
+		header  ProxyHeader
+		want    string
+	}{
+		{"untrusted peer", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, XForwardedForHeader, "203.0.113.7"},
+		{"x-forwarded-for", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7"}, XForwardedForHeader, "203.0.113.7"},
+		{"trusted hops", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7, 10.0.0.2"}, XForwardedForHeader, "203.0.113.7"},
+		{"forwarded", "10.0.0.1:1234", map[string]string{"Forwarded": `for=198.51.100.1, for="[2001:db8::1]:443"`}, ForwardedHeader, "2001:db8::1"},
+		{
+			"forwarded spoofed behind x-forwarded-for proxy", "10.0.0.1:1234",
+			map[string]string{"Forwarded": "for=10.0.0.9", "X-Forwarded-For": "203.0.113.7"},
+			XForwardedForHeader, "203.0.113.7",
+		},
+		{
+			"x-forwarded-for spoofed behind forwarded proxy", "10.0.0.1:1234",
+			map[string]string{"Forwarded": "for=203.0.113.7", "X-Forwarded-For": "10.0.0.9"},
+			ForwardedHeader, "203.0.113.7",
+		},
+		{"no header configured", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"}, "", "invalid IP"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = test.peer
+		for name, value := range test.headers {
+			r.Header.Set(name, value)
+		}
+		if got := ClientIP(r, proxies, test.header).String(); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestIpFilterAuthenticatorIgnoresSpoofedForwarded(t *testing.T) {
+	filter := IpFilterAuthenticator{
+		Allow:          MustParsePrefixes("10.0.0.0/8"),
+		TrustedProxies: MustParsePrefixes("10.0.0.1"),
+		ProxyHeader:    XForwardedForHeader,
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("Forwarded", "for=10.0.0.9")
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	if _, err := filter.Authenticate(r); err != ErrIpForbidden {
+		t.Errorf("got %v, want ErrIpForbidden", err)
+	}
+}
@@ -0,0 +1,64 @@
+package resdk
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Streaming Json encoder, satisfied by *json.Encoder
+type JsonEncoder interface {
+	Encode(v interface{}) error
+	SetIndent(prefix, indent string)
+	SetEscapeHTML(on bool)
+}
+
+// Streaming Json decoder, satisfied by *json.Decoder
+type JsonDecoder interface {
+	Decode(v interface{}) error
+	DisallowUnknownFields()
+	UseNumber()
+}
+
+// A Json implementation. Adapters for drop-in replacements of
+// encoding/json such as jsoniter, sonic or go-json only need to convert
+// their encoder and decoder types, e.g.
+//
+//	var api = jsoniter.ConfigCompatibleWithStandardLibrary
+//
+//	type jsoniterCodec struct{}
+//
+//	func (jsoniterCodec) Marshal(v interface{}) ([]byte, error)      { return api.Marshal(v) }
+//	func (jsoniterCodec) Unmarshal(data []byte, v interface{}) error { return api.Unmarshal(data, v) }
+//	func (jsoniterCodec) NewEncoder(w io.Writer) resdk.JsonEncoder   { return api.NewEncoder(w) }
+//	func (jsoniterCodec) NewDecoder(r io.Reader) resdk.JsonDecoder   { return api.NewDecoder(r) }
+type JsonCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	NewEncoder(w io.Writer) JsonEncoder
+	NewDecoder(r io.Reader) JsonDecoder
+}
+
+// JsonCodec backed by encoding/json
+type StdJsonCodec struct{}
+
+func (StdJsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (StdJsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (StdJsonCodec) NewEncoder(w io.Writer) JsonEncoder {
+	return json.NewEncoder(w)
+}
+
+func (StdJsonCodec) NewDecoder(r io.Reader) JsonDecoder {
+	return json.NewDecoder(r)
+}
+
+// JsonCodec used by all Json serializers and deserializers of resdk.
+// Replace it during initialization, before any handler serves requests,
+// to use a faster Json implementation, and set health.JsonMarshal to
+// its Marshal.
+var JsonEngine JsonCodec = StdJsonCodec{}
@@ -0,0 +1,103 @@
+package resdk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// Error for a request without body where one is required
+var ErrEmptyBody = errors.New("Request body is empty")
+
+// Decodes the Json body of r into v with JsonEngine
+func decodeJsonBody(r *http.Request, v interface{}, max_bytes int64, disallow_unknown bool) error {
+	b, err := readBody(r, max_bytes)
+	if err != nil {
+		return err
+	}
+	dec := JsonEngine.NewDecoder(bytes.NewReader(b))
+	if disallow_unknown {
+		dec.DisallowUnknownFields()
+	}
+	if err = dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return ErrEmptyBody
+		}
+		return err
+	}
+	return nil
+}
+
+// A TypedDeserializable decoding the Json request body into an In,
+// usually a pointer to a struct. Fields with a `default:"..."` tag
+// which are missing from the body get their default. Use
+// AsDeserializer to plug it into a BaseHandler or NewTypedHandler.
+//
+//	d := resdk.JsonDeserializer[*CreateUser]{MaxBytes: 1 << 20, DisallowUnknownFields: true}
+type JsonDeserializer[In Inputable] struct {
+	// Returns an empty In to decode into. If nil, In must be a
+	// pointer type and a new zero value is allocated.
+	New func() In
+	// Maximum size of the request body. Larger bodies fail with a
+	// *BodyTooLargeError. No limit if zero.
+	MaxBytes int64
+	// Fail on object members which do not match a field
+	DisallowUnknownFields bool
+}
+
+func (j JsonDeserializer[In]) Deserialize(r *http.Request) (In, error) {
+	in, err := j.new()
+	if err != nil {
+		return in, err
+	}
+	if err = applyDefaults(in); err != nil {
+		var zero In
+		return zero, err
+	}
+	if err = decodeJsonBody(r, in, j.MaxBytes, j.DisallowUnknownFields); err != nil {
+		var zero In
+		return zero, err
+	}
+	return in, nil
+}
+
+// Returns the value to decode into
+func (j JsonDeserializer[In]) new() (In, error) {
+	if j.New != nil {
+		return j.New(), nil
+	}
+	var in In
+	t := reflect.TypeOf(&in).Elem()
+	if t.Kind() != reflect.Pointer {
+		return in, fmt.Errorf("resdk: JsonDeserializer needs New for non-pointer type %s", t)
+	}
+	return reflect.New(t.Elem()).Interface().(In), nil
+}
+
+// A Deserializer decoding the Json request body into the Inputable
+// returned by New. Fields with a `default:"..."` tag which are missing
+// from the body get their default.
+type JsonBodyDeserializer struct {
+	// Required. Returns an empty Inputable to decode into, usually a
+	// pointer to a struct.
+	New func() Inputable
+	// Maximum size of the request body. Larger bodies fail with a
+	// *BodyTooLargeError. No limit if zero.
+	MaxBytes int64
+	// Fail on object members which do not match a field
+	DisallowUnknownFields bool
+}
+
+func (j JsonBodyDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	in := j.New()
+	if err := applyDefaults(in); err != nil {
+		return nil, err
+	}
+	if err := decodeJsonBody(r, in, j.MaxBytes, j.DisallowUnknownFields); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
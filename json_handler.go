@@ -1,6 +1,7 @@
 package resdk
 
 import (
+	"errors"
 	"net/http"
 )
 
@@ -30,6 +31,9 @@ func (j *JsonHandler) setDefaults() {
 	if j.ValidationErrorSerializer == nil {
 		j.ValidationErrorSerializer = &JsonErrorSerializer{StatusCode: http.StatusBadRequest}
 	}
+	if j.ExternalValidationErrorSerializer == nil {
+		j.ExternalValidationErrorSerializer = &JsonErrorSerializer{StatusCode: http.StatusUnprocessableEntity}
+	}
 	if j.AuthenticationErrorSerializer == nil {
 		j.AuthenticationErrorSerializer = &JsonErrorSerializer{StatusCode: http.StatusUnauthorized}
 	}
@@ -42,4 +46,18 @@ func (j *JsonHandler) setDefaults() {
 	if j.AuthorizationErrorSerializer == nil {
 		j.AuthorizationErrorSerializer = &JsonErrorSerializer{StatusCode: http.StatusForbidden}
 	}
+	if j.MethodNotAllowedSerializer == nil {
+		j.MethodNotAllowedSerializer = &JsonErrorSerializer{StatusCode: http.StatusMethodNotAllowed}
+	}
+	if j.TimeoutSerializer == nil {
+		// TimeoutError picks 408 or 504 depending on the phase
+		j.TimeoutSerializer = &JsonErrorSerializer{StatusCode: http.StatusGatewayTimeout}
+	}
+	if j.RecoverySerializer == nil {
+		// Panic values are not meant for clients
+		j.RecoverySerializer = &JsonErrorSerializer{
+			StatusCode: http.StatusInternalServerError,
+			Error:      errors.New("Internal server error"),
+		}
+	}
 }
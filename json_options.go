@@ -0,0 +1,158 @@
+package resdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// Options controlling the output of Json serializers
+type JsonOptions struct {
+	// Indentation of nested values, e.g. "  ". Compact output if empty.
+	Indent string
+	// Do not escape <, > and & inside strings
+	DisableHTMLEscape bool
+	// Leave out object members whose value is null
+	OmitNulls bool
+	// Layout used to render time.Time values, e.g. time.RFC1123,
+	// instead of RFC 3339. Strings which merely look like times are
+	// left alone.
+	TimeFormat string
+	// Name of a query parameter enabling indentation (with Indent or
+	// two spaces), e.g. "pretty" for ?pretty or ?pretty=true
+	PrettyParam string
+}
+
+// Marshals v into buf according to the options. r may be nil.
+// OmitNulls and TimeFormat need an extra marshaling round trip, which
+// also sorts object members by name.
+func (o JsonOptions) encode(buf *bytes.Buffer, v interface{}, r *http.Request) error {
+	if o.OmitNulls || o.TimeFormat != "" {
+		b, err := JsonEngine.Marshal(v)
+		if err != nil {
+			return err
+		}
+		dec := JsonEngine.NewDecoder(bytes.NewReader(b))
+		// Keep numbers exactly as marshaled
+		dec.UseNumber()
+		var generic interface{}
+		if err = dec.Decode(&generic); err != nil {
+			return err
+		}
+		if o.TimeFormat != "" {
+			generic = o.formatTimes(reflect.ValueOf(v), generic)
+		}
+		v = o.transform(generic)
+	}
+
+	enc := JsonEngine.NewEncoder(buf)
+	enc.SetEscapeHTML(!o.DisableHTMLEscape)
+	if indent := o.indent(r); indent != "" {
+		enc.SetIndent("", indent)
+	}
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	// Drop the newline added by Encode
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// Returns the indentation to use for r
+func (o JsonOptions) indent(r *http.Request) string {
+	if o.Indent != "" || o.PrettyParam == "" || r == nil {
+		return o.Indent
+	}
+	query := r.URL.Query()
+	if !query.Has(o.PrettyParam) {
+		return ""
+	}
+	switch query.Get(o.PrettyParam) {
+	case "0", "false":
+		return ""
+	}
+	return "  "
+}
+
+// Applies OmitNulls to a decoded Json value
+func (o JsonOptions) transform(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, member := range value {
+			if member == nil && o.OmitNulls {
+				delete(value, key)
+				continue
+			}
+			value[key] = o.transform(member)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = o.transform(item)
+		}
+	}
+	return v
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Renders with TimeFormat the times of the decoded Json value generic,
+// walking v, the value it was marshaled from, alongside. Values with
+// their own Json marshaling other than time.Time are not entered.
+func (o JsonOptions) formatTimes(v reflect.Value, generic interface{}) interface{} {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return generic
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || !v.CanInterface() {
+		return generic
+	}
+	if v.Type() == timeType {
+		if _, ok := generic.(string); ok {
+			return v.Interface().(time.Time).Format(o.TimeFormat)
+		}
+		return generic
+	}
+	if v.Type().Implements(jsonMarshalerType) || reflect.PointerTo(v.Type()).Implements(jsonMarshalerType) {
+		return generic
+	}
+	switch value := generic.(type) {
+	case map[string]interface{}:
+		switch v.Kind() {
+		case reflect.Struct:
+			for key, member := range value {
+				index, ok := jsonFieldIndex(v.Type(), key)
+				if !ok {
+					continue
+				}
+				// Fails for nil embedded pointers, whose fields are
+				// not marshaled
+				if field, err := v.FieldByIndexErr(index); err == nil {
+					value[key] = o.formatTimes(field, member)
+				}
+			}
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				break
+			}
+			for key, member := range value {
+				if elem := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())); elem.IsValid() {
+					value[key] = o.formatTimes(elem, member)
+				}
+			}
+		}
+	case []interface{}:
+		if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			for i := 0; i < len(value) && i < v.Len(); i++ {
+				value[i] = o.formatTimes(v.Index(i), value[i])
+			}
+		}
+	}
+	return generic
+}
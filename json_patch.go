@@ -0,0 +1,405 @@
+package resdk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media type of JSON Patch documents (RFC 6902)
+const JsonPatchMediaType = "application/json-patch+json"
+
+// One operation of a JSON Patch
+type PatchOperation struct {
+	// One of add, remove, replace, move, copy and test
+	Op string
+	// JSON Pointer of the target location
+	Path string
+	// JSON Pointer of the source location of move and copy
+	From string
+	// Value of add, replace and test, decoded with json.Number
+	// numbers
+	Value interface{}
+
+	hasValue bool
+}
+
+// Inputable of a JSON Patch request: a list of operations applied in
+// order, all or nothing
+type JsonPatch struct {
+	Operations []PatchOperation
+}
+
+// Checks that every operation is known, has its required members and
+// valid JSON Pointers. Failures are *ValidationErrors keyed by the
+// location within the patch, e.g. "/0/path".
+func (j *JsonPatch) Validate() error {
+	errs := NewValidationErrors()
+	for i, op := range j.Operations {
+		location := "/" + strconv.Itoa(i)
+		if _, err := parsePointer(op.Path); err != nil {
+			errs.Add(location+"/path", "pointer", err.Error())
+		}
+		switch op.Op {
+		case "add", "replace", "test":
+			if !op.hasValue {
+				errs.Add(location+"/value", "required", "is required")
+			}
+		case "move", "copy":
+			if _, err := parsePointer(op.From); err != nil {
+				errs.Add(location+"/from", "pointer", err.Error())
+			} else if op.Op == "move" && strings.HasPrefix(op.Path, op.From+"/") {
+				errs.Add(location+"/path", "move", "cannot move a value into one of its children")
+			}
+		case "remove":
+		default:
+			errs.Add(location+"/op", "enum", "must be one of add, remove, replace, move, copy and test")
+		}
+	}
+	return errs.Err()
+}
+
+// Error of an operation which cannot be applied to the document.
+// Responds with 409 Conflict for a failed test and 422 Unprocessable
+// Entity otherwise.
+type JsonPatchError struct {
+	// Index of the operation in the patch
+	Index int
+	// Op of the operation
+	Op  string
+	Err error
+}
+
+// Error of a test operation whose value does not match
+var ErrPatchTestFailed = errors.New("test failed")
+
+func (j *JsonPatchError) Error() string {
+	return fmt.Sprintf("patch operation %d (%s): %v", j.Index, j.Op, j.Err)
+}
+
+func (j *JsonPatchError) Unwrap() error {
+	return j.Err
+}
+
+func (j *JsonPatchError) StatusCode() int {
+	if errors.Is(j.Err, ErrPatchTestFailed) {
+		return http.StatusConflict
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// Applies the patch to a Json document and returns the result. Fails
+// with a *JsonPatchError if an operation cannot be applied.
+func (j *JsonPatch) Apply(doc []byte) ([]byte, error) {
+	var v interface{}
+	if err := decodeJsonNumbers(doc, &v); err != nil {
+		return nil, err
+	}
+	for i, op := range j.Operations {
+		var err error
+		if v, err = applyPatchOperation(v, op); err != nil {
+			return nil, &JsonPatchError{Index: i, Op: op.Op, Err: err}
+		}
+	}
+	return JsonEngine.Marshal(v)
+}
+
+// Applies the patch to target, a pointer to a value marshaled and
+// unmarshaled with JsonEngine. Only the members the patch changes are
+// written, as with MergePatch.ApplyTo, so fields Json does not see keep
+// their value. target is left unchanged if the patch fails.
+func (j *JsonPatch) ApplyTo(target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("resdk: cannot apply json patch to %T", target)
+	}
+	doc, err := JsonEngine.Marshal(target)
+	if err != nil {
+		return err
+	}
+	patched, err := j.Apply(doc)
+	if err != nil {
+		return err
+	}
+	var before, after interface{}
+	if err = decodeJsonNumbers(doc, &before); err != nil {
+		return err
+	}
+	if err = decodeJsonNumbers(patched, &after); err != nil {
+		return err
+	}
+	// Patched on a copy, so a failure leaves target unchanged
+	work := reflect.New(rv.Elem().Type()).Elem()
+	work.Set(rv.Elem())
+	if err = mergeInto(work, mergeDiff(before, after)); err != nil {
+		return err
+	}
+	rv.Elem().Set(work)
+	return nil
+}
+
+// Returns the merge patch turning the decoded document before into
+// after: removed members are null, changed objects are diffed and any
+// other change replaces the value
+func mergeDiff(before, after interface{}) interface{} {
+	from, ok := before.(map[string]interface{})
+	to, ok2 := after.(map[string]interface{})
+	if !ok || !ok2 {
+		return after
+	}
+	diff := make(map[string]interface{})
+	for key := range from {
+		if _, ok := to[key]; !ok {
+			diff[key] = nil
+		}
+	}
+	for key, value := range to {
+		old, ok := from[key]
+		if !ok {
+			diff[key] = value
+		} else if !reflect.DeepEqual(old, value) {
+			diff[key] = mergeDiff(old, value)
+		}
+	}
+	return diff
+}
+
+// Splits a JSON Pointer into unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON Pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// Applies op to the decoded document v and returns the new document
+func applyPatchOperation(v interface{}, op PatchOperation) (interface{}, error) {
+	path, _ := parsePointer(op.Path)
+	switch op.Op {
+	case "add":
+		// Copied so that later operations on the document cannot
+		// change op, e.g. when a patch is applied to several documents
+		return pointerAdd(v, path, deepCopyJson(op.Value))
+	case "remove":
+		v, _, err := pointerRemove(v, path)
+		return v, err
+	case "replace":
+		v, _, err := pointerRemove(v, path)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(v, path, deepCopyJson(op.Value))
+	case "move":
+		from, _ := parsePointer(op.From)
+		v, value, err := pointerRemove(v, from)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(v, path, value)
+	case "copy":
+		from, _ := parsePointer(op.From)
+		value, err := pointerGet(v, from)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(v, path, deepCopyJson(value))
+	case "test":
+		value, err := pointerGet(v, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(value, op.Value) {
+			return nil, ErrPatchTestFailed
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// Returns the array index of token for an array of length n
+func arrayIndex(token string, n int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i >= n || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return i, nil
+}
+
+// Calls leaf on the parent of the location tokens points to and stores
+// its result in place of the parent
+func pointerUpdate(v interface{}, tokens []string, leaf func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return leaf(v, tokens[0])
+	}
+	switch node := v.(type) {
+	case map[string]interface{}:
+		child, ok := node[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("path member %q not found", tokens[0])
+		}
+		child, err := pointerUpdate(child, tokens[1:], leaf)
+		if err != nil {
+			return nil, err
+		}
+		node[tokens[0]] = child
+		return node, nil
+	case []interface{}:
+		i, err := arrayIndex(tokens[0], len(node))
+		if err != nil {
+			return nil, err
+		}
+		if node[i], err = pointerUpdate(node[i], tokens[1:], leaf); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	return nil, fmt.Errorf("path member %q not found", tokens[0])
+}
+
+func pointerGet(v interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path member %q not found", token)
+			}
+			v = child
+		case []interface{}:
+			i, err := arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("path member %q not found", token)
+		}
+	}
+	return v, nil
+}
+
+func pointerAdd(v interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return pointerUpdate(v, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			if token == "-" {
+				return append(node, value), nil
+			}
+			i, err := arrayIndex(token, len(node)+1)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("cannot add member %q to a scalar", token)
+	})
+}
+
+// Removes the value at tokens. Returns the new document and the removed
+// value.
+func pointerRemove(v interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+	var removed interface{}
+	v, err := pointerUpdate(v, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path member %q not found", token)
+			}
+			removed = value
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			removed = node[i]
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("path member %q not found", token)
+	})
+	return v, removed, err
+}
+
+// Returns a deep copy of a decoded Json value
+func deepCopyJson(v interface{}) interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(node))
+		for key, value := range node {
+			copied[key] = deepCopyJson(value)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(node))
+		for i, value := range node {
+			copied[i] = deepCopyJson(value)
+		}
+		return copied
+	}
+	return v
+}
+
+// A Deserializer for JSON Patch (RFC 6902) request bodies producing a
+// *JsonPatch. Bodies with a Content-Type other than
+// application/json-patch+json or application/json fail with an
+// *UnsupportedMediaTypeError.
+type JsonPatchDeserializer struct {
+	// Maximum size of the request body. Larger bodies fail with a
+	// *BodyTooLargeError. No limit if zero.
+	MaxBytes int64
+}
+
+func (j JsonPatchDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	if content_type := r.Header.Get("Content-Type"); content_type != "" {
+		media_type, _, err := mime.ParseMediaType(content_type)
+		if err != nil || (media_type != JsonPatchMediaType && media_type != "application/json") {
+			return nil, &UnsupportedMediaTypeError{MediaType: content_type}
+		}
+	}
+	b, err := readBody(r, j.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, ErrEmptyBody
+	}
+	var raw []map[string]interface{}
+	if err = decodeJsonNumbers(b, &raw); err != nil {
+		return nil, err
+	}
+	patch := &JsonPatch{Operations: make([]PatchOperation, len(raw))}
+	for i, member := range raw {
+		op := &patch.Operations[i]
+		op.Op, _ = member["op"].(string)
+		op.Path, _ = member["path"].(string)
+		op.From, _ = member["from"].(string)
+		op.Value, op.hasValue = member["value"]
+	}
+	return patch, nil
+}
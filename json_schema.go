@@ -0,0 +1,486 @@
+package resdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// A compiled JSON Schema. Supports the commonly used subset of draft
+// 2020-12: type, enum, const, the numeric, string, array and object
+// keywords (including additionalProperties), allOf, anyOf, oneOf, not,
+// the formats email, uuid, date and date-time, and local $refs such as
+// "#/$defs/address". Unknown keywords are ignored.
+type JsonSchema struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// Parses and compiles a JSON Schema document
+func CompileJsonSchema(schema []byte) (*JsonSchema, error) {
+	dec := JsonEngine.NewDecoder(bytes.NewReader(schema))
+	dec.UseNumber()
+	var root interface{}
+	if err := dec.Decode(&root); err != nil {
+		return nil, fmt.Errorf("resdk: invalid JSON Schema: %w", err)
+	}
+	s := &JsonSchema{root: root, patterns: make(map[string]*regexp.Regexp)}
+	if err := s.compile(root); err != nil {
+		return nil, fmt.Errorf("resdk: invalid JSON Schema: %w", err)
+	}
+	return s, nil
+}
+
+// Like CompileJsonSchema but panics on error, for schemas in package
+// variables
+func MustCompileJsonSchema(schema []byte) *JsonSchema {
+	s, err := CompileJsonSchema(schema)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Compiles patterns and checks $refs of a schema and its subschemas
+func (s *JsonSchema) compile(schema interface{}) error {
+	switch schema := schema.(type) {
+	case map[string]interface{}:
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return err
+			}
+			s.patterns[pattern] = re
+		}
+		if ref, ok := schema["$ref"].(string); ok {
+			if _, err := s.resolve(ref); err != nil {
+				return err
+			}
+		}
+		for key, value := range schema {
+			if key == "enum" || key == "const" {
+				continue
+			}
+			if err := s.compile(value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, value := range schema {
+			if err := s.compile(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the subschema a local $ref points to
+func (s *JsonSchema) resolve(ref string) (interface{}, error) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+	current := s.root
+	if pointer == "" {
+		return current, nil
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case map[string]interface{}:
+			if current, ok = node[token]; !ok {
+				return nil, fmt.Errorf("unresolvable $ref %q", ref)
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("unresolvable $ref %q", ref)
+			}
+			current = node[i]
+		default:
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return current, nil
+}
+
+// Validates a Json document. Returns *ValidationErrors keyed by the
+// JSON Pointer of every failing location ("" for the document itself)
+// and coded by the failing keyword.
+func (s *JsonSchema) ValidateJson(doc []byte) error {
+	dec := JsonEngine.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	return s.Validate(v)
+}
+
+// Validates a document decoded with UseNumber, see ValidateJson
+func (s *JsonSchema) Validate(v interface{}) error {
+	errs := NewValidationErrors()
+	s.validate(s.root, v, "", errs)
+	return errs.Err()
+}
+
+// Reports whether v is valid against schema without recording errors
+func (s *JsonSchema) valid(schema, v interface{}, pointer string) bool {
+	errs := NewValidationErrors()
+	s.validate(schema, v, pointer, errs)
+	return errs.Empty()
+}
+
+func (s *JsonSchema) validate(schema, v interface{}, pointer string, errs *ValidationErrors) {
+	switch schema := schema.(type) {
+	case bool:
+		if !schema {
+			errs.Add(pointer, "false", "is not allowed")
+		}
+		return
+	case map[string]interface{}:
+		s.validateObject(schema, v, pointer, errs)
+	}
+}
+
+func (s *JsonSchema) validateObject(schema map[string]interface{}, v interface{}, pointer string, errs *ValidationErrors) {
+	if ref, ok := schema["$ref"].(string); ok {
+		target, _ := s.resolve(ref)
+		s.validate(target, v, pointer, errs)
+	}
+	if types, ok := schema["type"]; ok && !matchesType(types, v) {
+		errs.Add(pointer, "type", fmt.Sprintf("must be of type %s", typeList(types)))
+		return
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, option := range enum {
+			if jsonEqual(option, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs.Add(pointer, "enum", "must be one of the allowed values")
+		}
+	}
+	if c, ok := schema["const"]; ok && !jsonEqual(c, v) {
+		errs.Add(pointer, "const", "must be the constant value")
+	}
+
+	for _, sub := range schemaList(schema["allOf"]) {
+		s.validate(sub, v, pointer, errs)
+	}
+	if subs := schemaList(schema["anyOf"]); subs != nil {
+		matched := false
+		for _, sub := range subs {
+			if s.valid(sub, v, pointer) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs.Add(pointer, "anyOf", "must match at least one schema")
+		}
+	}
+	if subs := schemaList(schema["oneOf"]); subs != nil {
+		matched := 0
+		for _, sub := range subs {
+			if s.valid(sub, v, pointer) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			errs.Add(pointer, "oneOf", "must match exactly one schema")
+		}
+	}
+	if not, ok := schema["not"]; ok && s.valid(not, v, pointer) {
+		errs.Add(pointer, "not", "must not match the schema")
+	}
+
+	switch v := v.(type) {
+	case json.Number:
+		s.validateNumber(schema, v, pointer, errs)
+	case string:
+		s.validateString(schema, v, pointer, errs)
+	case []interface{}:
+		s.validateArray(schema, v, pointer, errs)
+	case map[string]interface{}:
+		s.validateProperties(schema, v, pointer, errs)
+	}
+}
+
+func (s *JsonSchema) validateNumber(schema map[string]interface{}, n json.Number, pointer string, errs *ValidationErrors) {
+	f, _ := n.Float64()
+	if limit, ok := schemaNumber(schema["minimum"]); ok && f < limit {
+		errs.Add(pointer, "minimum", fmt.Sprintf("must be at least %v", schema["minimum"]))
+	}
+	if limit, ok := schemaNumber(schema["maximum"]); ok && f > limit {
+		errs.Add(pointer, "maximum", fmt.Sprintf("must be at most %v", schema["maximum"]))
+	}
+	if limit, ok := schemaNumber(schema["exclusiveMinimum"]); ok && f <= limit {
+		errs.Add(pointer, "exclusiveMinimum", fmt.Sprintf("must be greater than %v", schema["exclusiveMinimum"]))
+	}
+	if limit, ok := schemaNumber(schema["exclusiveMaximum"]); ok && f >= limit {
+		errs.Add(pointer, "exclusiveMaximum", fmt.Sprintf("must be less than %v", schema["exclusiveMaximum"]))
+	}
+	if divisor, ok := schemaNumber(schema["multipleOf"]); ok && divisor > 0 {
+		if q := f / divisor; math.Abs(q-math.Round(q)) > 1e-9 {
+			errs.Add(pointer, "multipleOf", fmt.Sprintf("must be a multiple of %v", schema["multipleOf"]))
+		}
+	}
+}
+
+func (s *JsonSchema) validateString(schema map[string]interface{}, str string, pointer string, errs *ValidationErrors) {
+	length := float64(utf8.RuneCountInString(str))
+	if limit, ok := schemaNumber(schema["minLength"]); ok && length < limit {
+		errs.Add(pointer, "minLength", fmt.Sprintf("must be at least %v characters long", schema["minLength"]))
+	}
+	if limit, ok := schemaNumber(schema["maxLength"]); ok && length > limit {
+		errs.Add(pointer, "maxLength", fmt.Sprintf("must be at most %v characters long", schema["maxLength"]))
+	}
+	if pattern, ok := schema["pattern"].(string); ok && !s.patterns[pattern].MatchString(str) {
+		errs.Add(pointer, "pattern", fmt.Sprintf("must match the pattern %q", pattern))
+	}
+	if format, ok := schema["format"].(string); ok && !validFormat(format, str) {
+		errs.Add(pointer, "format", fmt.Sprintf("must be a valid %s", format))
+	}
+}
+
+func (s *JsonSchema) validateArray(schema map[string]interface{}, items []interface{}, pointer string, errs *ValidationErrors) {
+	count := float64(len(items))
+	if limit, ok := schemaNumber(schema["minItems"]); ok && count < limit {
+		errs.Add(pointer, "minItems", fmt.Sprintf("must have at least %v items", schema["minItems"]))
+	}
+	if limit, ok := schemaNumber(schema["maxItems"]); ok && count > limit {
+		errs.Add(pointer, "maxItems", fmt.Sprintf("must have at most %v items", schema["maxItems"]))
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+	outer:
+		for i := range items {
+			for j := i + 1; j < len(items); j++ {
+				if jsonEqual(items[i], items[j]) {
+					errs.Add(pointer, "uniqueItems", "must not contain duplicate items")
+					break outer
+				}
+			}
+		}
+	}
+	if item_schema, ok := schema["items"]; ok {
+		for i, item := range items {
+			s.validate(item_schema, item, pointer+"/"+strconv.Itoa(i), errs)
+		}
+	}
+}
+
+func (s *JsonSchema) validateProperties(schema map[string]interface{}, object map[string]interface{}, pointer string, errs *ValidationErrors) {
+	count := float64(len(object))
+	if limit, ok := schemaNumber(schema["minProperties"]); ok && count < limit {
+		errs.Add(pointer, "minProperties", fmt.Sprintf("must have at least %v properties", schema["minProperties"]))
+	}
+	if limit, ok := schemaNumber(schema["maxProperties"]); ok && count > limit {
+		errs.Add(pointer, "maxProperties", fmt.Sprintf("must have at most %v properties", schema["maxProperties"]))
+	}
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, present := object[name]; !present {
+					errs.Add(pointer+"/"+escapePointer(name), "required", "is required")
+				}
+			}
+		}
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	additional, has_additional := schema["additionalProperties"]
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		location := pointer + "/" + escapePointer(name)
+		if property_schema, ok := properties[name]; ok {
+			s.validate(property_schema, object[name], location, errs)
+		} else if has_additional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				errs.Add(location, "additionalProperties", "is not allowed")
+			} else if !ok {
+				s.validate(additional, object[name], location, errs)
+			}
+		}
+	}
+}
+
+// Escapes a reference token of a JSON Pointer
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// Returns the subschemas of an allOf, anyOf or oneOf keyword
+func schemaList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+// Returns the value of a numeric keyword
+func schemaNumber(v interface{}) (float64, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// Reports whether v has one of the types of a type keyword
+func matchesType(types interface{}, v interface{}) bool {
+	switch types := types.(type) {
+	case string:
+		return matchesSingleType(types, v)
+	case []interface{}:
+		for _, t := range types {
+			if t, ok := t.(string); ok && matchesSingleType(t, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesSingleType(t string, v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case []interface{}:
+		return t == "array"
+	case map[string]interface{}:
+		return t == "object"
+	case json.Number:
+		if t == "number" {
+			return true
+		}
+		if t == "integer" {
+			f, ok := new(big.Float).SetString(v.String())
+			return ok && f.IsInt()
+		}
+	}
+	return false
+}
+
+// Returns a type keyword as text for messages
+func typeList(types interface{}) string {
+	if list, ok := types.([]interface{}); ok {
+		names := make([]string, len(list))
+		for i, t := range list {
+			names[i] = fmt.Sprint(t)
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(types)
+}
+
+// Reports whether two decoded Json values are equal, comparing numbers
+// by value
+func jsonEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		bn, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aok := new(big.Float).SetString(a.String())
+		bf, bok := new(big.Float).SetString(bn.String())
+		return aok && bok && af.Cmp(bf) == 0
+	case []interface{}:
+		bl, ok := b.([]interface{})
+		if !ok || len(a) != len(bl) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], bl[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		bm, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(bm) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := bm[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+var emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// Reports whether s is valid in format. Unknown formats are valid.
+func validFormat(format, s string) bool {
+	switch format {
+	case "email":
+		return emailPattern.MatchString(s)
+	case "uuid":
+		_, err := ParseUUID(s)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "date":
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	}
+	return true
+}
+
+// A Deserializer validating the raw Json request body against a JSON
+// Schema before handing it to Deserializer, so contracts defined schema
+// first are enforced before binding. Failures are reported as
+// *ValidationErrors keyed by JSON Pointer, e.g.
+// {"errors": {"/address/city": [{"code": "required", "message": "is required"}]}}.
+type SchemaValidatingDeserializer struct {
+	// Required. The schema of the request body.
+	Schema *JsonSchema
+	// Required. Deserializes the validated body, e.g. a
+	// JsonBodyDeserializer.
+	Deserializer Deserializable
+	// Maximum size of the request body. Larger bodies fail with a
+	// *BodyTooLargeError. No limit if zero.
+	MaxBytes int64
+}
+
+func (s SchemaValidatingDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	b, err := readBody(r, s.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, ErrEmptyBody
+	}
+	if err = s.Schema.ValidateJson(b); err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	return s.Deserializer.Deserialize(r)
+}
@@ -1,46 +1,85 @@
 package resdk
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"net/http"
 )
 
+// Body sent instead of the response if it cannot be marshaled
+var marshalFailureBody = []byte(`{"error":"Internal server error"}`)
+
+// Marshals v and writes it as content_type with the status and headers
+// chosen for out (see writeMarshaled)
+func writeJsonAs(w http.ResponseWriter, out Outputable, v interface{}, content_type string, def_status int) error {
+	return writeMarshaled(w, out, content_type, def_status, marshalFailureBody, func(buf *bytes.Buffer) error {
+		return encodeJson(buf, v)
+	})
+}
+
+// Marshals v into buf with the default JsonOptions
+func encodeJson(buf *bytes.Buffer, v interface{}) error {
+	return JsonOptions{}.encode(buf, v, nil)
+}
+
 // A serializer for response in json
 type JsonSerializer struct {
-	// HTTP Status Code to be returned
+	// HTTP Status Code to be returned unless out implements
+	// StatusCoder
 	StatusCode int
+	// Formatting options
+	JsonOptions
 }
 
 // Serializes Outputable to a ResponseWriter
 func (j JsonSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
-	out_b, _ := json.Marshal(out)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(j.StatusCode)
-	w.Write(out_b)
-	return
+	j.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors. Responds
+// with a 500 error if out cannot be marshaled.
+func (j JsonSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	return writeMarshaled(w, out, "application/json", j.StatusCode, marshalFailureBody, func(buf *bytes.Buffer) error {
+		return j.JsonOptions.encode(buf, out, r)
+	})
 }
 
 // A serializer for error response in json
 type JsonErrorSerializer struct {
-	// HTTP Status Code to be returned
+	// HTTP Status Code to be returned unless the error implements
+	// StatusCoder
 	StatusCode int
 	// If set it overrides the error message in response
 	Error Outputable
+	// Formatting options
+	JsonOptions
 }
 
 // Serializes out to a ResponseWriter in standard error format
 // If out is not Json Marshalable but is an error, its error message
 // is used instead.
 // Error response format: {"error": <object or error message>}
+// Errors wrapping *ValidationErrors are rendered as {"errors": {...}}.
 func (j JsonErrorSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	j.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors
+func (j JsonErrorSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	// Status and headers always come from the original error
+	original := out
 	if j.Error != nil {
 		out = j.Error
 	}
 
 	var out_obj interface{} = out
 
-	if out_err, ok := out.(error); ok {
+	var verrs *ValidationErrors
+	if out_err, ok := out.(error); ok && errors.As(out_err, &verrs) {
+		// Field level errors keep their structure even when wrapped
+		out_obj = verrs
+	} else if ok {
 		if _, ok = out.(json.Marshaler); !ok {
 			// If out type is error but out is not a marshaller use error string
 			out_obj = map[string]interface{}{
@@ -49,11 +88,9 @@ func (j JsonErrorSerializer) Serialize(out Outputable, w http.ResponseWriter, r
 		}
 	}
 
-	out_b, _ := json.Marshal(out_obj)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(j.StatusCode)
-	w.Write(out_b)
-	return
+	return writeMarshaled(w, original, "application/json", j.StatusCode, marshalFailureBody, func(buf *bytes.Buffer) error {
+		return j.JsonOptions.encode(buf, out_obj, r)
+	})
 }
 
 // A JsonErrorSerializer which serializes Not found error
@@ -62,8 +99,12 @@ type JsonNotFoundSerializer struct {
 }
 
 func (j JsonNotFoundSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	j.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors
+func (j JsonNotFoundSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
 	j.StatusCode = http.StatusNotFound
 	j.Error = errors.New("Not found")
-	j.JsonErrorSerializer.Serialize(out, w, r)
-	return
+	return j.JsonErrorSerializer.SerializeChecked(out, w, r)
 }
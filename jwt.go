@@ -0,0 +1,551 @@
+package resdk
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Error of a bearer token which is malformed, badly signed or whose
+// claims are not acceptable. Responds with 401 Unauthorized and a
+// WWW-Authenticate header as described in RFC 6750.
+type InvalidTokenError struct {
+	// Why the token was rejected, sent as error_description
+	Reason string
+}
+
+func (e *InvalidTokenError) Error() string {
+	return "invalid token: " + e.Reason
+}
+
+func (e *InvalidTokenError) StatusCode() int {
+	return http.StatusUnauthorized
+}
+
+func (e *InvalidTokenError) Headers() http.Header {
+	return http.Header{"Www-Authenticate": {fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, e.Reason)}}
+}
+
+// Returns the token of an "Authorization: Bearer <token>" header.
+// Fails with ErrNoCredentials if there is none.
+func BearerToken(r *http.Request) (string, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrNoCredentials
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// Claims of a verified Json Web Token, the auth details returned by
+// JwtAuthenticator. Numbers are float64 as with encoding/json.
+type JwtClaims map[string]interface{}
+
+// Returns the claim name if it is a string, "" otherwise
+func (c JwtClaims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Returns the "sub" claim
+func (c JwtClaims) Subject() string {
+	return c.String("sub")
+}
+
+// Returns the subject, see Principal
+func (c JwtClaims) PrincipalID() string {
+	return c.Subject()
+}
+
+// Returns the "iss" claim
+func (c JwtClaims) Issuer() string {
+	return c.String("iss")
+}
+
+// Returns the "aud" claim, which may be a string or an array
+func (c JwtClaims) Audience() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		audience := make([]string, 0, len(aud))
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				audience = append(audience, s)
+			}
+		}
+		return audience
+	}
+	return nil
+}
+
+// Returns the space separated "scope" claim, or the "scp" claim used by
+// some providers as a string or an array
+func (c JwtClaims) GrantedScopes() []string {
+	if scope := c.String("scope"); scope != "" {
+		return strings.Fields(scope)
+	}
+	switch scp := c["scp"].(type) {
+	case string:
+		return strings.Fields(scp)
+	case []interface{}:
+		scopes := make([]string, 0, len(scp))
+		for _, s := range scp {
+			if scope, ok := s.(string); ok {
+				scopes = append(scopes, scope)
+			}
+		}
+		return scopes
+	}
+	return nil
+}
+
+// Returns the "roles" claim
+func (c JwtClaims) Roles() []string {
+	roles, _ := c["roles"].([]interface{})
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if name, ok := role.(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Returns the numeric date claim name, e.g. "exp", and whether it is
+// present
+func (c JwtClaims) Time(name string) (time.Time, bool) {
+	seconds, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), true
+}
+
+// Provides the keys verifying Json Web Tokens. Keys are []byte for the
+// HS algorithms, *rsa.PublicKey for RS and PS, *ecdsa.PublicKey for ES
+// and ed25519.PublicKey for EdDSA.
+type JwtKeySource interface {
+	// Returns the key with the key id kid for the algorithm alg. kid
+	// is "" if the token has none.
+	Key(ctx context.Context, kid, alg string) (interface{}, error)
+}
+
+// A JwtKeySource of fixed keys by key id. The key under "" verifies
+// tokens without a key id.
+type StaticJwtKeys map[string]interface{}
+
+func (s StaticJwtKeys) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+	key, ok := s[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// A JwtKeySource fetching a JSON Web Key Set (RFC 7517) from a URL,
+// usually the jwks_uri of an identity provider. The set is cached for
+// CacheTTL and fetched again early when a token names an unknown key,
+// so rotated keys are picked up without a restart. RSA, EC (P-256,
+// P-384, P-521) and Ed25519 keys are supported. Use NewJwksKeySource to
+// create one.
+type JwksKeySource struct {
+	// Required. URL of the key set.
+	URL string
+	// Defaults to http.DefaultClient
+	Client *http.Client
+	// How long the fetched set is used. Defaults to an hour.
+	CacheTTL time.Duration
+	// Minimum time between fetches triggered by unknown keys or
+	// following failed fetches, so tokens with made up key ids or an
+	// unreachable URL cannot hammer it. Defaults to a minute.
+	MinRefreshInterval time.Duration
+	// Maximum duration of a fetch. Defaults to 10 seconds.
+	FetchTimeout time.Duration
+
+	mu   sync.Mutex
+	keys map[string]interface{}
+	// Time of the last successful fetch
+	fetched time.Time
+	// Time and error of the last fetch, successful or not
+	attempted time.Time
+	err       error
+	// Closed when the running fetch ends. Requests needing a fetch
+	// share it.
+	pending chan struct{}
+}
+
+// Creates a JwksKeySource for the key set at url
+func NewJwksKeySource(url string) *JwksKeySource {
+	return &JwksKeySource{URL: url}
+}
+
+// Returns the key kid. Fetches run in the background and are shared by
+// concurrent requests; only requests whose key is missing wait for
+// them, the others use the cached set meanwhile.
+func (j *JwksKeySource) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+	ttl := j.CacheTTL
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	min_refresh := j.MinRefreshInterval
+	if min_refresh <= 0 {
+		min_refresh = time.Minute
+	}
+	j.mu.Lock()
+	key, known := j.lookup(kid)
+	pending := j.pending
+	if pending == nil && (!known || time.Since(j.fetched) > ttl) && time.Since(j.attempted) > min_refresh {
+		pending = j.startFetch(ctx)
+	}
+	j.mu.Unlock()
+	if known {
+		return key, nil
+	}
+
+	if pending != nil {
+		select {
+		case <-pending:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if key, known = j.lookup(kid); known {
+		return key, nil
+	}
+	if j.keys == nil {
+		return nil, j.err
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// Starts fetching the key set with FetchTimeout and a context detached
+// from the cancellation of ctx, so the requests waiting for it do not
+// depend on the one which started it. On errors the stale set is used
+// until the next attempt. j.mu must be held.
+func (j *JwksKeySource) startFetch(ctx context.Context) chan struct{} {
+	timeout := j.FetchTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	done := make(chan struct{})
+	j.pending = done
+	j.attempted = time.Now()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+		keys, err := j.fetch(ctx)
+		j.mu.Lock()
+		if err == nil {
+			j.keys, j.fetched = keys, time.Now()
+		}
+		j.err = err
+		j.pending = nil
+		j.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+// Returns the cached key kid, or the only key if kid is ""
+func (j *JwksKeySource) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+// Fetches and parses the key set. Keys of unsupported types are
+// skipped.
+func (j *JwksKeySource) fetch(ctx context.Context) (map[string]interface{}, error) {
+	client := j.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching key set: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching key set: %s", resp.Status)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding key set: %w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// A JSON Web Key, with the members of the supported key types
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		x, err := decode(k.X)
+		if err != nil || k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// An Authenticator verifying the Json Web Token (RFC 7519) in the
+// Authorization: Bearer header and returning its JwtClaims as auth
+// details:
+//
+//	resdk.JwtAuthenticator{
+//		Keys:     resdk.NewJwksKeySource("https://auth.example.com/.well-known/jwks.json"),
+//		Issuer:   "https://auth.example.com/",
+//		Audience: "orders-api",
+//	}
+//
+// The algorithm of a token must fit the type of its key, so a public
+// RSA key can never be used as an HMAC secret, and "none" is always
+// rejected. Requests without a bearer token fail with ErrNoCredentials,
+// invalid tokens with *InvalidTokenError.
+type JwtAuthenticator struct {
+	// Required. Provides the verification keys.
+	Keys JwtKeySource
+	// Accepted "alg" values, e.g. []string{"RS256"}. Any supported
+	// algorithm fitting the key if empty.
+	Algorithms []string
+	// Required "iss" claim, not checked if empty
+	Issuer string
+	// Value required among the "aud" claim, not checked if empty
+	Audience string
+	// Require an "exp" claim
+	RequireExpiry bool
+	// Allowed clock skew for "exp" and "nbf"
+	Leeway time.Duration
+}
+
+func (j JwtAuthenticator) Authenticate(r *http.Request) (interface{}, error) {
+	token, err := BearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := j.Verify(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Verifies the signature and claims of token and returns its claims
+func (j JwtAuthenticator) Verify(ctx context.Context, token string) (JwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, &InvalidTokenError{Reason: "malformed token"}
+	}
+	var header struct {
+		Alg  string          `json:"alg"`
+		Kid  string          `json:"kid"`
+		Crit json.RawMessage `json:"crit"`
+	}
+	if err := decodeJwtPart(parts[0], &header); err != nil {
+		return nil, &InvalidTokenError{Reason: "malformed header"}
+	}
+	if header.Crit != nil {
+		return nil, &InvalidTokenError{Reason: "unsupported critical header"}
+	}
+	if len(j.Algorithms) > 0 && !containsString(j.Algorithms, header.Alg) {
+		return nil, &InvalidTokenError{Reason: fmt.Sprintf("algorithm %q not allowed", header.Alg)}
+	}
+	key, err := j.Keys.Key(ctx, header.Kid, header.Alg)
+	if err != nil {
+		return nil, &InvalidTokenError{Reason: err.Error()}
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, &InvalidTokenError{Reason: "malformed signature"}
+	}
+	if err = verifyJwtSignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, &InvalidTokenError{Reason: err.Error()}
+	}
+	var claims JwtClaims
+	if err = decodeJwtPart(parts[1], &claims); err != nil {
+		return nil, &InvalidTokenError{Reason: "malformed claims"}
+	}
+	if err = j.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Validates exp, nbf, iss and aud
+func (j JwtAuthenticator) checkClaims(claims JwtClaims) error {
+	now := time.Now()
+	if exp, ok := claims.Time("exp"); ok {
+		if !now.Before(exp.Add(j.Leeway)) {
+			return &InvalidTokenError{Reason: "token expired"}
+		}
+	} else if j.RequireExpiry {
+		return &InvalidTokenError{Reason: "missing exp claim"}
+	}
+	if nbf, ok := claims.Time("nbf"); ok && now.Add(j.Leeway).Before(nbf) {
+		return &InvalidTokenError{Reason: "token not valid yet"}
+	}
+	if j.Issuer != "" && claims.Issuer() != j.Issuer {
+		return &InvalidTokenError{Reason: "unexpected issuer"}
+	}
+	if j.Audience != "" && !containsString(claims.Audience(), j.Audience) {
+		return &InvalidTokenError{Reason: "unexpected audience"}
+	}
+	return nil
+}
+
+// Decodes a base64url encoded Json part of a token into v
+func decodeJwtPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Hashes of the algorithm families by their size suffix
+var jwtHashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// Verifies the signature of signed with key using alg. Fails if key
+// does not fit alg.
+func verifyJwtSignature(alg string, key interface{}, signed, signature []byte) error {
+	if alg == "EdDSA" {
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pub, signed, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	hash, ok := jwtHashes[alg[2:]]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	var valid bool
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return errors.New("key does not fit algorithm")
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write(signed)
+		valid = hmac.Equal(mac.Sum(nil), signature)
+	case "RS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key does not fit algorithm")
+		}
+		valid = rsa.VerifyPKCS1v15(pub, hash, digest, signature) == nil
+	case "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key does not fit algorithm")
+		}
+		valid = rsa.VerifyPSS(pub, hash, digest, signature, nil) == nil
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve.Params().BitSize != map[string]int{"256": 256, "384": 384, "512": 521}[alg[2:]] {
+			return errors.New("key does not fit algorithm")
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		valid = ecdsa.Verify(pub, digest, r, s)
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if !valid {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
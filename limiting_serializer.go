@@ -0,0 +1,164 @@
+package resdk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// What LimitingSerializer does with a response larger than its limit
+type LimitPolicy int
+
+const (
+	// Discard the response and respond with a 500 error
+	LimitError LimitPolicy = iota
+	// Send the first MaxBytes bytes with the status of a
+	// *ResponseTooLargeError and a "Response-Truncated: true" header,
+	// for clients which can use partial bodies
+	LimitTruncate
+	// Stop buffering and stream the rest of the response unlimited
+	LimitStream
+)
+
+// Error for a response exceeding the limit of a LimitingSerializer.
+// Responds with 500 Internal Server Error.
+type ResponseTooLargeError struct {
+	// Limit in bytes
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response larger than %d bytes", e.Limit)
+}
+
+func (e *ResponseTooLargeError) StatusCode() int {
+	return http.StatusInternalServerError
+}
+
+// A serializer limiting the size of the response of another Serializer.
+// The response is buffered up to MaxBytes; what happens beyond that is
+// decided by Policy.
+//
+// It bounds the bytes it buffers and sends, not the memory used to
+// produce them: the Json, Xml, Msgpack and Yaml serializers marshal the
+// whole body before writing it. Bound the size of results in the
+// Processor, e.g. with pagination, to protect memory.
+type LimitingSerializer struct {
+	// Required. Serializer producing the response.
+	Serializer Serializable
+	// Maximum response body size in bytes. No limit if not positive.
+	MaxBytes int64
+	// Defaults to LimitError
+	Policy LimitPolicy
+	// Serializer for the *ResponseTooLargeError of the LimitError
+	// policy. A plain text 500 error if nil.
+	ErrorSerializer Serializable
+}
+
+// Serializes Outputable to a ResponseWriter
+func (l LimitingSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	l.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns the errors of the wrapped Serializer and
+// writing. Returns a *ResponseTooLargeError if the limit was exceeded,
+// unless Policy is LimitStream.
+func (l LimitingSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	if l.MaxBytes <= 0 {
+		return callSerializer(l.Serializer, out, w, r)
+	}
+	// Headers set before, e.g. CORS ones, are kept in error responses
+	headers := w.Header().Clone()
+	lw := &limitWriter{w: w, max: l.MaxBytes, policy: l.Policy}
+	serr := callSerializer(l.Serializer, out, lw, r)
+	if lw.streaming {
+		return serr
+	}
+	if lw.exceeded && l.Policy == LimitError {
+		too_large := &ResponseTooLargeError{Limit: l.MaxBytes}
+		for key := range w.Header() {
+			delete(w.Header(), key)
+		}
+		for key, values := range headers {
+			w.Header()[key] = values
+		}
+		if l.ErrorSerializer == nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return too_large
+		}
+		return errors.Join(too_large, callSerializer(l.ErrorSerializer, too_large, w, r))
+	}
+	if lw.exceeded {
+		// Never sent as a successful response, whose body clients
+		// would take as complete
+		too_large := &ResponseTooLargeError{Limit: l.MaxBytes}
+		w.Header().Del("Content-Length")
+		w.Header().Set("Response-Truncated", "true")
+		w.WriteHeader(too_large.StatusCode())
+		_, err := w.Write(lw.buf.Bytes())
+		return errors.Join(serr, err, too_large)
+	}
+	w.WriteHeader(lw.statusCode())
+	_, err := w.Write(lw.buf.Bytes())
+	return errors.Join(serr, err)
+}
+
+// A ResponseWriter buffering up to max bytes of the response body for
+// LimitingSerializer. The header is shared with the wrapped writer.
+type limitWriter struct {
+	w         http.ResponseWriter
+	max       int64
+	policy    LimitPolicy
+	status    int
+	buf       bytes.Buffer
+	exceeded  bool
+	streaming bool
+}
+
+func (l *limitWriter) Header() http.Header {
+	return l.w.Header()
+}
+
+func (l *limitWriter) WriteHeader(code int) {
+	if l.status == 0 {
+		l.status = code
+	}
+}
+
+// Returns the buffered status, 200 if none was written
+func (l *limitWriter) statusCode() int {
+	if l.status == 0 {
+		return http.StatusOK
+	}
+	return l.status
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if l.status == 0 {
+		l.status = http.StatusOK
+	}
+	if l.streaming {
+		return l.w.Write(p)
+	}
+	room := l.max - int64(l.buf.Len())
+	if int64(len(p)) <= room {
+		return l.buf.Write(p)
+	}
+	l.exceeded = true
+	switch l.policy {
+	case LimitTruncate:
+		// Pretend the write succeeded so the Serializer finishes
+		l.buf.Write(p[:room])
+		return len(p), nil
+	case LimitStream:
+		l.streaming = true
+		l.w.WriteHeader(l.status)
+		if _, err := l.w.Write(l.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		l.buf = bytes.Buffer{}
+		return l.w.Write(p)
+	}
+	return 0, &ResponseTooLargeError{Limit: l.max}
+}
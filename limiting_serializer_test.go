@@ -0,0 +1,51 @@
+package resdk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLimitTruncateIsNeverSuccessful(t *testing.T) {
+	s := LimitingSerializer{
+		Serializer: SerializerFunc(func(out Outputable, w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"items": ["` + strings.Repeat("a", 100) + `"]}`))
+		}),
+		MaxBytes: 16,
+		Policy:   LimitTruncate,
+	}
+	rec := httptest.NewRecorder()
+	err := s.SerializeChecked(nil, rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var too_large *ResponseTooLargeError
+	if !errors.As(err, &too_large) {
+		t.Errorf("got error %v, want *ResponseTooLargeError", err)
+	}
+	if rec.Code < 500 {
+		t.Errorf("got status %d for a truncated body, want a server error", rec.Code)
+	}
+	if rec.Header().Get("Response-Truncated") != "true" || rec.Body.Len() != 16 {
+		t.Errorf("got header %q and %d bytes, want true and 16 bytes", rec.Header().Get("Response-Truncated"), rec.Body.Len())
+	}
+}
+
+func TestLimitTruncateBelowLimit(t *testing.T) {
+	s := LimitingSerializer{
+		Serializer: SerializerFunc(func(out Outputable, w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		}),
+		MaxBytes: 16,
+		Policy:   LimitTruncate,
+	}
+	rec := httptest.NewRecorder()
+	if err := s.SerializeChecked(nil, rec, httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusCreated || rec.Body.String() != "{}" || rec.Header().Get("Response-Truncated") != "" {
+		t.Errorf("got %d %q, want 201 {} untruncated", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,54 @@
+package resdk
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Logs the outcome of the request served with x: method, path, status,
+// duration, principal and, for failed requests, the error and the
+// phase which failed, with the duration of every phase in the
+// "phases" group. Server errors are logged at LevelError, client
+// errors at LevelWarn and the rest at LevelInfo.
+func (m *BaseHandler) logRequest(r *http.Request, x *Exchange, err error) {
+	status := x.Status()
+	level := slog.LevelInfo
+	if status >= 500 {
+		level = slog.LevelError
+	} else if status >= 400 {
+		level = slog.LevelWarn
+	}
+	ctx := context.WithoutCancel(r.Context())
+	if !m.Logger.Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", status),
+		slog.Duration("duration", time.Since(x.Started)),
+		slog.String("action", string(x.Action)),
+	}
+	if principal := PrincipalID(x.AuthDetails); principal != "" {
+		attrs = append(attrs, slog.String("principal", principal))
+	}
+	if x.Tenant != "" {
+		attrs = append(attrs, slog.String("tenant", x.Tenant))
+	}
+	if err != nil {
+		attrs = append(attrs,
+			slog.String("phase", string(x.FailedPhase())),
+			slog.String("error", err.Error()))
+	}
+	timings := x.Timings()
+	var phases []interface{}
+	for _, phase := range []Phase{PhaseAuthenticate, PhaseDeserialize, PhaseValidate, PhaseValidateExternal, PhaseProcess, PhaseSerialize} {
+		if d, ok := timings[phase]; ok {
+			phases = append(phases, slog.Duration(string(phase), d))
+		}
+	}
+	attrs = append(attrs, slog.Group("phases", phases...))
+	m.Logger.LogAttrs(ctx, level, "request", attrs...)
+}
@@ -0,0 +1,377 @@
+package resdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Media type of JSON Merge Patch documents (RFC 7386)
+const MergePatchMediaType = "application/merge-patch+json"
+
+// Inputable of a JSON Merge Patch request. Unlike a struct it tells
+// omitted members (left unchanged) from members set to null (removed)
+// or to a zero value.
+type MergePatch struct {
+	// The decoded patch object. Numbers are json.Number.
+	Patch map[string]interface{}
+	// The patch also decoded into the Inputable of
+	// MergePatchDeserializer.New, nil without New
+	Input Inputable
+}
+
+// Validates Input if there is one
+func (m *MergePatch) Validate() error {
+	if m.Input != nil {
+		return m.Input.Validate()
+	}
+	return nil
+}
+
+// Returns the patch member at path of nested member names and whether
+// it is present
+func (m *MergePatch) lookup(path []string) (interface{}, bool) {
+	var current interface{} = m.Patch
+	for _, name := range path {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[name]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// Reports whether the patch has a member at path, e.g.
+// Has("address", "city"), including members set to null
+func (m *MergePatch) Has(path ...string) bool {
+	_, ok := m.lookup(path)
+	return ok
+}
+
+// Reports whether the member at path is present and null, i.e. is to
+// be removed
+func (m *MergePatch) IsNull(path ...string) bool {
+	v, ok := m.lookup(path)
+	return ok && v == nil
+}
+
+// Applies the patch to target, a pointer to a value unmarshaled with
+// JsonEngine. Only the members present in the patch are touched:
+// members set to null end up with their zero value (or are deleted
+// from maps), the others are decoded onto target. Fields Json does not
+// see, such as unexported or `json:"-"` ones, keep their value. target
+// is left unchanged if the patch fails.
+func (m *MergePatch) ApplyTo(target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("resdk: cannot apply merge patch to %T", target)
+	}
+	// Patched on a copy, so a failure leaves target unchanged
+	work := reflect.New(rv.Elem().Type()).Elem()
+	work.Set(rv.Elem())
+	if err := mergeInto(work, m.Patch); err != nil {
+		return err
+	}
+	rv.Elem().Set(work)
+	return nil
+}
+
+// Applies a decoded merge patch to the settable v. Structs and maps are
+// merged member by member; everything else is replaced by the patch
+// decoded with JsonEngine. Pointers and maps on the way are copied
+// before they are modified, so values shared with the original are not
+// changed.
+func mergeInto(v reflect.Value, patch interface{}) error {
+	object, ok := patch.(map[string]interface{})
+	if !ok || !mergeable(v.Type()) {
+		return decodeInto(v, patch)
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		copied := reflect.New(v.Type().Elem())
+		if !v.IsNil() {
+			copied.Elem().Set(v.Elem())
+		}
+		if err := mergeInto(copied.Elem(), patch); err != nil {
+			return err
+		}
+		v.Set(copied)
+	case reflect.Struct:
+		for name, value := range object {
+			index, ok := jsonFieldIndex(v.Type(), name)
+			if !ok {
+				// Unknown members are ignored like by Unmarshal
+				continue
+			}
+			field, err := writableField(v, index)
+			if err != nil {
+				return err
+			}
+			if value == nil {
+				field.SetZero()
+			} else if err := mergeInto(field, value); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), iter.Value())
+		}
+		for name, value := range object {
+			key := reflect.ValueOf(name).Convert(v.Type().Key())
+			if value == nil {
+				copied.SetMapIndex(key, reflect.Value{})
+				continue
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if existing := copied.MapIndex(key); existing.IsValid() {
+				elem.Set(existing)
+			}
+			if err := mergeInto(elem, value); err != nil {
+				return err
+			}
+			copied.SetMapIndex(key, elem)
+		}
+		v.Set(copied)
+	case reflect.Interface:
+		return decodeInto(v, mergePatch(jsonValue(v), patch))
+	}
+	return nil
+}
+
+// Reports whether values of t are merged member by member rather than
+// replaced: structs without own Json decoding, maps with string keys
+// and interfaces, through any pointers
+func mergeable(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Interface:
+		return true
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String
+	}
+	return false
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// Replaces the settable v by value decoded with JsonEngine
+func decodeInto(v reflect.Value, value interface{}) error {
+	b, err := JsonEngine.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoded := reflect.New(v.Type())
+	if err = JsonEngine.Unmarshal(b, decoded.Interface()); err != nil {
+		return err
+	}
+	v.Set(decoded.Elem())
+	return nil
+}
+
+// Returns the decoded Json form of v, nil if it cannot be marshaled
+func jsonValue(v reflect.Value) interface{} {
+	b, err := JsonEngine.Marshal(v.Interface())
+	if err != nil {
+		return nil
+	}
+	var decoded interface{}
+	if decodeJsonNumbers(b, &decoded) != nil {
+		return nil
+	}
+	return decoded
+}
+
+// Returns the index of the field of struct type t Json decodes member
+// name into: an exact match of the Json name before a case insensitive
+// one, shallower fields of embedded structs before deeper ones
+func jsonFieldIndex(t reflect.Type, name string) ([]int, bool) {
+	var fold []int
+	level := []embeddedStruct{{t: t}}
+	visited := map[reflect.Type]bool{}
+	for len(level) > 0 {
+		var next []embeddedStruct
+		for _, s := range level {
+			if visited[s.t] {
+				continue
+			}
+			visited[s.t] = true
+			for i := 0; i < s.t.NumField(); i++ {
+				field := s.t.Field(i)
+				tag := field.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				tag_name, _, _ := strings.Cut(tag, ",")
+				index := append(append([]int(nil), s.index...), i)
+				if field.Anonymous && tag_name == "" {
+					ft := field.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						next = append(next, embeddedStruct{t: ft, index: index})
+						continue
+					}
+				}
+				if !field.IsExported() {
+					continue
+				}
+				json_name := orDefault(tag_name, field.Name)
+				if json_name == name {
+					return index, true
+				}
+				if fold == nil && strings.EqualFold(json_name, name) {
+					fold = index
+				}
+			}
+		}
+		if fold != nil {
+			return fold, true
+		}
+		level = next
+	}
+	return nil, false
+}
+
+// A struct embedded at index of the struct searched by jsonFieldIndex
+type embeddedStruct struct {
+	t     reflect.Type
+	index []int
+}
+
+// Returns the field of the settable struct v at index. Embedded
+// pointers on the way are replaced by copies, allocated if nil. Like
+// with Unmarshal, nil pointers to unexported structs fail; the others
+// are not copied but used in place, since they cannot be set.
+func writableField(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, n := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			switch {
+			case v.CanSet():
+				copied := reflect.New(v.Type().Elem())
+				if !v.IsNil() {
+					copied.Elem().Set(v.Elem())
+				}
+				v.Set(copied)
+			case v.IsNil():
+				return reflect.Value{}, fmt.Errorf("resdk: cannot set embedded pointer to unexported struct %v", v.Type().Elem())
+			}
+			v = v.Elem()
+		}
+		v = v.Field(n)
+	}
+	return v, nil
+}
+
+// Applies a JSON Merge Patch to a Json document as described in
+// RFC 7386 and returns the result
+func ApplyMergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if err := decodeJsonNumbers(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := decodeJsonNumbers(patch, &p); err != nil {
+		return nil, err
+	}
+	return JsonEngine.Marshal(mergePatch(target, p))
+}
+
+// Decodes b into v keeping numbers as json.Number
+func decodeJsonNumbers(b []byte, v interface{}) error {
+	dec := JsonEngine.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// The MergePatch algorithm of RFC 7386 on decoded values
+func mergePatch(target, patch interface{}) interface{} {
+	patch_object, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	target_object, ok := target.(map[string]interface{})
+	if !ok {
+		target_object = make(map[string]interface{})
+	}
+	for name, value := range patch_object {
+		if value == nil {
+			delete(target_object, name)
+		} else {
+			target_object[name] = mergePatch(target_object[name], value)
+		}
+	}
+	return target_object
+}
+
+// Error for a merge patch which is not a Json object
+var ErrMergePatchNotObject = errors.New("Merge patch must be a Json object")
+
+// A Deserializer for JSON Merge Patch (RFC 7386) request bodies
+// producing a *MergePatch. Bodies with a Content-Type other than
+// application/merge-patch+json or application/json fail with an
+// *UnsupportedMediaTypeError.
+//
+//	func (p *UpdateUser) ProcessAuth(ctx context.Context, in resdk.Inputable, auth interface{}) (resdk.Outputable, error) {
+//		patch := in.(*resdk.MergePatch)
+//		user := load(ctx)
+//		if err := patch.ApplyTo(user); err != nil {
+//			return nil, err
+//		}
+//		...
+//	}
+type MergePatchDeserializer struct {
+	// Optional. Returns an empty Inputable the patch is also decoded
+	// into, so it can be validated.
+	New func() Inputable
+	// Maximum size of the request body. Larger bodies fail with a
+	// *BodyTooLargeError. No limit if zero.
+	MaxBytes int64
+}
+
+func (m MergePatchDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	if content_type := r.Header.Get("Content-Type"); content_type != "" {
+		media_type, _, err := mime.ParseMediaType(content_type)
+		if err != nil || (media_type != MergePatchMediaType && media_type != "application/json") {
+			return nil, &UnsupportedMediaTypeError{MediaType: content_type}
+		}
+	}
+	b, err := readBody(r, m.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, ErrEmptyBody
+	}
+	var patch interface{}
+	if err = decodeJsonNumbers(b, &patch); err != nil {
+		return nil, err
+	}
+	object, ok := patch.(map[string]interface{})
+	if !ok {
+		return nil, ErrMergePatchNotObject
+	}
+	in := &MergePatch{Patch: object}
+	if m.New != nil {
+		in.Input = m.New()
+		if err = JsonEngine.Unmarshal(b, in.Input); err != nil {
+			return nil, err
+		}
+	}
+	return in, nil
+}
@@ -0,0 +1,94 @@
+package resdk
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Deserializer and Processor pair handling one HTTP method of a
+// MethodHandler
+type MethodRoute struct {
+	// Required. Deserializer for requests with this method
+	Deserializer Deserializable
+	// Required. Processor for requests with this method
+	Processor Processable
+	// Optional. Overrides the SuccessSerializer of the base handler
+	SuccessSerializer Serializable
+}
+
+// A net/http Handler which dispatches requests to a different
+// Deserializer/Processor pair depending on the request method.
+// Requests with an unmapped method get a 405 response with the Allow
+// header listing the mapped methods. HEAD requests are served by the
+// GET route unless HEAD is mapped explicitly.
+// Use NewMethodHandler to create one.
+type MethodHandler struct {
+	base     BaseHandler
+	handlers map[string]*BaseHandler
+	allow    string
+}
+
+// Creates a MethodHandler from a base handler and a route per method.
+// All routes share the Authenticator, Interceptors and serializers of
+// base. Pass NewJsonHandler(base).BaseHandler to get the default Json
+// serializers.
+func NewMethodHandler(base BaseHandler, routes map[string]MethodRoute) *MethodHandler {
+	m := &MethodHandler{
+		base:     base,
+		handlers: make(map[string]*BaseHandler, len(routes)),
+	}
+	for method, route := range routes {
+		h := base
+		h.Deserializer = route.Deserializer
+		h.Processor = route.Processor
+		if route.SuccessSerializer != nil {
+			h.SuccessSerializer = route.SuccessSerializer
+		}
+		m.handlers[strings.ToUpper(method)] = &h
+	}
+
+	allowed := make([]string, 0, len(m.handlers)+1)
+	for method := range m.handlers {
+		allowed = append(allowed, method)
+	}
+	_, has_get := m.handlers[http.MethodGet]
+	_, has_head := m.handlers[http.MethodHead]
+	if has_get && !has_head {
+		allowed = append(allowed, http.MethodHead)
+	}
+	sort.Strings(allowed)
+	m.allow = strings.Join(allowed, ", ")
+	return m
+}
+
+// Returns the handler for method or nil if method is not mapped
+func (m *MethodHandler) handler(method string) *BaseHandler {
+	if h, ok := m.handlers[method]; ok {
+		return h
+	}
+	if method == http.MethodHead {
+		return m.handlers[http.MethodGet]
+	}
+	return nil
+}
+
+func (m *MethodHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h := m.handler(r.Method)
+	if h == nil && m.base.Cors != nil && m.base.Cors.handle(w, r) {
+		// Preflight answered
+		return
+	}
+	if h == nil {
+		w.Header().Set("Allow", m.allow)
+		if m.base.MethodNotAllowedSerializer == nil {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		err := errors.New("Method not allowed")
+		m.base.MethodNotAllowedSerializer.Serialize(err, w, r)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
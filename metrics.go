@@ -0,0 +1,239 @@
+package resdk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Receives measurements of the request lifecycle from a
+// MetricsInterceptor. Implemented by Metrics; exporting to a
+// prometheus.Registerer of github.com/prometheus/client_golang takes a
+// few lines:
+//
+//	type promRecorder struct {
+//		requests *prometheus.CounterVec   // handler, method, code
+//		errors   *prometheus.CounterVec   // handler, phase
+//		phases   *prometheus.HistogramVec // handler, phase
+//	}
+//
+//	func (p promRecorder) ObserveRequest(handler, method string, status int, d time.Duration) {
+//		p.requests.WithLabelValues(handler, method, strconv.Itoa(status)).Inc()
+//		p.phases.WithLabelValues(handler, "request").Observe(d.Seconds())
+//	}
+//
+//	func (p promRecorder) ObservePhase(handler string, phase resdk.Phase, d time.Duration, err error) {
+//		p.phases.WithLabelValues(handler, string(phase)).Observe(d.Seconds())
+//		if err != nil {
+//			p.errors.WithLabelValues(handler, string(phase)).Inc()
+//		}
+//	}
+//
+// with the vectors created by promauto.With(registerer).
+type MetricsRecorder interface {
+	// Called when a request ended with the status of its response.
+	// Methods other than the standard ones are passed as "OTHER", so
+	// clients cannot create unbounded label values.
+	ObserveRequest(handler, method string, status int, d time.Duration)
+	// Called when any other phase ended, err is the error which
+	// ended it
+	ObservePhase(handler string, phase Phase, d time.Duration, err error)
+}
+
+// An Interceptor measuring every phase of the requests of a handler
+// into Recorder, so dashboards can show where time goes:
+//
+//	metrics := resdk.NewMetrics("api")
+//	http.Handle("/metrics", metrics)
+//	orders := resdk.BaseHandler{
+//		...
+//		Interceptors: []resdk.Interceptor{
+//			resdk.MetricsInterceptor{Handler: "orders", Recorder: metrics},
+//		},
+//	}
+type MetricsInterceptor struct {
+	// Required. Name of the handler in the metrics.
+	Handler string
+	// Required
+	Recorder MetricsRecorder
+}
+
+type metricsStartKey Phase
+
+func (m MetricsInterceptor) Before(ctx context.Context, phase Phase, r *http.Request) context.Context {
+	return context.WithValue(ctx, metricsStartKey(phase), time.Now())
+}
+
+func (m MetricsInterceptor) After(ctx context.Context, phase Phase, r *http.Request, err error) {
+	start, ok := ctx.Value(metricsStartKey(phase)).(time.Time)
+	if !ok {
+		return
+	}
+	d := time.Since(start)
+	if phase != PhaseRequest {
+		m.Recorder.ObservePhase(m.Handler, phase, d, err)
+		return
+	}
+	status := http.StatusOK
+	if x := ExchangeFromContext(ctx); x != nil && x.Status() != 0 {
+		status = x.Status()
+	}
+	m.Recorder.ObserveRequest(m.Handler, metricsMethod(r.Method), status, d)
+}
+
+// Returns method if it is a standard method, "OTHER" otherwise
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// Upper bounds in seconds of the latency histograms of Metrics, the
+// same as those of the Prometheus client libraries
+var DefaultMetricsBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// A MetricsRecorder keeping metrics in memory and serving them in the
+// Prometheus text format, for services without a Prometheus client
+// library. Use NewMetrics to create one. It exports
+//
+//	<namespace>_requests_total{handler, method, code}
+//	<namespace>_phase_errors_total{handler, phase}
+//	<namespace>_phase_duration_seconds{handler, phase}
+//
+// where the duration histogram includes the "request" phase spanning
+// the whole request.
+type Metrics struct {
+	namespace string
+	buckets   []float64
+	mu        sync.Mutex
+	requests  map[[3]string]uint64
+	errors    map[[2]string]uint64
+	durations map[[2]string]*histogram
+}
+
+// Histogram of observations
+type histogram struct {
+	// Observations per bucket, not cumulative yet
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Creates an empty Metrics with names prefixed by namespace and
+// DefaultMetricsBuckets
+func NewMetrics(namespace string) *Metrics {
+	return NewMetricsWithBuckets(namespace, DefaultMetricsBuckets)
+}
+
+// Same as NewMetrics with the upper bounds of the histogram buckets in
+// seconds, in increasing order
+func NewMetricsWithBuckets(namespace string, buckets []float64) *Metrics {
+	return &Metrics{
+		namespace: namespace,
+		buckets:   buckets,
+		requests:  make(map[[3]string]uint64),
+		errors:    make(map[[2]string]uint64),
+		durations: make(map[[2]string]*histogram),
+	}
+}
+
+func (m *Metrics) ObserveRequest(handler, method string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[[3]string{handler, method, strconv.Itoa(status)}]++
+	m.observe([2]string{handler, string(PhaseRequest)}, d)
+}
+
+func (m *Metrics) ObservePhase(handler string, phase Phase, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.errors[[2]string{handler, string(phase)}]++
+	}
+	m.observe([2]string{handler, string(phase)}, d)
+}
+
+// Adds d to the duration histogram of key. m.mu must be held.
+func (m *Metrics) observe(key [2]string, d time.Duration) {
+	h, ok := m.durations[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.durations[key] = h
+	}
+	seconds := d.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// Serves the metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(m.text())
+}
+
+// Returns the metrics in the Prometheus text format
+func (m *Metrics) text() []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var buf bytes.Buffer
+	name := m.namespace + "_requests_total"
+	fmt.Fprintf(&buf, "# HELP %s Requests by handler, method and status code.\n# TYPE %s counter\n", name, name)
+	for _, key := range sortedKeys(m.requests) {
+		fmt.Fprintf(&buf, "%s{handler=%s,method=%s,code=%s} %d\n", name,
+			quoteLabel(key[0]), quoteLabel(key[1]), quoteLabel(key[2]), m.requests[key])
+	}
+	name = m.namespace + "_phase_errors_total"
+	fmt.Fprintf(&buf, "# HELP %s Failed phases by handler and phase.\n# TYPE %s counter\n", name, name)
+	for _, key := range sortedKeys(m.errors) {
+		fmt.Fprintf(&buf, "%s{handler=%s,phase=%s} %d\n", name,
+			quoteLabel(key[0]), quoteLabel(key[1]), m.errors[key])
+	}
+	name = m.namespace + "_phase_duration_seconds"
+	fmt.Fprintf(&buf, "# HELP %s Duration of phases by handler and phase.\n# TYPE %s histogram\n", name, name)
+	for _, key := range sortedKeys(m.durations) {
+		h := m.durations[key]
+		labels := "handler=" + quoteLabel(key[0]) + ",phase=" + quoteLabel(key[1])
+		var cumulative uint64
+		for i, bound := range m.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&buf, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels,
+				strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&buf, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		fmt.Fprintf(&buf, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&buf, "%s_count{%s} %d\n", name, labels, h.count)
+	}
+	return buf.Bytes()
+}
+
+// Returns the keys of series in order, so the output is stable
+func sortedKeys[K [2]string | [3]string, V any](series map[K]V) []K {
+	keys := make([]K, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	return keys
+}
+
+// Quotes a label value of the Prometheus text format
+func quoteLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
@@ -0,0 +1,89 @@
+package resdk
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+)
+
+// Marshals a value into bytes. Used to plug in encoders resdk does not
+// ship with, so that it stays free of dependencies.
+type MarshalFunc func(v interface{}) ([]byte, error)
+
+// Body sent instead of the response if it cannot be marshaled:
+// {"error": "Internal server error"} in MessagePack
+var msgpackMarshalFailureBody = append(
+	[]byte{0x81, 0xa5, 'e', 'r', 'r', 'o', 'r', 0xa0 | 21},
+	"Internal server error"...,
+)
+
+// Marshals v with marshal and writes it with the status and headers
+// chosen for out (see writeMarshaled)
+func writeMsgpack(w http.ResponseWriter, out Outputable, v interface{}, marshal MarshalFunc, def_status int) error {
+	return writeMarshaled(w, out, "application/msgpack", def_status, msgpackMarshalFailureBody, func(buf *bytes.Buffer) error {
+		if marshal == nil {
+			return errors.New("resdk: msgpack serializer has no Marshal function")
+		}
+		b, err := marshal(v)
+		buf.Write(b)
+		return err
+	})
+}
+
+// A serializer for response in MessagePack
+type MsgpackSerializer struct {
+	// HTTP Status Code to be returned unless out implements
+	// StatusCoder
+	StatusCode int
+	// Required. Marshals the response, e.g. msgpack.Marshal of
+	// github.com/vmihailenco/msgpack/v5
+	Marshal MarshalFunc
+}
+
+// Serializes Outputable to a ResponseWriter
+func (m MsgpackSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	m.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors. Responds
+// with a 500 error if out cannot be marshaled.
+func (m MsgpackSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	return writeMsgpack(w, out, out, m.Marshal, m.StatusCode)
+}
+
+// A serializer for error response in MessagePack
+type MsgpackErrorSerializer struct {
+	// HTTP Status Code to be returned unless the error implements
+	// StatusCoder
+	StatusCode int
+	// If set it overrides the error message in response
+	Error Outputable
+	// Required. Marshals the response, e.g. msgpack.Marshal of
+	// github.com/vmihailenco/msgpack/v5
+	Marshal MarshalFunc
+}
+
+// Serializes out to a ResponseWriter in standard error format.
+// Errors are sent as their error message.
+// Error response format: {"error": <object or error message>}
+func (m MsgpackErrorSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	m.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors
+func (m MsgpackErrorSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	// Status and headers always come from the original error
+	original := out
+	if m.Error != nil {
+		out = m.Error
+	}
+
+	var out_obj interface{} = out
+	if out_err, ok := out.(error); ok {
+		out_obj = map[string]interface{}{
+			"error": out_err.Error(),
+		}
+	}
+
+	return writeMsgpack(w, original, out_obj, m.Marshal, m.StatusCode)
+}
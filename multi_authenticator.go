@@ -0,0 +1,57 @@
+package resdk
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Error which Authenticators should return (possibly wrapped) when the
+// request carries no credentials of the kind they handle, as opposed to
+// invalid credentials. MultiAuthenticator leaves it out of its error
+// message when another Authenticator found credentials.
+var ErrNoCredentials = errors.New("No credentials")
+
+// An Authenticator trying several Authenticators in order (e.g. JWT,
+// then API key, then session cookie) and succeeding with the first
+// one which succeeds. If all of them fail the errors are returned as
+// AuthenticationErrors.
+type MultiAuthenticator []Authenticatable
+
+func (m MultiAuthenticator) Authenticate(r *http.Request) (interface{}, error) {
+	errs := make(AuthenticationErrors, 0, len(m))
+	for _, a := range m {
+		auth_details, err := a.Authenticate(r)
+		if err == nil {
+			return auth_details, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, ErrNoCredentials
+	}
+	return nil, errs
+}
+
+// Errors of all Authenticators of a MultiAuthenticator, in order
+type AuthenticationErrors []error
+
+// Joins the messages of the errors. ErrNoCredentials is only mentioned
+// if no Authenticator found credentials.
+func (a AuthenticationErrors) Error() string {
+	var msgs []string
+	for _, err := range a {
+		if !errors.Is(err, ErrNoCredentials) {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) == 0 {
+		return ErrNoCredentials.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Allows errors.Is and errors.As to inspect every error
+func (a AuthenticationErrors) Unwrap() []error {
+	return a
+}
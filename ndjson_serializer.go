@@ -0,0 +1,133 @@
+package resdk
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// Set of functions implemented by an Outputable producing its items one
+// at a time, in the style of sql.Rows. If it also implements io.Closer
+// it is closed once serialization ends.
+type Iterator interface {
+	// Advances to the next item. Returns false when there are no more
+	// items or an error occurred.
+	Next() bool
+	// Returns the current item
+	Value() interface{}
+	// Returns the error which stopped the iteration, if any
+	Err() error
+}
+
+// A serializer streaming collections as newline delimited Json, one
+// item per line, so that large collections never have to be held in
+// memory. out may be an Iterator, a receive channel (read until closed
+// or the request is cancelled) or a slice. Anything else is written as
+// a single line.
+//
+// The status is sent before the first item, so an error while streaming
+// cannot change it anymore; it is returned by SerializeChecked and the
+// response is cut short.
+type NdjsonSerializer struct {
+	// HTTP Status Code to be returned unless out implements
+	// StatusCoder
+	StatusCode int
+	// Flush the response after this many items. Defaults to 100.
+	FlushEvery int
+	// Also flush the response if this much time passed since the last
+	// flush. No time based flushing if zero.
+	FlushInterval time.Duration
+}
+
+// Serializes Outputable to a ResponseWriter
+func (n NdjsonSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	n.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns iteration, marshaling and write errors
+func (n NdjsonSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	if closer, ok := out.(io.Closer); ok {
+		defer closer.Close()
+	}
+	status := writeHeader(w, out, "application/x-ndjson", n.StatusCode)
+	if !bodyAllowed(status) {
+		return nil
+	}
+
+	flush_every := n.FlushEvery
+	if flush_every <= 0 {
+		flush_every = 100
+	}
+	bw := bufio.NewWriter(w)
+	enc := JsonEngine.NewEncoder(bw)
+	rc := http.NewResponseController(w)
+	count, last_flush := 0, time.Now()
+	flush := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		last_flush = time.Now()
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	}
+	write := func(item interface{}) error {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+		count++
+		if count%flush_every == 0 || (n.FlushInterval > 0 && time.Since(last_flush) >= n.FlushInterval) {
+			return flush()
+		}
+		return nil
+	}
+
+	err := streamItems(out, r, write)
+	return errors.Join(err, flush())
+}
+
+// Calls write for every item of out, see NdjsonSerializer
+func streamItems(out Outputable, r *http.Request, write func(item interface{}) error) error {
+	if it, ok := out.(Iterator); ok {
+		for it.Next() {
+			if err := write(it.Value()); err != nil {
+				return err
+			}
+		}
+		return it.Err()
+	}
+
+	v := reflect.ValueOf(out)
+	switch v.Kind() {
+	case reflect.Chan:
+		done := reflect.ValueOf(r.Context().Done())
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: v},
+			{Dir: reflect.SelectRecv, Chan: done},
+		}
+		for {
+			chosen, item, ok := reflect.Select(cases)
+			if chosen == 1 {
+				return r.Context().Err()
+			}
+			if !ok {
+				return nil
+			}
+			if err := write(item.Interface()); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := write(v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return write(out)
+}
@@ -0,0 +1,171 @@
+package resdk
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// A Serializer responsible for one media type of a NegotiatingSerializer
+type MediaSerializer struct {
+	// Media type produced by Serializer, e.g. "application/json"
+	MediaType  string
+	Serializer Serializable
+}
+
+// A Serializer which picks one of several Serializers based on the
+// Accept header of the request (proactive content negotiation). Offers
+// are in order of server preference, which breaks ties between equally
+// acceptable media types. If the request has no Accept header or
+// accepts none of the offers, the first offer is used.
+//
+//	s := &resdk.NegotiatingSerializer{Offers: []resdk.MediaSerializer{
+//		{MediaType: "application/json", Serializer: &resdk.JsonSerializer{StatusCode: http.StatusOK}},
+//		{MediaType: "application/xml", Serializer: &resdk.XmlSerializer{StatusCode: http.StatusOK}},
+//	}}
+type NegotiatingSerializer struct {
+	Offers []MediaSerializer
+}
+
+// Returns the Serializer for the Accept header of r
+func (n *NegotiatingSerializer) serializer(r *http.Request) Serializable {
+	media_types := make([]string, len(n.Offers))
+	for i, offer := range n.Offers {
+		media_types[i] = offer.MediaType
+	}
+	i := negotiate(r.Header.Values("Accept"), media_types)
+	if i < 0 {
+		i = 0
+	}
+	return n.Offers[i].Serializer
+}
+
+func (n *NegotiatingSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	n.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns the error reported by the chosen
+// Serializer if it is a CheckedSerializable
+func (n *NegotiatingSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	w.Header().Add("Vary", "Accept")
+	return callSerializer(n.serializer(r), out, w, r)
+}
+
+// A media range of an Accept header
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// Parses the values of an Accept header. Invalid ranges are skipped.
+func parseAccept(values []string) []mediaRange {
+	var ranges []mediaRange
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			media_type, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			typ, subtype, ok := strings.Cut(media_type, "/")
+			if !ok {
+				continue
+			}
+			q := 1.0
+			if qs, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(qs, 64); err != nil {
+					continue
+				}
+			}
+			ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+		}
+	}
+	return ranges
+}
+
+// Returns the quality and specificity with which ranges accept
+// media_type. Specificity is 0 for */*, 1 for type/* and 2 for an exact
+// match. Returns a quality of -1 if media_type is not accepted.
+func acceptQuality(ranges []mediaRange, media_type string) (float64, int) {
+	typ, subtype, _ := strings.Cut(strings.ToLower(media_type), "/")
+	q, specificity := -1.0, -1
+	for _, rng := range ranges {
+		s := -1
+		switch {
+		case rng.typ == typ && rng.subtype == subtype:
+			s = 2
+		case rng.typ == typ && rng.subtype == "*":
+			s = 1
+		case rng.typ == "*" && rng.subtype == "*":
+			s = 0
+		}
+		// The most specific matching range decides the quality
+		if s > specificity {
+			q, specificity = rng.q, s
+		}
+	}
+	return q, specificity
+}
+
+// Returns the index of the offer best matching the Accept header values
+// or -1 if none is acceptable. Returns 0 if there is no Accept header.
+func negotiate(accept []string, offers []string) int {
+	if len(accept) == 0 {
+		return 0
+	}
+	ranges := parseAccept(accept)
+	best, best_q := -1, 0.0
+	for i, offer := range offers {
+		q, _ := acceptQuality(ranges, offer)
+		if q > best_q {
+			best, best_q = i, q
+		}
+	}
+	return best
+}
+
+// A Deserializer responsible for one media type of a
+// NegotiatingDeserializer
+type MediaDeserializer struct {
+	// Media type read by Deserializer, e.g. "application/json". May
+	// be a range such as "text/*" or "*/*".
+	MediaType    string
+	Deserializer Deserializable
+}
+
+// A Deserializer which picks one of several Deserializers based on the
+// Content-Type header of the request, the counterpart of
+// NegotiatingSerializer. Offers are tried in order. Requests without
+// Content-Type use the first offer; requests with a Content-Type no
+// offer accepts fail with an *UnsupportedMediaTypeError (415).
+//
+//	d := &resdk.NegotiatingDeserializer{Offers: []resdk.MediaDeserializer{
+//		{MediaType: "application/json", Deserializer: resdk.JsonBodyDeserializer{New: newInput}},
+//		{MediaType: "application/x-www-form-urlencoded", Deserializer: resdk.FormDeserializer{New: newInput}},
+//	}}
+type NegotiatingDeserializer struct {
+	Offers []MediaDeserializer
+}
+
+func (n *NegotiatingDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	content_type := r.Header.Get("Content-Type")
+	if content_type == "" && len(n.Offers) > 0 {
+		return n.Offers[0].Deserializer.Deserialize(r)
+	}
+	media_type, _, err := mime.ParseMediaType(content_type)
+	if err != nil {
+		return nil, &UnsupportedMediaTypeError{MediaType: content_type}
+	}
+	typ, subtype, _ := strings.Cut(media_type, "/")
+	for _, offer := range n.Offers {
+		offer_type, offer_subtype, _ := strings.Cut(strings.ToLower(offer.MediaType), "/")
+		if (offer_type == "*" || offer_type == typ) && (offer_subtype == "*" || offer_subtype == subtype) {
+			return offer.Deserializer.Deserialize(r)
+		}
+	}
+	return nil, &UnsupportedMediaTypeError{MediaType: content_type}
+}
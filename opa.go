@@ -0,0 +1,173 @@
+package resdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Outcome of a policy evaluation
+type PolicyDecision struct {
+	Allow bool `json:"allow"`
+	// Optional explanation of a denial
+	Reason string `json:"reason,omitempty"`
+}
+
+// Evaluates a policy for an input document. Implemented by OpaClient
+// for a remote OPA; an embedded Rego engine can implement it in a few
+// lines around its prepared query.
+type PolicyEvaluator interface {
+	Evaluate(ctx context.Context, input interface{}) (PolicyDecision, error)
+}
+
+// A PolicyEvaluator querying the data API of an Open Policy Agent
+// server
+type OpaClient struct {
+	// Required. URL of the decision, e.g.
+	// "http://localhost:8181/v1/data/httpapi/authz". The decision is
+	// either a boolean or an object with "allow" and "reason".
+	URL string
+	// Defaults to http.DefaultClient
+	Client *http.Client
+}
+
+func (o OpaClient) Evaluate(ctx context.Context, input interface{}) (PolicyDecision, error) {
+	var decision PolicyDecision
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return decision, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.URL, bytes.NewReader(body))
+	if err != nil {
+		return decision, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return decision, fmt.Errorf("querying policy: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return decision, fmt.Errorf("querying policy: %s", resp.Status)
+	}
+	var result struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return decision, fmt.Errorf("decoding policy decision: %w", err)
+	}
+	// An undefined decision has no result and denies
+	if len(result.Result) == 0 || json.Unmarshal(result.Result, &decision.Allow) == nil {
+		return decision, nil
+	}
+	if err = json.Unmarshal(result.Result, &decision); err != nil {
+		return decision, fmt.Errorf("decoding policy decision: %w", err)
+	}
+	return decision, nil
+}
+
+// Error for a request denied by a policy. Responds with 403 Forbidden.
+type PolicyDeniedError struct {
+	// Reason given by the policy, if any
+	Reason string
+}
+
+func (e *PolicyDeniedError) Error() string {
+	if e.Reason == "" {
+		return "Forbidden"
+	}
+	return e.Reason
+}
+
+func (e *PolicyDeniedError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+// A RequestAuthorizable delegating decisions to Open Policy Agent, so
+// complex policies live outside Go code:
+//
+//	resdk.BaseHandler{
+//		...
+//		Authorizer: resdk.OpaAuthorizer{
+//			Evaluator: resdk.OpaClient{URL: "http://localhost:8181/v1/data/httpapi/authz"},
+//		},
+//	}
+//
+// Reads are decided after the Processor ran, with the policy input
+//
+//	{"method": "GET", "action": "read", "path": ["orders", "42"],
+//		"query": {"expand": ["items"]}, "auth": <auth details>,
+//		"output": <Outputable>}
+//
+// Writes are decided before the Processor performs them, so a denial
+// prevents the change: the policy input has the Inputable as "input"
+// instead of "output". With BaseHandler.Loader a second decision is
+// made on the loaded resource as "output".
+//
+// Auth details, Inputable and Outputable are in their Json form.
+// Denials fail with *PolicyDeniedError. Evaluation errors are returned
+// as is, so requests are denied when the policy cannot be evaluated.
+type OpaAuthorizer struct {
+	// Required
+	Evaluator PolicyEvaluator
+	// Builds the policy input of reads and loaded resources instead
+	// of the default above
+	Input func(r *http.Request, out Outputable, auth_details interface{}) interface{}
+	// Builds the policy input of writes instead of the default above
+	WriteInput func(r *http.Request, in Inputable, auth_details interface{}) interface{}
+}
+
+func (o OpaAuthorizer) AuthorizeRequest(r *http.Request, out Outputable, auth_details interface{}) error {
+	var input interface{}
+	if o.Input != nil {
+		input = o.Input(r, out, auth_details)
+	} else {
+		input = opaInput(r, "output", out, auth_details)
+	}
+	return o.decide(r, input)
+}
+
+func (o OpaAuthorizer) AuthorizeInputRequest(r *http.Request, in Inputable, auth_details interface{}) error {
+	var input interface{}
+	if o.WriteInput != nil {
+		input = o.WriteInput(r, in, auth_details)
+	} else {
+		input = opaInput(r, "input", in, auth_details)
+	}
+	return o.decide(r, input)
+}
+
+// Evaluates the policy for input
+func (o OpaAuthorizer) decide(r *http.Request, input interface{}) error {
+	decision, err := o.Evaluator.Evaluate(r.Context(), input)
+	if err != nil {
+		return err
+	}
+	if !decision.Allow {
+		return &PolicyDeniedError{Reason: decision.Reason}
+	}
+	return nil
+}
+
+// Returns the default policy input of r with value as member name
+func opaInput(r *http.Request, name string, value interface{}, auth_details interface{}) map[string]interface{} {
+	action := ActionFromMethod(r.Method)
+	if x := ExchangeFromRequest(r); x != nil {
+		action = x.Action
+	}
+	return map[string]interface{}{
+		"method": r.Method,
+		"action": action,
+		"path":   strings.Split(strings.Trim(r.URL.Path, "/"), "/"),
+		"query":  r.URL.Query(),
+		"auth":   auth_details,
+		name:     value,
+	}
+}
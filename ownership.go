@@ -0,0 +1,114 @@
+package resdk
+
+import (
+	"net/http"
+)
+
+// Set of functions which can be implemented by auth details to identify
+// the caller, e.g. JwtClaims (the subject) and *ApiKey (the owner).
+// Plain string auth details, like those of BasicUsers, identify the
+// caller themselves.
+type Principal interface {
+	// Returns the id of the caller
+	PrincipalID() string
+}
+
+// Returns the id of the caller described by auth_details, "" if it is
+// neither a Principal nor a string
+func PrincipalID(auth_details interface{}) string {
+	switch p := auth_details.(type) {
+	case Principal:
+		return p.PrincipalID()
+	case string:
+		return p
+	}
+	return ""
+}
+
+// Set of functions which can be implemented by Outputables, or the
+// Inputables of writes, belonging to a user, checked by
+// OwnershipAuthorizer
+type Owned interface {
+	// Returns the id of the owner, compared with PrincipalID
+	OwnerID() string
+}
+
+// Error for a caller accessing a resource owned by someone else.
+// Responds with 403 Forbidden.
+var ErrNotOwner error = notOwnerError{}
+
+type notOwnerError struct{}
+
+func (notOwnerError) Error() string {
+	return "Forbidden"
+}
+
+func (notOwnerError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+// Error OwnershipAuthorizer returns with HideAsNotFound. Responds with
+// 404 Not Found.
+var errOwnedNotFound error = ownedNotFoundError{}
+
+type ownedNotFoundError struct{}
+
+func (ownedNotFoundError) Error() string {
+	return "Not found"
+}
+
+func (ownedNotFoundError) StatusCode() int {
+	return http.StatusNotFound
+}
+
+// A RequestAuthorizable letting only the owner access Owned outputs,
+// the most common authorization rule. Other outputs pass.
+//
+//	resdk.BaseHandler{
+//		...
+//		Authorizer: resdk.OwnershipAuthorizer{
+//			Bypass: func(auth_details interface{}) bool {
+//				return slices.Contains(auth_details.(resdk.JwtClaims).Roles(), "admin")
+//			},
+//		},
+//		Loader: loadDocument,
+//	}
+//
+// Writes are checked before the Processor performs them: the resource
+// loaded by BaseHandler.Loader, and the Inputable if it is Owned. Set
+// Loader for handlers changing existing resources, or a non-owner's
+// write happens before the output would be refused.
+type OwnershipAuthorizer struct {
+	// Optional. Returns true for callers allowed to access everything,
+	// e.g. admins.
+	Bypass func(auth_details interface{}) bool
+	// Respond with 404 Not Found instead of 403 Forbidden, so other
+	// users cannot even learn that the resource exists
+	HideAsNotFound bool
+}
+
+func (o OwnershipAuthorizer) AuthorizeRequest(r *http.Request, out Outputable, auth_details interface{}) error {
+	return o.authorize(out, auth_details)
+}
+
+func (o OwnershipAuthorizer) AuthorizeInputRequest(r *http.Request, in Inputable, auth_details interface{}) error {
+	return o.authorize(in, auth_details)
+}
+
+// Lets the owner access v if it is Owned
+func (o OwnershipAuthorizer) authorize(v interface{}, auth_details interface{}) error {
+	owned, ok := v.(Owned)
+	if !ok {
+		return nil
+	}
+	if o.Bypass != nil && o.Bypass(auth_details) {
+		return nil
+	}
+	if caller := PrincipalID(auth_details); caller != "" && caller == owned.OwnerID() {
+		return nil
+	}
+	if o.HideAsNotFound {
+		return errOwnedNotFound
+	}
+	return ErrNotOwner
+}
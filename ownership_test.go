@@ -0,0 +1,62 @@
+package resdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOwnershipAuthorizerChecksWritesBeforeProcessing(t *testing.T) {
+	tests := []struct {
+		caller string
+		status int
+	}{
+		{"mallory", http.StatusNotFound},
+		{"alice", http.StatusOK},
+	}
+	for _, test := range tests {
+		processed := false
+		handler := actionHandler(&processed)
+		handler.Authorizer = OwnershipAuthorizer{HideAsNotFound: true}
+		handler.Loader = LoaderFunc(func(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error) {
+			return ownedInput{Owner: "alice"}, nil
+		})
+		req := httptest.NewRequest(http.MethodPut, "/documents/1", nil)
+		req.Header.Set("Authorization", test.caller)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.status || processed != (test.status == http.StatusOK) {
+			t.Errorf("%s: got %d, processed %v, want %d", test.caller, rec.Code, processed, test.status)
+		}
+	}
+}
+
+type ownedInput struct {
+	Owner string
+}
+
+func (ownedInput) Validate() error {
+	return nil
+}
+
+func (i ownedInput) OwnerID() string {
+	return i.Owner
+}
+
+func TestOwnershipAuthorizerChecksOwnedInput(t *testing.T) {
+	processed := false
+	handler := actionHandler(&processed)
+	handler.Loader = nil
+	handler.Authorizer = OwnershipAuthorizer{}
+	handler.Deserializer = DeserializerFunc(func(r *http.Request) (Inputable, error) {
+		return ownedInput{Owner: "alice"}, nil
+	})
+	req := httptest.NewRequest(http.MethodPost, "/documents", nil)
+	req.Header.Set("Authorization", "mallory")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || processed {
+		t.Errorf("got %d, processed %v, want 403 and not processed", rec.Code, processed)
+	}
+}
@@ -0,0 +1,156 @@
+package resdk
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+)
+
+// Page size used by Pagination.Validate if the request has none
+var DefaultPageLimit = 20
+
+// Largest page size accepted by Pagination.Validate
+var MaxPageLimit = 100
+
+// Pagination parameters to embed into list Inputables. The query
+// binders fill them from ?page=2&limit=50 or ?cursor=...&limit=50:
+//
+//	type ListUsers struct {
+//		resdk.Pagination
+//		Role string `query:"role"`
+//	}
+//
+// Validate applies DefaultPageLimit and MaxPageLimit; embedders with
+// other limits call ValidatePage from their own Validate.
+type Pagination struct {
+	// 1-based page number. Defaults to 1.
+	Page int `query:"page" json:"page,omitempty"`
+	// Page size
+	Limit int `query:"limit" json:"limit,omitempty"`
+	// Opaque cursor for cursor based pagination. Cannot be combined
+	// with Page.
+	Cursor string `query:"cursor" json:"cursor,omitempty"`
+}
+
+// Validates the parameters with DefaultPageLimit and MaxPageLimit, see
+// ValidatePage
+func (p *Pagination) Validate() error {
+	return p.ValidatePage(DefaultPageLimit, MaxPageLimit)
+}
+
+// Sets missing Page and Limit to 1 and default_limit and checks that
+// they are in range and that Page and Cursor are not combined. Failures
+// are *ValidationErrors.
+func (p *Pagination) ValidatePage(default_limit, max_limit int) error {
+	errs := NewValidationErrors()
+	if p.Page == 0 {
+		p.Page = 1
+	}
+	if p.Limit == 0 {
+		p.Limit = default_limit
+	}
+	if p.Page < 1 {
+		errs.Add("page", "min", "must be at least 1")
+	} else if p.Limit > 0 && p.Page-1 > math.MaxInt/p.Limit {
+		// The offset would overflow
+		errs.Add("page", "max", "is too large")
+	}
+	if p.Limit < 1 {
+		errs.Add("limit", "min", "must be at least 1")
+	} else if p.Limit > max_limit {
+		errs.Add("limit", "max", "must be at most "+strconv.Itoa(max_limit))
+	}
+	if p.Cursor != "" && p.Page > 1 {
+		errs.Add("cursor", "mutually_exclusive", "cannot be combined with page")
+	}
+	return errs.Err()
+}
+
+// Returns the number of items before the page, for OFFSET clauses.
+// Saturates at math.MaxInt for pages not checked by ValidatePage.
+func (p Pagination) Offset() int {
+	if p.Page < 1 || p.Limit < 1 {
+		return 0
+	}
+	if p.Page-1 > math.MaxInt/p.Limit {
+		return math.MaxInt
+	}
+	return (p.Page - 1) * p.Limit
+}
+
+// Outputable of one page of a page based list. Json serializers render
+// it as
+//
+//	{"items": [...], "meta": {"page": 2, "limit": 20, "total": 95,
+//		"next": "/users?limit=20&page=3", "prev": "/users?limit=20&page=1"}}
+//
+// Create it with NewPaginatedOutput.
+type PaginatedOutput struct {
+	// The items of the page, usually a slice
+	Items interface{}
+	Pagination
+	// Total number of items, negative if unknown
+	Total int64
+	// URL of the next page, empty on the last page
+	Next string
+	// URL of the previous page, empty on the first page
+	Prev string
+}
+
+// Creates the PaginatedOutput for items of the page p. total is the
+// number of items of all pages or negative if unknown, in which case a
+// full page is assumed to have a successor. Next and Prev are links
+// relative to the request URL, which is taken from the Exchange in ctx.
+func NewPaginatedOutput(ctx context.Context, p Pagination, items interface{}, total int64) *PaginatedOutput {
+	out := &PaginatedOutput{Items: items, Pagination: p, Total: total}
+	x := ExchangeFromContext(ctx)
+	if x == nil || x.Request == nil {
+		return out
+	}
+	count := 0
+	if v := reflect.ValueOf(items); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		count = v.Len()
+	}
+	has_next := count >= p.Limit && p.Limit > 0
+	if total >= 0 {
+		has_next = int64(p.Page)*int64(p.Limit) < total
+	}
+	if has_next {
+		out.Next = pageURL(x.Request.URL, p.Page+1, p.Limit)
+	}
+	if p.Page > 1 {
+		out.Prev = pageURL(x.Request.URL, p.Page-1, p.Limit)
+	}
+	return out
+}
+
+// Returns u with its page and limit query parameters replaced
+func pageURL(u *url.URL, page, limit int) string {
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(limit))
+	page_url := url.URL{Path: u.Path, RawQuery: query.Encode()}
+	return page_url.String()
+}
+
+func (p *PaginatedOutput) MarshalJSON() ([]byte, error) {
+	meta := map[string]interface{}{
+		"page":  p.Page,
+		"limit": p.Limit,
+	}
+	if p.Total >= 0 {
+		meta["total"] = p.Total
+	}
+	if p.Next != "" {
+		meta["next"] = p.Next
+	}
+	if p.Prev != "" {
+		meta["prev"] = p.Prev
+	}
+	return JsonEngine.Marshal(map[string]interface{}{
+		"items": p.Items,
+		"meta":  meta,
+	})
+}
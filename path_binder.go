@@ -0,0 +1,72 @@
+package resdk
+
+import (
+	"net/http"
+)
+
+// Returns the value of the path parameter name of r, or "" if there is
+// none. Adapters for common routers:
+//
+//	// net/http ServeMux patterns (Go 1.22+), the default
+//	func(r *http.Request, name string) string { return r.PathValue(name) }
+//	// github.com/go-chi/chi
+//	func(r *http.Request, name string) string { return chi.URLParam(r, name) }
+//	// github.com/gorilla/mux
+//	func(r *http.Request, name string) string { return mux.Vars(r)[name] }
+//	// github.com/julienschmidt/httprouter
+//	func(r *http.Request, name string) string {
+//		return httprouter.ParamsFromContext(r.Context()).ByName(name)
+//	}
+type PathParamFunc func(r *http.Request, name string) string
+
+// Reads path parameters with http.Request.PathValue
+func StdPathParam(r *http.Request, name string) string {
+	return r.PathValue(name)
+}
+
+// Binds path parameters into the fields of a struct which have a path
+// tag, e.g. `path:"id"` for /users/{id}. Parsing follows the same rules
+// as QueryDeserializer, see bindValues.
+type PathBinder struct {
+	// Reads path parameters from the router. Defaults to
+	// StdPathParam.
+	Param PathParamFunc
+}
+
+// Binds the path parameters of r into v, a pointer to a struct. Fails
+// with BindingErrors if a value cannot be parsed.
+func (p PathBinder) Bind(r *http.Request, v interface{}) error {
+	param := p.Param
+	if param == nil {
+		param = StdPathParam
+	}
+	return bindValues(v, "path", func(name string) ([]string, bool) {
+		value := param(r, name)
+		if value == "" {
+			return nil, false
+		}
+		return []string{value}, true
+	})
+}
+
+// A Deserializer binding the path parameters of the request into the
+// Inputable returned by New with a PathBinder
+type PathDeserializer struct {
+	// Required. Returns an empty Inputable to bind into, a pointer to
+	// a struct.
+	New func() Inputable
+	// Reads path parameters from the router. Defaults to
+	// StdPathParam.
+	Param PathParamFunc
+}
+
+func (p PathDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	in := p.New()
+	if err := applyDefaults(in); err != nil {
+		return nil, err
+	}
+	if err := (PathBinder{Param: p.Param}).Bind(r, in); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
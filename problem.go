@@ -0,0 +1,134 @@
+package resdk
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Media type of RFC 9457 problem details
+const ProblemMediaType = "application/problem+json"
+
+// Problem details of an error response as defined by RFC 9457. A
+// *Problem is an error itself, so Processors can return one directly.
+type Problem struct {
+	// URI identifying the problem type. Omitted (meaning
+	// "about:blank") if empty.
+	Type string `json:"type,omitempty"`
+	// Short summary of the problem type
+	Title string `json:"title,omitempty"`
+	// HTTP status code
+	Status int `json:"status,omitempty"`
+	// Explanation specific to this occurrence
+	Detail string `json:"detail,omitempty"`
+	// URI identifying this occurrence
+	Instance string `json:"instance,omitempty"`
+	// Extension members. Members named like a standard member are
+	// ignored.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+func (p *Problem) StatusCode() int {
+	return p.Status
+}
+
+// Marshals the standard members and the extension members into one
+// object
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for name, value := range p.Extensions {
+		members[name] = value
+	}
+	// Standard members always win
+	type problem Problem
+	b, err := JsonEngine.Marshal((*problem)(p))
+	if err != nil {
+		return nil, err
+	}
+	var standard map[string]interface{}
+	if err = JsonEngine.Unmarshal(b, &standard); err != nil {
+		return nil, err
+	}
+	for name, value := range standard {
+		members[name] = value
+	}
+	return JsonEngine.Marshal(members)
+}
+
+// Set of functions which can be optionally implemented by an error to
+// add extension members to its problem details
+type ProblemExtender interface {
+	// Returns the extension members, e.g. {"balance": 30}
+	ProblemExtensions() map[string]interface{}
+}
+
+// A serializer for error response as RFC 9457 problem details
+// (application/problem+json), a standards compliant alternative to the
+// {"error": "..."} format of JsonErrorSerializer.
+//
+// A *Problem (anywhere in the error chain) is sent as is, with Status
+// and Title filled in if missing. Any other error becomes a problem
+// with the status chosen by statusCode, the status text as title and
+// the error message as detail. Extension members are taken from
+// ProblemExtender errors.
+type ProblemDetailsSerializer struct {
+	// HTTP Status Code to be returned unless the error implements
+	// StatusCoder
+	StatusCode int
+	// Optional. Type of problems built from plain errors.
+	Type string
+	// Leave out the error message of plain errors, e.g. for 500s
+	HideDetail bool
+}
+
+// Serializes out to a ResponseWriter as problem details
+func (p ProblemDetailsSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	p.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors
+func (p ProblemDetailsSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	return writeJsonAs(w, out, p.problem(out), ProblemMediaType, p.StatusCode)
+}
+
+// Builds the problem details for out
+func (p ProblemDetailsSerializer) problem(out Outputable) *Problem {
+	status := statusCode(out, p.StatusCode)
+	err, _ := out.(error)
+
+	var problem Problem
+	var found *Problem
+	if err != nil && errors.As(err, &found) {
+		problem = *found
+	} else {
+		problem.Type = p.Type
+		if err != nil && !p.HideDetail {
+			problem.Detail = err.Error()
+		}
+	}
+	if problem.Status == 0 {
+		problem.Status = status
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+
+	var extender ProblemExtender
+	if err != nil && errors.As(err, &extender) {
+		extensions := make(map[string]interface{})
+		for name, value := range problem.Extensions {
+			extensions[name] = value
+		}
+		for name, value := range extender.ProblemExtensions() {
+			extensions[name] = value
+		}
+		problem.Extensions = extensions
+	}
+	return &problem
+}
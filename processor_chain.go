@@ -0,0 +1,81 @@
+package resdk
+
+import (
+	"context"
+	"fmt"
+)
+
+// Converts the Outputable of a step of a ProcessorChain into the
+// Inputable of the next step
+type ChainAdapter func(out Outputable) (Inputable, error)
+
+// A Processor piping the Outputable of each Processor into the next
+// one, e.g. fetch -> decorate -> audit. Use NewProcessorChain to
+// create one.
+//
+// The chain stops at the first error or nil Outputable, which are
+// returned as is. Context and authentication details are passed to
+// every step implementing ProcessableCtx or AuthAwareProcessable.
+type ProcessorChain struct {
+	steps []chainStep
+}
+
+type chainStep struct {
+	processor Processable
+	// Converts the output of the previous step. nil for the first step.
+	adapter ChainAdapter
+}
+
+// Creates a ProcessorChain starting with first
+func NewProcessorChain(first Processable) *ProcessorChain {
+	return &ProcessorChain{
+		steps: []chainStep{{processor: first}},
+	}
+}
+
+// Appends p to the chain. The Outputable of the previous step must
+// also implement Inputable.
+func (c *ProcessorChain) Then(p Processable) *ProcessorChain {
+	return c.ThenAdapt(nil, p)
+}
+
+// Appends p to the chain. adapter converts the Outputable of the
+// previous step into the Inputable of p.
+func (c *ProcessorChain) ThenAdapt(adapter ChainAdapter, p Processable) *ProcessorChain {
+	if adapter == nil {
+		adapter = outputAsInput
+	}
+	c.steps = append(c.steps, chainStep{processor: p, adapter: adapter})
+	return c
+}
+
+func (c *ProcessorChain) Process(in Inputable) (Outputable, error) {
+	return c.ProcessAuth(context.Background(), in, nil)
+}
+
+func (c *ProcessorChain) ProcessAuth(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error) {
+	var out Outputable
+	var err error
+	for _, step := range c.steps {
+		if step.adapter != nil {
+			in, err = step.adapter(out)
+			if err != nil {
+				return nil, err
+			}
+		}
+		out, err = callProcessor(ctx, step.processor, in, auth_details)
+		if err != nil || out == nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// Default ChainAdapter which requires out to implement Inputable
+func outputAsInput(out Outputable) (Inputable, error) {
+	in, ok := out.(Inputable)
+	if !ok {
+		return nil, fmt.Errorf("resdk: chained output %T does not implement Inputable", out)
+	}
+	return in, nil
+}
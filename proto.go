@@ -0,0 +1,108 @@
+package resdk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Set of functions implemented by generated protocol buffer messages
+// (both google.golang.org/protobuf and github.com/golang/protobuf)
+type ProtoMessage interface {
+	Reset()
+	String() string
+	ProtoMessage()
+}
+
+// Marshals and unmarshals protocol buffer messages. resdk does not
+// depend on a protobuf runtime, plug one in with e.g.
+//
+//	type protoCodec struct{}
+//
+//	func (protoCodec) Marshal(m resdk.ProtoMessage) ([]byte, error) {
+//		return proto.Marshal(m.(proto.Message))
+//	}
+//
+//	func (protoCodec) Unmarshal(b []byte, m resdk.ProtoMessage) error {
+//		return proto.Unmarshal(b, m.(proto.Message))
+//	}
+type ProtoCodec interface {
+	Marshal(m ProtoMessage) ([]byte, error)
+	Unmarshal(b []byte, m ProtoMessage) error
+}
+
+// Media type of protocol buffer bodies
+const ProtoMediaType = "application/x-protobuf"
+
+// A serializer for response in protocol buffers. out must implement
+// ProtoMessage. Pair it with e.g. JsonErrorSerializer for errors, or
+// combine it with JsonSerializer in a NegotiatingSerializer so that the
+// same Processor serves binary and Json clients.
+type ProtoSerializer struct {
+	// HTTP Status Code to be returned unless out implements
+	// StatusCoder
+	StatusCode int
+	// Required. Marshals the response.
+	Codec ProtoCodec
+}
+
+// Serializes Outputable to a ResponseWriter
+func (p ProtoSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	p.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors. Responds
+// with an empty 500 response if out cannot be marshaled.
+func (p ProtoSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	return writeMarshaled(w, out, ProtoMediaType, p.StatusCode, nil, func(buf *bytes.Buffer) error {
+		msg, ok := out.(ProtoMessage)
+		if !ok {
+			return fmt.Errorf("resdk: %T is not a protocol buffer message", out)
+		}
+		if p.Codec == nil {
+			return errors.New("resdk: ProtoSerializer has no Codec")
+		}
+		b, err := p.Codec.Marshal(msg)
+		buf.Write(b)
+		return err
+	})
+}
+
+// Inputable wrapping a protocol buffer message which does not implement
+// Inputable itself. Validate always succeeds, so validation is left to
+// the Processor.
+type ProtoInput struct {
+	Message ProtoMessage
+}
+
+func (p *ProtoInput) Validate() error {
+	return nil
+}
+
+// A deserializer for request bodies in protocol buffers. The returned
+// Inputable is the message created by New if it implements Inputable,
+// otherwise a *ProtoInput wrapping it.
+type ProtoDeserializer struct {
+	// Required. Unmarshals the request body.
+	Codec ProtoCodec
+	// Required. Returns an empty message to unmarshal into.
+	New func() ProtoMessage
+	// Maximum size of the request body. No limit if zero.
+	MaxBytes int64
+}
+
+func (p ProtoDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	b, err := readBody(r, p.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	msg := p.New()
+	if err = p.Codec.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+	if in, ok := msg.(Inputable); ok {
+		return in, nil
+	}
+	return &ProtoInput{Message: msg}, nil
+}
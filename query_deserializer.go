@@ -0,0 +1,44 @@
+package resdk
+
+import (
+	"net/http"
+)
+
+// A Deserializer binding the query parameters of the request into the
+// fields of the Inputable returned by New which have a query tag:
+//
+//	type ListUsers struct {
+//		Page   int       `query:"page" default:"1"`
+//		Limit  int       `query:"limit" default:"20"`
+//		Since  time.Time `query:"since"`
+//		Tenant resdk.UUID `query:"tenant"`
+//		Roles  []string  `query:"role"`
+//	}
+//
+// Values which cannot be parsed fail with BindingErrors (400). See
+// bindValues for the supported field types and tag options.
+type QueryDeserializer struct {
+	// Required. Returns an empty Inputable to bind into, a pointer to
+	// a struct.
+	New func() Inputable
+}
+
+func (q QueryDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	in := q.New()
+	if err := applyDefaults(in); err != nil {
+		return nil, err
+	}
+	if err := bindQuery(r, in); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// Binds the query parameters of r into v
+func bindQuery(r *http.Request, v interface{}) error {
+	query := r.URL.Query()
+	return bindValues(v, "query", func(name string) ([]string, bool) {
+		values, ok := query[name]
+		return values, ok
+	})
+}
@@ -0,0 +1,274 @@
+package resdk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Outcome of a RateLimiter decision
+type RateLimitStatus struct {
+	// Whether the request may proceed
+	Allowed bool
+	// Requests allowed per period
+	Limit int
+	// Requests left right now
+	Remaining int
+	// Time until the next request is allowed, zero if Allowed
+	RetryAfter time.Duration
+	// Time until the limit is fully replenished
+	Reset time.Duration
+}
+
+// Writes the X-RateLimit-* headers of s to header
+func (s RateLimitStatus) setHeaders(header http.Header) {
+	header.Set("X-RateLimit-Limit", strconv.Itoa(s.Limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(s.Remaining))
+	header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(s.Reset)))
+}
+
+// Rounds d up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// Decides whether requests are within their rate limit
+type RateLimiter interface {
+	// Takes a request from the limit of key
+	Allow(ctx context.Context, key string) (RateLimitStatus, error)
+}
+
+// Error for a request over its rate limit. Responds with 429 Too Many
+// Requests and the Retry-After and X-RateLimit-* headers.
+type RateLimitedError struct {
+	Status RateLimitStatus
+}
+
+func (e *RateLimitedError) Error() string {
+	return "Too many requests"
+}
+
+func (e *RateLimitedError) StatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+func (e *RateLimitedError) Headers() http.Header {
+	header := http.Header{}
+	e.Status.setHeaders(header)
+	header.Set("Retry-After", strconv.Itoa(ceilSeconds(e.Status.RetryAfter)))
+	return header
+}
+
+// Token bucket parameters: Limit requests per Period on average, with
+// bursts of up to Burst requests
+type TokenBucket struct {
+	// Required
+	Limit int
+	// Required
+	Period time.Duration
+	// Defaults to Limit
+	Burst int
+}
+
+// Returns the bucket capacity
+func (b TokenBucket) burst() float64 {
+	if b.Burst > 0 {
+		return float64(b.Burst)
+	}
+	return float64(b.Limit)
+}
+
+// Returns the tokens added per second
+func (b TokenBucket) rate() float64 {
+	return float64(b.Limit) / b.Period.Seconds()
+}
+
+// Returns the status of a bucket left with tokens
+func (b TokenBucket) status(allowed bool, tokens float64) RateLimitStatus {
+	status := RateLimitStatus{
+		Allowed:   allowed,
+		Limit:     b.Limit,
+		Remaining: int(tokens),
+		Reset:     time.Duration((b.burst() - tokens) / b.rate() * float64(time.Second)),
+	}
+	if !allowed {
+		status.RetryAfter = time.Duration((1 - tokens) / b.rate() * float64(time.Second))
+	}
+	return status
+}
+
+// A token bucket RateLimiter keeping buckets in memory, for single
+// instance deployments. Use NewMemoryRateLimiter to create one.
+type MemoryRateLimiter struct {
+	bucket    TokenBucket
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastPrune time.Time
+}
+
+type memoryBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Creates a MemoryRateLimiter with bucket
+func NewMemoryRateLimiter(bucket TokenBucket) *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		bucket:  bucket,
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+func (l *MemoryRateLimiter) Allow(ctx context.Context, key string) (RateLimitStatus, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	burst, rate := l.bucket.burst(), l.bucket.rate()
+	// Full buckets are the same as none
+	if now.Sub(l.lastPrune) > time.Minute {
+		for k, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*rate >= burst {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastPrune = now
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return l.bucket.status(allowed, b.tokens), nil
+}
+
+// Runs Lua scripts on Redis, implemented in a few lines on top of a
+// client, e.g. for github.com/redis/go-redis:
+//
+//	type redisEval struct{ c *redis.Client }
+//
+//	func (r redisEval) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+//		return r.c.Eval(ctx, script, keys, args...).Result()
+//	}
+type RedisEvaler interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// Takes a token from the bucket in KEYS[1]. ARGV holds the tokens per
+// millisecond, the capacity and the current time in milliseconds.
+// Returns whether a token was taken and the tokens left as a string,
+// since Lua numbers are truncated to integers in replies.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`
+
+// A token bucket RateLimiter keeping buckets in Redis, so limits are
+// shared between instances. Buckets are updated atomically by a Lua
+// script and expire once full.
+type RedisRateLimiter struct {
+	// Required
+	Bucket TokenBucket
+	// Required
+	Client RedisEvaler
+	// Prefix of the Redis keys. Defaults to "ratelimit:".
+	Prefix string
+}
+
+func (l RedisRateLimiter) Allow(ctx context.Context, key string) (RateLimitStatus, error) {
+	reply, err := l.Client.Eval(ctx, tokenBucketScript,
+		[]string{orDefault(l.Prefix, "ratelimit:") + key},
+		l.Bucket.rate()/1000, l.Bucket.burst(), time.Now().UnixMilli())
+	if err != nil {
+		return RateLimitStatus{}, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return RateLimitStatus{}, fmt.Errorf("resdk: unexpected rate limit reply %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	left, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(left, 64)
+	if err != nil {
+		return RateLimitStatus{}, fmt.Errorf("resdk: unexpected rate limit reply %v", reply)
+	}
+	return l.Bucket.status(allowed == 1, tokens), nil
+}
+
+// Rate limiting of a BaseHandler. Requests are limited per principal
+// (see PrincipalID) or, for anonymous callers, per client address.
+// Since that needs authentication, set ClientLimiter to throttle
+// credential guessing too.
+// Every response carries the X-RateLimit-Limit, X-RateLimit-Remaining
+// and X-RateLimit-Reset (seconds until the limit is replenished)
+// headers; requests over the limit fail with *RateLimitedError.
+//
+//	resdk.BaseHandler{
+//		...
+//		RateLimit: &resdk.RateLimitConfig{
+//			Limiter: resdk.NewMemoryRateLimiter(resdk.TokenBucket{Limit: 100, Period: time.Minute}),
+//		},
+//	}
+type RateLimitConfig struct {
+	// Required
+	Limiter RateLimiter
+	// Optional. Limits every request per client address before
+	// authentication, failed ones included.
+	ClientLimiter RateLimiter
+	// Returns the key r is limited by instead of the default above
+	// (auth_details is nil for ClientLimiter),
+	// e.g. to use ClientIP with trusted proxies
+	Key func(r *http.Request, auth_details interface{}) string
+	// Let requests through when Limiter fails instead of failing them
+	FailOpen bool
+	// Error response serializer in case of limited requests and
+	// Limiter failures. Falls back to AuthorizationErrorSerializer.
+	ErrorSerializer Serializable
+}
+
+// Takes r from its limit in limiter and sets the rate limit headers on
+// w. Returns a *RateLimitedError if r is over the limit.
+func (c *RateLimitConfig) allow(w http.ResponseWriter, r *http.Request, limiter RateLimiter, auth_details interface{}) error {
+	var key string
+	if c.Key != nil {
+		key = c.Key(r, auth_details)
+	} else if principal := PrincipalID(auth_details); principal != "" {
+		key = "principal:" + principal
+	} else {
+		key = "ip:" + ClientIP(r, nil, "").String()
+	}
+	status, err := limiter.Allow(r.Context(), key)
+	if err != nil {
+		if c.FailOpen {
+			return nil
+		}
+		return err
+	}
+	status.setHeaders(w.Header())
+	if !status.Allowed {
+		return &RateLimitedError{Status: status}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package resdk
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Set of functions which can be implemented by auth details of callers
+// with roles, e.g. JwtClaims. Permission checks fail for auth details
+// not implementing it.
+type RoleHolder interface {
+	// Returns the roles of the caller
+	Roles() []string
+}
+
+// Maps roles to the permissions they grant, e.g. from a database
+type PolicyStore interface {
+	// Returns the permissions of role, none if it is unknown
+	Permissions(ctx context.Context, role string) ([]string, error)
+}
+
+// A PolicyStore of fixed role to permission mappings. The permission
+// "*" grants every permission:
+//
+//	resdk.StaticPolicy{
+//		"admin":  {"*"},
+//		"editor": {"posts:read", "posts:write"},
+//		"viewer": {"posts:read"},
+//	}
+type StaticPolicy map[string][]string
+
+func (s StaticPolicy) Permissions(ctx context.Context, role string) ([]string, error) {
+	return s[role], nil
+}
+
+// Error for a caller whose roles lack permissions. Responds with 403
+// Forbidden.
+type PermissionDeniedError struct {
+	// Required permissions none of the roles grant
+	Missing []string
+}
+
+func (e *PermissionDeniedError) Error() string {
+	return "missing permissions " + strings.Join(e.Missing, ", ")
+}
+
+func (e *PermissionDeniedError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+// Checks that the roles of auth_details (see RoleHolder) grant all
+// permissions according to policy. Fails with *PermissionDeniedError
+// otherwise.
+func RequirePermissions(ctx context.Context, policy PolicyStore, auth_details interface{}, permissions ...string) error {
+	granted := make(map[string]bool)
+	if holder, ok := auth_details.(RoleHolder); ok {
+		for _, role := range holder.Roles() {
+			role_permissions, err := policy.Permissions(ctx, role)
+			if err != nil {
+				return err
+			}
+			for _, permission := range role_permissions {
+				granted[permission] = true
+			}
+		}
+	}
+	if granted["*"] {
+		return nil
+	}
+	var missing []string
+	for _, permission := range permissions {
+		if !granted[permission] {
+			missing = append(missing, permission)
+		}
+	}
+	if len(missing) > 0 {
+		return &PermissionDeniedError{Missing: missing}
+	}
+	return nil
+}
+
+// Permissions required for an Inputable or Outputable, checked against
+// the roles of the caller with RequirePermissions. Embedding it makes
+// the type Authorizable and InputAuthorizable:
+//
+//	type Invoice struct {
+//		resdk.PermissionAuthorizer `json:"-"`
+//		Total int64 `json:"total"`
+//	}
+//
+//	invoice.PermissionAuthorizer = resdk.PermissionAuthorizer{
+//		Policy:      policy,
+//		Permissions: []string{"invoices:read"},
+//	}
+type PermissionAuthorizer struct {
+	// Required
+	Policy PolicyStore
+	// Permissions required
+	Permissions []string
+}
+
+func (p PermissionAuthorizer) Authorize(auth_details interface{}) error {
+	return RequirePermissions(context.Background(), p.Policy, auth_details, p.Permissions...)
+}
+
+func (p PermissionAuthorizer) AuthorizeInput(auth_details interface{}) error {
+	return RequirePermissions(context.Background(), p.Policy, auth_details, p.Permissions...)
+}
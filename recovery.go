@@ -0,0 +1,51 @@
+package resdk
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// Error describing a panic recovered by BaseHandler
+type PanicError struct {
+	// Value passed to panic
+	Value interface{}
+	// Stack trace of the panicking goroutine
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Same as serve but converts panics into a response through
+// RecoverySerializer if it is set. http.ErrAbortHandler is re-panicked
+// so that net/http can abort the response.
+func (m *BaseHandler) serveRecover(w http.ResponseWriter, r *http.Request) (err error) {
+	if m.RecoverySerializer != nil {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// Panics from phases running in their own goroutine
+			// (see withTimeout) already carry their stack
+			perr, ok := rec.(*PanicError)
+			if !ok {
+				perr = &PanicError{Value: rec, Stack: debug.Stack()}
+			}
+			if perr.Value == http.ErrAbortHandler {
+				panic(http.ErrAbortHandler)
+			}
+			if m.OnPanic != nil {
+				m.OnPanic(r, perr)
+			}
+			serr := callSerializer(m.RecoverySerializer, perr, w, r)
+			if serr != nil && m.OnSerializeError != nil {
+				m.OnSerializeError(r, serr)
+			}
+			err = perr
+		}()
+	}
+	return m.serve(w, r)
+}
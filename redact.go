@@ -0,0 +1,205 @@
+package resdk
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Replacement of masked string fields
+const RedactedMask = "***"
+
+// A serializer stripping fields from the output of another Serializer
+// depending on the roles of the caller (see RoleHolder), so a single
+// type serves every audience instead of one DTO per role. Fields are
+// tagged with the roles which may see them:
+//
+//	type User struct {
+//		Name  string `json:"name"`
+//		Email string `json:"email,omitempty" redact:"admin,support"`
+//		SSN   string `json:"ssn" redact:"admin,mask"`
+//		Notes *Notes `json:"notes,omitempty" redact:"admin"`
+//	}
+//
+// For other callers tagged fields are set to their zero value, so
+// together with omitempty they disappear, or to RedactedMask for
+// strings with the mask option. Nested structs, pointers, slices and
+// maps are redacted too. The output is copied, never modified.
+//
+// Roles come from the auth details of the Exchange; anonymous callers
+// have none.
+type RedactingSerializer struct {
+	// Required. Serializer for the redacted output.
+	Serializer Serializable
+}
+
+// Serializes Outputable to a ResponseWriter
+func (s RedactingSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	s.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns the errors of the wrapped Serializer
+func (s RedactingSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	var roles []string
+	if x := ExchangeFromRequest(r); x != nil {
+		if holder, ok := x.AuthDetails.(RoleHolder); ok {
+			roles = holder.Roles()
+		}
+	}
+	return callSerializer(s.Serializer, Redact(out, roles), w, r)
+}
+
+// Returns a copy of v without the redact tagged fields roles may not
+// see, see RedactingSerializer. Returns v itself if its type has no
+// such fields.
+func Redact(v interface{}, roles []string) interface{} {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if !needsRedaction(rv.Type()) {
+		return v
+	}
+	return redactValue(rv, roles).Interface()
+}
+
+// Types by whether they contain redact tags
+var redactionTypes sync.Map
+
+// Reports whether values of t may contain redact tagged fields
+func needsRedaction(t reflect.Type) bool {
+	if cached, ok := redactionTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	needs := typeNeedsRedaction(t, make(map[reflect.Type]bool))
+	redactionTypes.Store(t, needs)
+	return needs
+}
+
+// Walks t for redact tags. Types in visiting are being walked further
+// up, which breaks the cycles of recursive types.
+func typeNeedsRedaction(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return typeNeedsRedaction(t.Elem(), visiting)
+	case reflect.Interface:
+		// Decided by the dynamic type
+		return true
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if _, tagged := field.Tag.Lookup("redact"); tagged || typeNeedsRedaction(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns a redacted copy of v
+func redactValue(v reflect.Value, roles []string) reflect.Value {
+	if !needsRedaction(v.Type()) {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.New(v.Type().Elem())
+		redacted.Elem().Set(redactValue(v.Elem(), roles))
+		return redacted
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.New(v.Type()).Elem()
+		redacted.Set(redactValue(v.Elem(), roles))
+		return redacted
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			redacted.Index(i).Set(redactValue(v.Index(i), roles))
+		}
+		return redacted
+	case reflect.Array:
+		redacted := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			redacted.Index(i).Set(redactValue(v.Index(i), roles))
+		}
+		return redacted
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		redacted := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			redacted.SetMapIndex(iter.Key(), redactValue(iter.Value(), roles))
+		}
+		return redacted
+	case reflect.Struct:
+		redacted := reflect.New(v.Type()).Elem()
+		redacted.Set(v)
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			tag, tagged := field.Tag.Lookup("redact")
+			if !tagged {
+				redacted.Field(i).Set(redactValue(v.Field(i), roles))
+				continue
+			}
+			allowed, mask := redactTag(tag)
+			if rolesIntersect(roles, allowed) {
+				redacted.Field(i).Set(redactValue(v.Field(i), roles))
+			} else if mask && field.Type.Kind() == reflect.String {
+				redacted.Field(i).SetString(RedactedMask)
+			} else {
+				redacted.Field(i).SetZero()
+			}
+		}
+		return redacted
+	}
+	return v
+}
+
+// Splits a redact tag into the allowed roles and the mask option
+func redactTag(tag string) ([]string, bool) {
+	var roles []string
+	mask := false
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "":
+		case "mask":
+			mask = true
+		default:
+			roles = append(roles, part)
+		}
+	}
+	return roles, mask
+}
+
+// Reports whether a and b have a common element
+func rolesIntersect(a, b []string) bool {
+	for _, role := range a {
+		if containsString(b, role) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package resdk
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Name of the path wildcard holding the item identifier in the routes
+// registered by RegisterResource
+const ResourceIDParam = "id"
+
+// A REST resource made of routes for the standard CRUD operations.
+// Methods return nil for operations the resource does not support.
+type Resource interface {
+	// Route for GET <path>
+	List() *MethodRoute
+	// Route for GET <path>/{id}
+	Get() *MethodRoute
+	// Route for POST <path>. Responds with 201 Created on success.
+	Create() *MethodRoute
+	// Route for PUT and PATCH <path>/{id}
+	Update() *MethodRoute
+	// Route for DELETE <path>/{id}. Responds with 204 No Content on
+	// success.
+	Delete() *MethodRoute
+}
+
+// Registers the routes of res on mux. The collection is served at path
+// and items at path/{id}, see ResourceID. Unsupported methods get a 405
+// response. All routes share the Authenticator, Interceptors and
+// serializers of base.
+func RegisterResource(mux *http.ServeMux, path string, base BaseHandler, res Resource) {
+	path = strings.TrimSuffix(path, "/")
+
+	collection := map[string]MethodRoute{}
+	if route := res.List(); route != nil {
+		collection[http.MethodGet] = *route
+	}
+	if route := res.Create(); route != nil {
+		collection[http.MethodPost] = withStatusRoute(*route, base, http.StatusCreated)
+	}
+
+	item := map[string]MethodRoute{}
+	if route := res.Get(); route != nil {
+		item[http.MethodGet] = *route
+	}
+	if route := res.Update(); route != nil {
+		item[http.MethodPut] = *route
+		item[http.MethodPatch] = *route
+	}
+	if route := res.Delete(); route != nil {
+		item[http.MethodDelete] = withStatusRoute(*route, base, http.StatusNoContent)
+	}
+
+	if len(collection) > 0 {
+		pattern := path
+		if pattern == "" {
+			pattern = "/{$}"
+		}
+		mux.Handle(pattern, NewMethodHandler(base, collection))
+	}
+	if len(item) > 0 {
+		mux.Handle(path+"/{"+ResourceIDParam+"}", NewMethodHandler(base, item))
+	}
+}
+
+// Returns the item identifier of a request routed by RegisterResource
+func ResourceID(r *http.Request) string {
+	return r.PathValue(ResourceIDParam)
+}
+
+// Returns route with a SuccessSerializer which responds with status
+// instead of 200
+func withStatusRoute(route MethodRoute, base BaseHandler, status int) MethodRoute {
+	s := route.SuccessSerializer
+	if s == nil {
+		s = base.SuccessSerializer
+	}
+	route.SuccessSerializer = statusSerializer{Serializable: s, status: status}
+	return route
+}
+
+// Wraps a Serializer and replaces a 200 status with status
+type statusSerializer struct {
+	Serializable
+	status int
+}
+
+func (s statusSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	s.SerializeChecked(out, w, r)
+}
+
+func (s statusSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	return callSerializer(s.Serializable, out, &statusWriter{ResponseWriter: w, status: s.status}, r)
+}
+
+// ResponseWriter which replaces a 200 status with status. The body is
+// dropped for 204 No Content.
+type statusWriter struct {
+	http.ResponseWriter
+	status    int
+	wrote     bool
+	drop_body bool
+}
+
+func (s *statusWriter) WriteHeader(code int) {
+	if s.wrote {
+		return
+	}
+	s.wrote = true
+	if code == http.StatusOK {
+		code = s.status
+	}
+	if code == http.StatusNoContent {
+		s.drop_body = true
+		s.Header().Del("Content-Type")
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusWriter) Write(b []byte) (int, error) {
+	if !s.wrote {
+		s.WriteHeader(http.StatusOK)
+	}
+	if s.drop_body {
+		return len(b), nil
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Allows http.ResponseController to reach the wrapped ResponseWriter
+func (s *statusWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
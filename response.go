@@ -0,0 +1,179 @@
+package resdk
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// Set of functions which can be optionally implemented by an
+// Outputable (or an error) to choose its response status code.
+// Serializers use the returned status instead of their configured
+// StatusCode. Return 0 to keep the configured one.
+type StatusCoder interface {
+	// Returns the HTTP status code of the response
+	StatusCode() int
+}
+
+// Outputable for successful responses without a body. Returning it
+// from a Processor (e.g. for DELETE) results in a 204 No Content
+// response instead of the 404 a nil Outputable results in.
+var NoContent Outputable = noContent{}
+
+type noContent struct{}
+
+func (noContent) StatusCode() int {
+	return http.StatusNoContent
+}
+
+// Outputable redirecting the client to URL, e.g. for POST-redirect-GET
+// or canonical URLs. Serializers respond with Code and a Location
+// header; the body (if any) is {"url": URL} in the serializer format.
+type Redirect struct {
+	// Target of the redirect. May be relative to the request URL.
+	URL string `json:"url" xml:"url"`
+	// One of 301, 302, 303, 307 or 308. Defaults to 302 Found.
+	Code int `json:"-" xml:"-"`
+}
+
+// Creates a Redirect to url with status code
+func RedirectTo(url string, code int) *Redirect {
+	return &Redirect{URL: url, Code: code}
+}
+
+func (rd *Redirect) StatusCode() int {
+	if rd.Code < 300 || rd.Code > 399 {
+		return http.StatusFound
+	}
+	return rd.Code
+}
+
+func (rd *Redirect) Headers() http.Header {
+	return http.Header{"Location": {rd.URL}}
+}
+
+// Set of functions which can be optionally implemented by an
+// Outputable (or an error) to add headers such as Location,
+// Cache-Control, ETag or Link to its response. Serializers honor it
+// without needing a custom Serializable.
+type Headerer interface {
+	// Returns the headers to be set on the response. They replace
+	// headers of the same name set by the serializer.
+	Headers() http.Header
+}
+
+// Writes the response header for out: sets Content-Type to
+// content_type, applies the headers of out if it is Cacheable or a
+// Headerer and writes the status code chosen by statusCode. Returns the
+// status code written. Serializers must not write a body if bodyAllowed
+// returns false for it.
+func writeHeader(w http.ResponseWriter, out Outputable, content_type string, def_status int) int {
+	w.Header().Set("Content-Type", content_type)
+	var headerer Headerer
+	if err, ok := out.(error); ok {
+		errors.As(err, &headerer)
+	} else {
+		setCacheHeaders(w.Header(), out)
+		headerer, _ = out.(Headerer)
+	}
+	if headerer != nil {
+		for key, values := range headerer.Headers() {
+			w.Header()[http.CanonicalHeaderKey(key)] = values
+		}
+	}
+	status := statusCode(out, def_status)
+	if !bodyAllowed(status) {
+		w.Header().Del("Content-Type")
+	}
+	w.WriteHeader(status)
+	return status
+}
+
+// Reports whether a response with status may have a body
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// Returns the status code chosen by out if it (or for errors anything
+// in its chain) implements StatusCoder. Returns def otherwise.
+func statusCode(out Outputable, def int) int {
+	var sc StatusCoder
+	if err, ok := out.(error); ok {
+		if !errors.As(err, &sc) {
+			return def
+		}
+	} else if sc, ok = out.(StatusCoder); !ok {
+		return def
+	}
+	if code := sc.StatusCode(); code > 0 {
+		return code
+	}
+	return def
+}
+
+// Buffers reused for marshaling responses
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// Returns buf to bufferPool unless it grew too large to keep around
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > 64<<10 {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// Marshals a response body into a buffer from bufferPool using marshal
+// and writes it with the status and headers chosen for out (see
+// writeHeader). The response is only started once marshaling
+// succeeded; if it fails failure_body is sent with a 500 status
+// instead and the marshaling error is returned.
+func writeMarshaled(w http.ResponseWriter, out Outputable, content_type string, def_status int, failure_body []byte, marshal func(buf *bytes.Buffer) error) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer putBuffer(buf)
+	if merr := marshal(buf); merr != nil {
+		w.Header().Set("Content-Type", content_type)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, err := w.Write(failure_body)
+		return errors.Join(merr, err)
+	}
+	status := writeHeader(w, out, content_type, def_status)
+	if !bodyAllowed(status) {
+		return nil
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// A ResponseWriter calling onHeader once right before the header is
+// written, so wrapping serializers can adjust headers set by the
+// wrapped one
+type headerWriter struct {
+	http.ResponseWriter
+	onHeader    func(header http.Header)
+	wroteHeader bool
+}
+
+func (h *headerWriter) WriteHeader(code int) {
+	if !h.wroteHeader {
+		h.wroteHeader = true
+		h.onHeader(h.Header())
+	}
+	h.ResponseWriter.WriteHeader(code)
+}
+
+func (h *headerWriter) Write(p []byte) (int, error) {
+	if !h.wroteHeader {
+		h.WriteHeader(http.StatusOK)
+	}
+	return h.ResponseWriter.Write(p)
+}
+
+func (h *headerWriter) Unwrap() http.ResponseWriter {
+	return h.ResponseWriter
+}
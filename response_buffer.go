@@ -0,0 +1,84 @@
+package resdk
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// A ResponseWriter buffering the complete response, so that wrapping
+// serializers can inspect and rewrite it before it is sent
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Creates an empty responseBuffer
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header)}
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(code int) {
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+// Returns the buffered status, 200 if none was written
+func (b *responseBuffer) statusCode() int {
+	if b.status == 0 {
+		return http.StatusOK
+	}
+	return b.status
+}
+
+// Sends the buffered header and status with body to w. Buffered
+// headers replace those of w, except Vary which is merged so caches
+// keep keying on the headers set earlier, e.g. Origin by CORS.
+func (b *responseBuffer) sendTo(w http.ResponseWriter, body []byte) error {
+	header := w.Header()
+	for key, values := range b.header {
+		if key == "Vary" {
+			mergeVary(header, values)
+			continue
+		}
+		header[key] = values
+	}
+	status := b.statusCode()
+	w.WriteHeader(status)
+	if !bodyAllowed(status) || len(body) == 0 {
+		return nil
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// Adds the header names of values to the Vary header of header, each
+// only once
+func mergeVary(header http.Header, values []string) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, value := range append(header.Values("Vary"), values...) {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" || seen[strings.ToLower(name)] {
+				continue
+			}
+			seen[strings.ToLower(name)] = true
+			names = append(names, name)
+		}
+	}
+	header["Vary"] = []string{strings.Join(names, ", ")}
+}
@@ -0,0 +1,27 @@
+package resdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompressingSerializerKeepsVary(t *testing.T) {
+	s := CompressingSerializer{
+		MinSize: 1,
+		Serializer: SerializerFunc(func(out Outputable, w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "accept-encoding")
+			w.Write([]byte(strings.Repeat("a", 100)))
+		}),
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	// Set by CORS before the serializer runs
+	rec.Header().Add("Vary", "Origin")
+	s.Serialize(nil, rec, r)
+	if got := rec.Header().Values("Vary"); len(got) != 1 || got[0] != "Origin, accept-encoding" {
+		t.Errorf("got Vary %q, want %q", got, "Origin, accept-encoding")
+	}
+}
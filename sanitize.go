@@ -0,0 +1,155 @@
+package resdk
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Set of functions which can be optionally implemented by an Inputable
+// to clean it up before validation, e.g. trim whitespace or lowercase
+// emails. BaseHandler calls Sanitize between Deserialize and Validate.
+// Implementations may use SanitizeFields for tag driven sanitization.
+type Sanitizable interface {
+	Sanitize()
+}
+
+var (
+	sanitizersMu sync.RWMutex
+	sanitizers   = map[string]func(string) string{
+		"trim":           strings.TrimSpace,
+		"lower":          strings.ToLower,
+		"upper":          strings.ToUpper,
+		"strip_control":  stripControl,
+		"collapse_space": collapseSpace,
+	}
+)
+
+// Registers a named string sanitizer for the sanitize tag, replacing
+// any existing one of the same name. Built in are trim, lower, upper,
+// strip_control and collapse_space. Unicode normalization is not part
+// of the standard library; plug it in with e.g.
+// golang.org/x/text/unicode/norm:
+//
+//	resdk.RegisterSanitizer("nfc", norm.NFC.String)
+func RegisterSanitizer(name string, fn func(string) string) {
+	sanitizersMu.Lock()
+	defer sanitizersMu.Unlock()
+	sanitizers[name] = fn
+}
+
+// Applies the sanitizers listed in the sanitize tags of the string
+// fields of the struct v points to, in order:
+//
+//	type SignUp struct {
+//		Email string `json:"email" sanitize:"trim,lower"`
+//		Name  string `json:"name" sanitize:"strip_control,collapse_space,trim"`
+//	}
+//
+//	func (s *SignUp) Sanitize() { resdk.SanitizeFields(s) }
+//
+// Tagged fields may be strings, string pointers or string slices.
+// Nested structs, struct pointers and slices of them are sanitized
+// recursively. Panics if a tag names an unregistered sanitizer.
+func SanitizeFields(v interface{}) {
+	sanitizeValue(reflect.ValueOf(v))
+}
+
+func sanitizeValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			sanitizeValue(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			sanitizeValue(v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if tag, ok := field.Tag.Lookup("sanitize"); ok {
+				sanitizeStrings(v.Field(i), sanitizerChain(tag))
+				continue
+			}
+			sanitizeValue(v.Field(i))
+		}
+	}
+}
+
+// Returns the sanitizers listed in a sanitize tag
+func sanitizerChain(tag string) []func(string) string {
+	sanitizersMu.RLock()
+	defer sanitizersMu.RUnlock()
+	var chain []func(string) string
+	for _, name := range strings.Split(tag, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		fn, ok := sanitizers[name]
+		if !ok {
+			panic(fmt.Sprintf("resdk: unknown sanitizer %q", name))
+		}
+		chain = append(chain, fn)
+	}
+	return chain
+}
+
+// Applies chain to the strings of v
+func sanitizeStrings(v reflect.Value, chain []func(string) string) {
+	switch v.Kind() {
+	case reflect.String:
+		if !v.CanSet() {
+			return
+		}
+		s := v.String()
+		for _, fn := range chain {
+			s = fn(s)
+		}
+		v.SetString(s)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			sanitizeStrings(v.Elem(), chain)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			sanitizeStrings(v.Index(i), chain)
+		}
+	}
+}
+
+// Removes control characters except tabs and newlines
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Replaces runs of whitespace with a single space
+func collapseSpace(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	in_space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !in_space {
+				b.WriteByte(' ')
+			}
+			in_space = true
+			continue
+		}
+		in_space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
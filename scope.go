@@ -0,0 +1,89 @@
+package resdk
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Set of functions which can be implemented by auth details carrying
+// OAuth style scopes or permissions, e.g. JwtClaims and *ApiKey. Scope
+// checks fail for auth details not implementing it.
+type Scoped interface {
+	// Returns the scopes granted to the caller
+	GrantedScopes() []string
+}
+
+// Error for a caller lacking scopes. Responds with 403 Forbidden, a
+// WWW-Authenticate header as described in RFC 6750 and, in Json,
+//
+//	{"error": "insufficient_scope", "error_description": "...",
+//		"scope": "orders:read orders:write", "missing": ["orders:write"]}
+type InsufficientScopeError struct {
+	// All scopes needed
+	Required []string
+	// Required scopes the caller was not granted
+	Missing []string
+}
+
+func (e *InsufficientScopeError) Error() string {
+	return "missing scopes " + strings.Join(e.Missing, ", ")
+}
+
+func (e *InsufficientScopeError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+func (e *InsufficientScopeError) Headers() http.Header {
+	return http.Header{"Www-Authenticate": {fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, strings.Join(e.Required, " "))}}
+}
+
+func (e *InsufficientScopeError) MarshalJSON() ([]byte, error) {
+	return JsonEngine.Marshal(map[string]interface{}{
+		"error":             "insufficient_scope",
+		"error_description": e.Error(),
+		"scope":             strings.Join(e.Required, " "),
+		"missing":           e.Missing,
+	})
+}
+
+// Checks that auth_details is Scoped and was granted all scopes. Fails
+// with *InsufficientScopeError otherwise.
+func RequireScopes(auth_details interface{}, scopes ...string) error {
+	var granted []string
+	if scoped, ok := auth_details.(Scoped); ok {
+		granted = scoped.GrantedScopes()
+	}
+	var missing []string
+	for _, scope := range scopes {
+		if !containsString(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		return &InsufficientScopeError{Required: scopes, Missing: missing}
+	}
+	return nil
+}
+
+// Scopes required for an Inputable or Outputable. Embedding it makes
+// the type Authorizable and InputAuthorizable with RequireScopes:
+//
+//	type Payroll struct {
+//		resdk.ScopeAuthorizer `json:"-"`
+//		Entries []Entry `json:"entries"`
+//	}
+//
+//	return &Payroll{ScopeAuthorizer: resdk.ScopeAuthorizer{"payroll:read"}, Entries: entries}, nil
+//
+// Scopes needed by every request of a handler are better declared with
+// BaseHandler.RequiredScopes.
+type ScopeAuthorizer []string
+
+func (s ScopeAuthorizer) Authorize(auth_details interface{}) error {
+	return RequireScopes(auth_details, s...)
+}
+
+func (s ScopeAuthorizer) AuthorizeInput(auth_details interface{}) error {
+	return RequireScopes(auth_details, s...)
+}
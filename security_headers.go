@@ -0,0 +1,69 @@
+package resdk
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Security related response headers set by BaseHandler on every
+// response, including errors. The zero value sends
+//
+//	X-Content-Type-Options: nosniff
+//	X-Frame-Options: DENY
+//	Strict-Transport-Security: max-age=31536000
+//	Content-Security-Policy: default-src 'none'; frame-ancestors 'none'
+//	Referrer-Policy: no-referrer
+//
+// which suits Json APIs. Handlers serving HTML relax
+// ContentSecurityPolicy.
+type SecurityHeaders struct {
+	// X-Frame-Options. Defaults to "DENY", "-" leaves it out.
+	FrameOptions string
+	// max-age of Strict-Transport-Security. Defaults to a year,
+	// negative leaves the header out.
+	HstsMaxAge time.Duration
+	// Adds includeSubDomains to Strict-Transport-Security
+	HstsIncludeSubDomains bool
+	// Adds preload to Strict-Transport-Security
+	HstsPreload bool
+	// Content-Security-Policy. Defaults to "default-src 'none';
+	// frame-ancestors 'none'", "-" leaves it out.
+	ContentSecurityPolicy string
+	// Referrer-Policy. Defaults to "no-referrer", "-" leaves it out.
+	ReferrerPolicy string
+}
+
+// Sets the headers on header
+func (s *SecurityHeaders) apply(header http.Header) {
+	header.Set("X-Content-Type-Options", "nosniff")
+	setSecurityHeader(header, "X-Frame-Options", s.FrameOptions, "DENY")
+	setSecurityHeader(header, "Content-Security-Policy", s.ContentSecurityPolicy, "default-src 'none'; frame-ancestors 'none'")
+	setSecurityHeader(header, "Referrer-Policy", s.ReferrerPolicy, "no-referrer")
+	if s.HstsMaxAge >= 0 {
+		max_age := s.HstsMaxAge
+		if max_age == 0 {
+			max_age = 365 * 24 * time.Hour
+		}
+		hsts := "max-age=" + strconv.FormatInt(int64(max_age/time.Second), 10)
+		if s.HstsIncludeSubDomains {
+			hsts += "; includeSubDomains"
+		}
+		if s.HstsPreload {
+			hsts += "; preload"
+		}
+		header.Set("Strict-Transport-Security", hsts)
+	}
+}
+
+// Sets header name to value, def if value is empty, or nothing if
+// value is "-"
+func setSecurityHeader(header http.Header, name, value, def string) {
+	switch value {
+	case "-":
+	case "":
+		header.Set(name, def)
+	default:
+		header.Set(name, value)
+	}
+}
@@ -0,0 +1,44 @@
+package resdk
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Phases in the order of the Server-Timing header
+var serverTimingPhases = []Phase{
+	PhaseAuthenticate,
+	PhaseDeserialize,
+	PhaseValidate,
+	PhaseValidateExternal,
+	PhaseProcess,
+	PhaseSerialize,
+}
+
+// Returns the Server-Timing header of the response, e.g.
+// "authenticate;dur=0.412, process;dur=12.5, serialize;dur=0.09, total;dur=13.2"
+// with durations in milliseconds. It is written before the body, so
+// phases still running, usually serialization, count until now.
+func (x *Exchange) serverTimingHeader() string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := time.Now()
+	var metrics []string
+	for _, phase := range serverTimingPhases {
+		d, ok := x.timings[phase]
+		if start, running := x.starts[phase]; running {
+			d, ok = d+now.Sub(start), true
+		}
+		if ok {
+			metrics = append(metrics, serverTimingMetric(string(phase), d))
+		}
+	}
+	metrics = append(metrics, serverTimingMetric("total", now.Sub(x.Started)))
+	return strings.Join(metrics, ", ")
+}
+
+// Formats a metric of the Server-Timing header
+func serverTimingMetric(name string, d time.Duration) string {
+	return name + ";dur=" + strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
@@ -0,0 +1,283 @@
+package resdk
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Error for a session cookie naming an unknown or expired session.
+// Responds with 401 Unauthorized.
+var ErrInvalidSession error = invalidSessionError{}
+
+type invalidSessionError struct{}
+
+func (invalidSessionError) Error() string {
+	return "Invalid session"
+}
+
+func (invalidSessionError) StatusCode() int {
+	return http.StatusUnauthorized
+}
+
+// A server side session of a browser client
+type Session struct {
+	// Random identifier, the value of the session cookie
+	ID string `json:"id"`
+	// Details of the signed in user, the auth details of
+	// SessionAuthenticator
+	User interface{} `json:"user"`
+	// The session is invalid from then on
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Reports whether the session has expired
+func (s *Session) Expired() bool {
+	return !time.Now().Before(s.ExpiresAt)
+}
+
+// Storage of Sessions for SessionAuthenticator
+type SessionStore interface {
+	// Returns the session with id. Returns (nil, nil) if there is
+	// none.
+	Get(ctx context.Context, id string) (*Session, error)
+	// Saves session, replacing a stored session with the same ID
+	Save(ctx context.Context, session *Session) error
+	// Removes the session with id if there is one
+	Delete(ctx context.Context, id string) error
+}
+
+// A SessionStore keeping sessions in memory, for single instance
+// deployments and development. Use NewMemorySessionStore to create one.
+type MemorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+// Creates an empty MemorySessionStore
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]*Session),
+	}
+}
+
+func (s *MemorySessionStore) Get(ctx context.Context, id string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *session
+	return &found, nil
+}
+
+func (s *MemorySessionStore) Save(ctx context.Context, session *Session) error {
+	stored := *session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session.ID] = &stored
+	now := time.Now()
+	for id, other := range s.sessions {
+		if now.After(other.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// Minimal key value storage with expiry, implemented in a few lines
+// on top of Redis, Memcached and similar clients:
+//
+//	type redisKV struct{ c *redis.Client }
+//
+//	func (r redisKV) Get(ctx context.Context, key string) ([]byte, error) {
+//		value, err := r.c.Get(ctx, key).Bytes()
+//		if errors.Is(err, redis.Nil) {
+//			return nil, nil
+//		}
+//		return value, err
+//	}
+//
+//	func (r redisKV) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+//		return r.c.Set(ctx, key, value, ttl).Err()
+//	}
+//
+//	func (r redisKV) Delete(ctx context.Context, key string) error {
+//		return r.c.Del(ctx, key).Err()
+//	}
+type KeyValueStore interface {
+	// Returns the value of key. Returns (nil, nil) if there is none.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Stores value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Removes key if it exists
+	Delete(ctx context.Context, key string) error
+}
+
+// A SessionStore keeping sessions as Json in a KeyValueStore, e.g.
+// Redis, so they are shared between instances
+type KVSessionStore struct {
+	// Required
+	Store KeyValueStore
+	// Prepended to session ids to form keys. Defaults to "session:".
+	Prefix string
+	// Returns a pointer to decode Session.User into. User is decoded
+	// as generic Json (maps, float64, ...) if nil.
+	NewUser func() interface{}
+}
+
+func (k KVSessionStore) key(id string) string {
+	return orDefault(k.Prefix, "session:") + id
+}
+
+func (k KVSessionStore) Get(ctx context.Context, id string) (*Session, error) {
+	data, err := k.Store.Get(ctx, k.key(id))
+	if err != nil || data == nil {
+		return nil, err
+	}
+	session := &Session{}
+	if k.NewUser != nil {
+		session.User = k.NewUser()
+	}
+	if err = json.Unmarshal(data, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (k KVSessionStore) Save(ctx context.Context, session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return k.Store.Set(ctx, k.key(session.ID), data, time.Until(session.ExpiresAt))
+}
+
+func (k KVSessionStore) Delete(ctx context.Context, id string) error {
+	return k.Store.Delete(ctx, k.key(id))
+}
+
+// An Authenticator for browser facing APIs resolving a session cookie
+// to the Session.User of a SessionStore. Requests without the cookie
+// fail with ErrNoCredentials, unknown and expired sessions with
+// ErrInvalidSession.
+//
+// Processors sign users in and out with Start and End:
+//
+//	func (l login) ProcessAuth(ctx context.Context, in resdk.Inputable, _ interface{}) (resdk.Outputable, error) {
+//		user, err := l.users.Check(ctx, in.(*Credentials))
+//		if err != nil {
+//			return nil, err
+//		}
+//		if _, err = l.sessions.Start(ctx, user); err != nil {
+//			return nil, err
+//		}
+//		return user, nil
+//	}
+type SessionAuthenticator struct {
+	// Required
+	Store SessionStore
+	// Defaults to "session"
+	CookieName string
+	// Lifetime of new sessions. Defaults to 24 hours.
+	TTL time.Duration
+	// Cookie attributes. Path defaults to "/", SameSite to Lax.
+	Path     string
+	Domain   string
+	SameSite http.SameSite
+	// Send the cookie over plain HTTP too. Only for development.
+	Insecure bool
+}
+
+func (s SessionAuthenticator) Authenticate(r *http.Request) (interface{}, error) {
+	cookie, err := r.Cookie(orDefault(s.CookieName, "session"))
+	if err != nil || cookie.Value == "" {
+		return nil, ErrNoCredentials
+	}
+	session, err := s.Store.Get(r.Context(), cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.Expired() {
+		return nil, ErrInvalidSession
+	}
+	return session.User, nil
+}
+
+// Creates a session for user with a new random id, saves it and sets
+// the cookie on the response. Starting a fresh session on sign in
+// prevents session fixation. ctx must be the context of a request
+// served by a BaseHandler.
+func (s SessionAuthenticator) Start(ctx context.Context, user interface{}) (*Session, error) {
+	x := ExchangeFromContext(ctx)
+	if x == nil {
+		return nil, errors.New("resdk: no exchange in context")
+	}
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	session := &Session{
+		ID:        base64.RawURLEncoding.EncodeToString(id),
+		User:      user,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.Store.Save(ctx, session); err != nil {
+		return nil, err
+	}
+	http.SetCookie(x.Writer, s.cookie(session.ID, session.ExpiresAt))
+	return session, nil
+}
+
+// Deletes the session of the request and expires its cookie. ctx must
+// be the context of a request served by a BaseHandler.
+func (s SessionAuthenticator) End(ctx context.Context) error {
+	x := ExchangeFromContext(ctx)
+	if x == nil {
+		return errors.New("resdk: no exchange in context")
+	}
+	if cookie, err := x.Request.Cookie(orDefault(s.CookieName, "session")); err == nil {
+		if err = s.Store.Delete(ctx, cookie.Value); err != nil {
+			return err
+		}
+	}
+	cookie := s.cookie("", time.Unix(0, 0))
+	cookie.MaxAge = -1
+	http.SetCookie(x.Writer, cookie)
+	return nil
+}
+
+// Returns the session cookie with value
+func (s SessionAuthenticator) cookie(value string, expires time.Time) *http.Cookie {
+	same_site := s.SameSite
+	if same_site == 0 {
+		same_site = http.SameSiteLaxMode
+	}
+	return &http.Cookie{
+		Name:     orDefault(s.CookieName, "session"),
+		Value:    value,
+		Path:     orDefault(s.Path, "/"),
+		Domain:   s.Domain,
+		Expires:  expires,
+		Secure:   !s.Insecure,
+		HttpOnly: true,
+		SameSite: same_site,
+	}
+}
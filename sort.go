@@ -0,0 +1,127 @@
+package resdk
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// One field of a Sort
+type SortField struct {
+	// Field name as given by the client
+	Field string
+	// Sort descending instead of ascending
+	Desc bool
+}
+
+// Sort order parsed from a parameter such as ?sort=-created_at,name: a
+// comma separated list of fields, descending if prefixed with "-" and
+// ascending otherwise (optionally prefixed with "+"). It binds from
+// query parameters:
+//
+//	type ListUsers struct {
+//		Sort resdk.Sort `query:"sort" default:"-created_at"`
+//	}
+//
+//	var userSortColumns = map[string]string{"created_at": "u.created_at", "name": "u.name"}
+//
+//	func (l *ListUsers) Validate() error {
+//		return l.Sort.Check("sort", userSortColumns)
+//	}
+//
+// Never put the fields themselves into queries; SQL maps them through
+// the allowlist.
+type Sort []SortField
+
+func (s *Sort) UnmarshalText(text []byte) error {
+	var parsed Sort
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(string(text), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Field: part}
+		if strings.HasPrefix(part, "-") {
+			field = SortField{Field: part[1:], Desc: true}
+		} else if strings.HasPrefix(part, "+") {
+			field.Field = part[1:]
+		}
+		if !validSortField(field.Field) {
+			return fmt.Errorf("invalid sort field %q", field.Field)
+		}
+		if seen[field.Field] {
+			return fmt.Errorf("duplicate sort field %q", field.Field)
+		}
+		seen[field.Field] = true
+		parsed = append(parsed, field)
+	}
+	*s = parsed
+	return nil
+}
+
+func (s Sort) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Returns the sort in parameter syntax, e.g. "-created_at,name"
+func (s Sort) String() string {
+	parts := make([]string, len(s))
+	for i, field := range s {
+		parts[i] = field.Field
+		if field.Desc {
+			parts[i] = "-" + field.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Reports whether name only has letters, digits, underscores and dots
+func validSortField(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks that all fields are keys of allowed, which maps client field
+// names to columns. Failures are *ValidationErrors for param, the name
+// of the parameter.
+func (s Sort) Check(param string, allowed map[string]string) error {
+	errs := NewValidationErrors()
+	for _, field := range s {
+		if _, ok := allowed[field.Field]; !ok {
+			names := make([]string, 0, len(allowed))
+			for name := range allowed {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			errs.Add(param, "sort", fmt.Sprintf("cannot sort by %q, allowed are %s", field.Field, strings.Join(names, ", ")))
+		}
+	}
+	return errs.Err()
+}
+
+// Returns an ORDER BY list such as "u.created_at DESC, u.name ASC" with
+// the columns allowed maps the fields to. Fields not in allowed are
+// left out, so the result never contains client input.
+func (s Sort) SQL(allowed map[string]string) string {
+	parts := make([]string, 0, len(s))
+	for _, field := range s {
+		column, ok := allowed[field.Field]
+		if !ok {
+			continue
+		}
+		if field.Desc {
+			parts = append(parts, column+" DESC")
+		} else {
+			parts = append(parts, column+" ASC")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
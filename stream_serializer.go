@@ -0,0 +1,134 @@
+package resdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Set of functions implemented by an Outputable writing its response
+// body incrementally, e.g. a large report generated row by row. Writes
+// block while the client is slow to read, so the Outputable never gets
+// ahead of the connection.
+type Streamer interface {
+	// Writes the body to w. ctx is cancelled when the client goes
+	// away.
+	Stream(ctx context.Context, w io.Writer) error
+}
+
+// A serializer for Streamer Outputables which writes the body straight
+// to the connection instead of buffering it, flushing it regularly.
+//
+// The status is sent before streaming starts, so an error while
+// streaming cannot change it anymore; it is returned by
+// SerializeChecked and the response is cut short.
+type StreamSerializer struct {
+	// HTTP Status Code to be returned unless out implements
+	// StatusCoder
+	StatusCode int
+	// Content-Type of the response. Defaults to
+	// application/octet-stream.
+	ContentType string
+	// Flush the response once this many bytes were written since the
+	// last flush. Defaults to 32KiB.
+	FlushBytes int
+	// Also flush the response if this much time passed since the last
+	// flush. No time based flushing if zero.
+	FlushInterval time.Duration
+	// Deadline for every single write to the connection, so a client
+	// which stops reading cannot hold the handler forever. No deadline
+	// if zero.
+	WriteTimeout time.Duration
+}
+
+// Serializes Outputable to a ResponseWriter
+func (s StreamSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	s.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns streaming and write errors
+func (s StreamSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	if closer, ok := out.(io.Closer); ok {
+		defer closer.Close()
+	}
+	streamer, ok := out.(Streamer)
+	if !ok {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return fmt.Errorf("resdk: stream serializer received %T", out)
+	}
+	content_type := s.ContentType
+	if content_type == "" {
+		content_type = "application/octet-stream"
+	}
+	status := writeHeader(w, out, content_type, s.StatusCode)
+	if !bodyAllowed(status) {
+		return nil
+	}
+
+	fw := &flushWriter{
+		w:             w,
+		rc:            http.NewResponseController(w),
+		flushBytes:    s.FlushBytes,
+		flushInterval: s.FlushInterval,
+		writeTimeout:  s.WriteTimeout,
+		lastFlush:     time.Now(),
+	}
+	if fw.flushBytes <= 0 {
+		fw.flushBytes = 32 << 10
+	}
+	err := streamer.Stream(r.Context(), fw)
+	return errors.Join(err, fw.flush())
+}
+
+// A writer flushing to the connection by size and time and applying a
+// deadline to every write
+type flushWriter struct {
+	w             io.Writer
+	rc            *http.ResponseController
+	flushBytes    int
+	flushInterval time.Duration
+	writeTimeout  time.Duration
+	pending       int
+	lastFlush     time.Time
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	if err := f.deadline(); err != nil {
+		return 0, err
+	}
+	n, err := f.w.Write(p)
+	f.pending += n
+	if err != nil {
+		return n, err
+	}
+	if f.pending >= f.flushBytes || (f.flushInterval > 0 && time.Since(f.lastFlush) >= f.flushInterval) {
+		return n, f.flush()
+	}
+	return n, nil
+}
+
+// Sets the write deadline for the next write
+func (f *flushWriter) deadline() error {
+	if f.writeTimeout <= 0 {
+		return nil
+	}
+	err := f.rc.SetWriteDeadline(time.Now().Add(f.writeTimeout))
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
+
+func (f *flushWriter) flush() error {
+	f.pending, f.lastFlush = 0, time.Now()
+	if err := f.deadline(); err != nil {
+		return err
+	}
+	if err := f.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package resdk
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// A validator checking struct tags such as
+// `validate:"required,email,max=50"`. Satisfied by *validator.Validate
+// of github.com/go-playground/validator.
+type StructValidator interface {
+	Struct(s interface{}) error
+}
+
+// A failed check of one field reported by a StructValidator. Satisfied
+// by validator.FieldError of github.com/go-playground/validator, whose
+// validator.ValidationErrors is a slice of them.
+type FieldError interface {
+	// Path of the field including the struct name, e.g.
+	// "CreateUser.Address.City"
+	Namespace() string
+	// Name of the field
+	Field() string
+	// The failed check, e.g. "required"
+	Tag() string
+	// Parameter of the check, e.g. "50" for max=50
+	Param() string
+	Error() string
+}
+
+// Messages of TagValidator for common checks. %s is replaced with the
+// parameter of the check.
+var DefaultValidationMessages = map[string]string{
+	"required": "is required",
+	"email":    "must be a valid email address",
+	"url":      "must be a valid URL",
+	"uuid":     "must be a valid UUID",
+	"min":      "must be at least %s",
+	"max":      "must be at most %s",
+	"len":      "must have length %s",
+	"gt":       "must be greater than %s",
+	"gte":      "must be at least %s",
+	"lt":       "must be less than %s",
+	"lte":      "must be at most %s",
+	"oneof":    "must be one of %s",
+}
+
+// Adapts a StructValidator to Inputable.Validate, turning its failures
+// into *ValidationErrors keyed by field path and coded by the failed
+// check:
+//
+//	var validate = resdk.TagValidator{Validator: validator.New()}
+//
+//	func (c *CreateUser) Validate() error {
+//		return validate.Validate(c)
+//	}
+type TagValidator struct {
+	// Required. Checks the struct tags.
+	Validator StructValidator
+	// Returns the message of a failed check, e.g. using the
+	// translations of the validator library. Defaults to
+	// DefaultValidationMessages with a generic fallback.
+	Translate func(fe FieldError) string
+}
+
+// Validates the struct v. Returns *ValidationErrors if checks failed or
+// the error of the StructValidator if it is not made of FieldErrors.
+func (t TagValidator) Validate(v interface{}) error {
+	err := t.Validator.Struct(v)
+	if err == nil {
+		return nil
+	}
+	field_errs := fieldErrors(err)
+	if field_errs == nil {
+		return err
+	}
+	translate := t.Translate
+	if translate == nil {
+		translate = defaultTranslate
+	}
+	errs := NewValidationErrors()
+	for _, fe := range field_errs {
+		errs.AddPath(fieldPath(fe), fe.Tag(), translate(fe))
+	}
+	return errs
+}
+
+// Returns the FieldErrors err consists of, or nil if it is no slice of
+// FieldErrors
+func fieldErrors(err error) []FieldError {
+	if fe, ok := err.(FieldError); ok {
+		return []FieldError{fe}
+	}
+	rv := reflect.ValueOf(err)
+	if rv.Kind() != reflect.Slice {
+		return nil
+	}
+	fes := make([]FieldError, rv.Len())
+	for i := range fes {
+		fe, ok := rv.Index(i).Interface().(FieldError)
+		if !ok {
+			return nil
+		}
+		fes[i] = fe
+	}
+	return fes
+}
+
+// Returns the path of the field without the name of the root struct
+func fieldPath(fe FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
+
+func defaultTranslate(fe FieldError) string {
+	if message, ok := DefaultValidationMessages[fe.Tag()]; ok {
+		if strings.Contains(message, "%s") {
+			return fmt.Sprintf(message, fe.Param())
+		}
+		return message
+	}
+	return fmt.Sprintf("failed the %q check", fe.Tag())
+}
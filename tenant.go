@@ -0,0 +1,172 @@
+package resdk
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Error for a request whose tenant cannot be determined. Responds with
+// 400 Bad Request.
+var ErrTenantRequired error = tenantRequiredError{}
+
+type tenantRequiredError struct{}
+
+func (tenantRequiredError) Error() string {
+	return "Tenant required"
+}
+
+func (tenantRequiredError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// Error for a caller accessing a tenant it does not belong to.
+// Responds with 403 Forbidden.
+var ErrTenantForbidden error = tenantForbiddenError{}
+
+type tenantForbiddenError struct{}
+
+func (tenantForbiddenError) Error() string {
+	return "Forbidden"
+}
+
+func (tenantForbiddenError) StatusCode() int {
+	return http.StatusForbidden
+}
+
+// Determines the tenant of a request in multi tenant APIs. BaseHandler
+// runs its TenantResolver right after authentication and stores the
+// tenant in the Exchange, where Processors find it with
+// TenantFromContext.
+type TenantResolvable interface {
+	// Returns the tenant id of r, "" if r names none. auth_details is
+	// the same as returned by Authenticatable.Authenticate.
+	ResolveTenant(r *http.Request, auth_details interface{}) (string, error)
+}
+
+// Resolves the tenant from the first label of subdomains of Domain,
+// e.g. "acme" for acme.api.example.com with Domain "api.example.com"
+type SubdomainTenant struct {
+	// Required
+	Domain string
+}
+
+func (s SubdomainTenant) ResolveTenant(r *http.Request, auth_details interface{}) (string, error) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	prefix, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(s.Domain))
+	if !ok || prefix == "" || strings.Contains(prefix, ".") {
+		return "", nil
+	}
+	return prefix, nil
+}
+
+// Resolves the tenant from a request header, e.g. X-Tenant-ID
+type HeaderTenant string
+
+func (h HeaderTenant) ResolveTenant(r *http.Request, auth_details interface{}) (string, error) {
+	return r.Header.Get(string(h)), nil
+}
+
+// Resolves the tenant from a string claim of JwtClaims auth details,
+// e.g. "tenant_id"
+type ClaimTenant string
+
+func (c ClaimTenant) ResolveTenant(r *http.Request, auth_details interface{}) (string, error) {
+	claims, _ := auth_details.(JwtClaims)
+	return claims.String(string(c)), nil
+}
+
+// Tries TenantResolvables in order and returns the first tenant found
+type TenantResolvers []TenantResolvable
+
+func (t TenantResolvers) ResolveTenant(r *http.Request, auth_details interface{}) (string, error) {
+	for _, resolver := range t {
+		tenant, err := resolver.ResolveTenant(r, auth_details)
+		if err != nil || tenant != "" {
+			return tenant, err
+		}
+	}
+	return "", nil
+}
+
+// Checks that the caller belongs to the tenant found by Resolver, which
+// matters when the tenant comes from the request (subdomain, header)
+// rather than from the credentials. Fails with ErrTenantForbidden.
+//
+//	resdk.TenantMembership{
+//		Resolver: resdk.SubdomainTenant{Domain: "api.example.com"},
+//		Member: func(auth_details interface{}, tenant string) bool {
+//			return auth_details.(resdk.JwtClaims).String("tenant_id") == tenant
+//		},
+//	}
+type TenantMembership struct {
+	// Required
+	Resolver TenantResolvable
+	// Required. Reports whether the caller belongs to tenant.
+	Member func(auth_details interface{}, tenant string) bool
+}
+
+func (t TenantMembership) ResolveTenant(r *http.Request, auth_details interface{}) (string, error) {
+	tenant, err := t.Resolver.ResolveTenant(r, auth_details)
+	if err != nil || tenant == "" {
+		return tenant, err
+	}
+	if !t.Member(auth_details, tenant) {
+		return "", ErrTenantForbidden
+	}
+	return tenant, nil
+}
+
+// Returns the tenant of the request served with ctx, "" if there is
+// none
+func TenantFromContext(ctx context.Context) string {
+	if x := ExchangeFromContext(ctx); x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+// Set of functions which can be implemented by Outputables, or the
+// Inputables of writes, belonging to a tenant, checked by
+// TenantScopedAuthorizer
+type TenantOwned interface {
+	// Returns the id of the tenant owning the object
+	TenantID() string
+}
+
+// A RequestAuthorizable hiding TenantOwned outputs of other tenants
+// than the one of the request behind a 404 Not Found, as a safety net
+// against Processors forgetting to scope their queries. Other outputs
+// pass.
+//
+// Writes are checked before the Processor performs them: the resource
+// loaded by BaseHandler.Loader, and the Inputable if it is TenantOwned.
+// Inputs naming another tenant fail with ErrTenantForbidden; inputs
+// naming none pass, leaving the tenant to the Processor.
+type TenantScopedAuthorizer struct{}
+
+func (TenantScopedAuthorizer) AuthorizeRequest(r *http.Request, out Outputable, auth_details interface{}) error {
+	owned, ok := out.(TenantOwned)
+	if !ok {
+		return nil
+	}
+	if tenant := TenantFromContext(r.Context()); tenant == "" || tenant != owned.TenantID() {
+		return errOwnedNotFound
+	}
+	return nil
+}
+
+func (TenantScopedAuthorizer) AuthorizeInputRequest(r *http.Request, in Inputable, auth_details interface{}) error {
+	owned, ok := in.(TenantOwned)
+	if !ok || owned.TenantID() == "" {
+		return nil
+	}
+	if tenant := TenantFromContext(r.Context()); tenant == "" || tenant != owned.TenantID() {
+		return ErrTenantForbidden
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package resdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type tenantInput struct {
+	Tenant string
+}
+
+func (tenantInput) Validate() error {
+	return nil
+}
+
+func (i tenantInput) TenantID() string {
+	return i.Tenant
+}
+
+// Serves a write of the tenant of the X-Tenant header with input
+// and loaded, returning the status and whether the Processor ran
+func serveTenantWrite(t *testing.T, input, loaded tenantInput) (int, bool) {
+	t.Helper()
+	processed := false
+	handler := actionHandler(&processed)
+	handler.TenantResolver = HeaderTenant("X-Tenant")
+	handler.Authorizer = TenantScopedAuthorizer{}
+	handler.Deserializer = DeserializerFunc(func(r *http.Request) (Inputable, error) {
+		return input, nil
+	})
+	handler.Loader = LoaderFunc(func(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error) {
+		return loaded, nil
+	})
+	handler.Processor = ProcessorFunc(func(in Inputable) (Outputable, error) {
+		processed = true
+		return loaded, nil
+	})
+	req := httptest.NewRequest(http.MethodPut, "/documents/1", nil)
+	req.Header.Set("X-Tenant", "acme")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code, processed
+}
+
+func TestTenantScopedAuthorizerChecksWritesBeforeProcessing(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  tenantInput
+		loaded tenantInput
+		status int
+	}{
+		{"same tenant", tenantInput{"acme"}, tenantInput{"acme"}, http.StatusOK},
+		{"input without tenant", tenantInput{}, tenantInput{"acme"}, http.StatusOK},
+		{"input of other tenant", tenantInput{"globex"}, tenantInput{"acme"}, http.StatusForbidden},
+		{"resource of other tenant", tenantInput{}, tenantInput{"globex"}, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		status, processed := serveTenantWrite(t, test.input, test.loaded)
+		if status != test.status || processed != (test.status == http.StatusOK) {
+			t.Errorf("%s: got %d, processed %v, want %d", test.name, status, processed, test.status)
+		}
+	}
+}
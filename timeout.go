@@ -0,0 +1,135 @@
+package resdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// Error sent through TimeoutSerializer when a phase exceeds its
+// timeout. It responds with 408 Request Timeout for PhaseDeserialize
+// (the client was too slow sending the body) and with 504 Gateway
+// Timeout otherwise. errors.Is(err, context.DeadlineExceeded) holds.
+type TimeoutError struct {
+	// Phase which timed out
+	Phase Phase
+	// Timeout which was exceeded
+	Timeout time.Duration
+}
+
+func (t *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", t.Phase, t.Timeout)
+}
+
+func (t *TimeoutError) StatusCode() int {
+	if t.Phase == PhaseDeserialize {
+		return http.StatusRequestTimeout
+	}
+	return http.StatusGatewayTimeout
+}
+
+func (t *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// Runs fn with a request whose context is cancelled after timeout.
+// Returns a *TimeoutError without waiting for fn if the timeout is
+// exceeded, so fn keeps running until it returns by itself. A panic in fn is re-panicked as a *PanicError in the
+// calling goroutine. fn runs directly if timeout is not positive.
+func withTimeout[T any](r *http.Request, phase Phase, timeout time.Duration, fn func(r *http.Request) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(r)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+	// Buffered so that fn can finish after a timeout without leaking
+	// the goroutine
+	done := make(chan result, 1)
+	panics := make(chan *PanicError, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				panics <- &PanicError{Value: rec, Stack: debug.Stack()}
+			}
+		}()
+		value, err := fn(r.WithContext(ctx))
+		done <- result{value: value, err: err}
+	}()
+
+	var zero T
+	select {
+	case res := <-done:
+		if res.err != nil && errors.Is(res.err, context.DeadlineExceeded) && ctx.Err() != nil && r.Context().Err() == nil {
+			return zero, &TimeoutError{Phase: phase, Timeout: timeout}
+		}
+		return res.value, res.err
+	case perr := <-panics:
+		panic(perr)
+	case <-ctx.Done():
+		if err := r.Context().Err(); err != nil {
+			// The request itself was cancelled, not timed out
+			return zero, err
+		}
+		return zero, &TimeoutError{Phase: phase, Timeout: timeout}
+	}
+}
+
+// A request body which fails reads once its deserialization timed
+// out, so a Deserializer still running cannot read the body after the
+// handler returned
+type timeoutBody struct {
+	body    io.ReadCloser
+	expired atomic.Bool
+}
+
+func (t *timeoutBody) Read(p []byte) (int, error) {
+	if t.expired.Load() {
+		return 0, context.DeadlineExceeded
+	}
+	return t.body.Read(p)
+}
+
+func (t *timeoutBody) Close() error {
+	return t.body.Close()
+}
+
+// Runs the Deserializer within DeserializeTimeout. On a timeout the
+// body is cut off and a read in progress is interrupted through the
+// read deadline of the connection.
+func (m *BaseHandler) deserialize(w http.ResponseWriter, r *http.Request) (Inputable, error) {
+	if m.DeserializeTimeout <= 0 || r.Body == nil {
+		return withTimeout(r, PhaseDeserialize, m.DeserializeTimeout, m.Deserializer.Deserialize)
+	}
+	body := &timeoutBody{body: r.Body}
+	r = r.WithContext(r.Context())
+	r.Body = body
+	in, err := withTimeout(r, PhaseDeserialize, m.DeserializeTimeout, m.Deserializer.Deserialize)
+	var terr *TimeoutError
+	if errors.As(err, &terr) {
+		body.expired.Store(true)
+		// Fails if w does not support deadlines, in which case the
+		// read ends with the request
+		http.NewResponseController(w).SetReadDeadline(time.Now())
+	}
+	return in, err
+}
+
+// Returns the Serializer for an error of a phase which may have timed
+// out: TimeoutSerializer for a *TimeoutError, fallback otherwise
+func (m *BaseHandler) timeoutSerializer(err error, fallback Serializable) Serializable {
+	var terr *TimeoutError
+	if m.TimeoutSerializer != nil && errors.As(err, &terr) {
+		return m.TimeoutSerializer
+	}
+	return fallback
+}
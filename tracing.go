@@ -0,0 +1,183 @@
+package resdk
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Identity of a span as carried by the W3C Trace Context headers
+// traceparent and tracestate
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	// Trace flags, 1 if the trace is sampled
+	Flags byte
+	// Vendor specific tracestate header, passed on as is
+	TraceState string
+	// Whether the span belongs to another service
+	Remote bool
+}
+
+// Reports whether s has non zero ids
+func (s SpanContext) IsValid() bool {
+	return s.TraceID != [16]byte{} && s.SpanID != [8]byte{}
+}
+
+// Returns the traceparent header of s, e.g.
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+func (s SpanContext) Traceparent() string {
+	return "00-" + hex.EncodeToString(s.TraceID[:]) + "-" + hex.EncodeToString(s.SpanID[:]) + "-" + hex.EncodeToString([]byte{s.Flags})
+}
+
+// Parses a traceparent header. Returns false if it is malformed or has
+// zero ids, in which case it must be ignored.
+func ParseTraceparent(header string) (SpanContext, bool) {
+	var s SpanContext
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	// Later versions may append fields
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return s, false
+	}
+	var version, flags [1]byte
+	if !decodeLowerHex(version[:], parts[0]) || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 ||
+		!decodeLowerHex(s.TraceID[:], parts[1]) || !decodeLowerHex(s.SpanID[:], parts[2]) || !decodeLowerHex(flags[:], parts[3]) {
+		return s, false
+	}
+	s.Flags = flags[0]
+	s.Remote = true
+	return s, s.IsValid()
+}
+
+// Decodes hex value into dst. Only lower case digits are valid in
+// traceparent headers.
+func decodeLowerHex(dst []byte, value string) bool {
+	if strings.ToLower(value) != value {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(value))
+	return err == nil
+}
+
+type spanContextKey struct{}
+
+// Returns a copy of ctx carrying s
+func ContextWithSpanContext(ctx context.Context, s SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, s)
+}
+
+// Returns the SpanContext of the current span in ctx, the zero
+// SpanContext if there is none. Within a handler with a
+// TracingInterceptor it is the span of the current phase.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	s, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return s
+}
+
+// Sets the traceparent and tracestate headers of an outgoing request
+// to the current span in ctx, so the trace continues in the called
+// service. Does nothing if ctx has no span.
+func InjectTraceparent(ctx context.Context, header http.Header) {
+	s := SpanContextFromContext(ctx)
+	if !s.IsValid() {
+		return
+	}
+	header.Set("traceparent", s.Traceparent())
+	if s.TraceState != "" {
+		header.Set("tracestate", s.TraceState)
+	}
+}
+
+// A span started by a Tracer
+type Span interface {
+	// Returns the identity of the span
+	SpanContext() SpanContext
+	// Ends the span, err is the error which ended the operation or
+	// nil if it succeeded
+	End(err error)
+}
+
+// Starts spans in a tracing system. Adapting OpenTelemetry takes a few
+// lines around a trace.Tracer of go.opentelemetry.io/otel/trace:
+//
+//	type otelTracer struct{ t trace.Tracer }
+//
+//	func (o otelTracer) Start(ctx context.Context, name string, server bool) resdk.Span {
+//		if parent := resdk.SpanContextFromContext(ctx); parent.IsValid() {
+//			state, _ := trace.ParseTraceState(parent.TraceState)
+//			ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+//				TraceID: parent.TraceID, SpanID: parent.SpanID,
+//				TraceFlags: trace.TraceFlags(parent.Flags), TraceState: state, Remote: parent.Remote,
+//			}))
+//		}
+//		kind := trace.SpanKindInternal
+//		if server {
+//			kind = trace.SpanKindServer
+//		}
+//		_, span := o.t.Start(ctx, name, trace.WithSpanKind(kind))
+//		return otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) SpanContext() resdk.SpanContext {
+//		sc := s.Span.SpanContext()
+//		return resdk.SpanContext{TraceID: sc.TraceID(), SpanID: sc.SpanID(),
+//			Flags: byte(sc.TraceFlags()), TraceState: sc.TraceState().String()}
+//	}
+//
+//	func (s otelSpan) End(err error) {
+//		if err != nil {
+//			s.Span.RecordError(err)
+//			s.Span.SetStatus(codes.Error, err.Error())
+//		}
+//		s.Span.End()
+//	}
+type Tracer interface {
+	// Starts a span named name as a child of the span in ctx (see
+	// SpanContextFromContext), a new trace if there is none. server
+	// is true for the span of a whole request.
+	Start(ctx context.Context, name string, server bool) Span
+}
+
+// An Interceptor tracing requests with a server span per request and a
+// child span per phase. The span of an incoming traceparent header
+// becomes the parent of the server span. The context of every phase,
+// including the one passed to ProcessableCtx Processors, carries the
+// span of the phase, so outgoing requests continue the trace with
+// InjectTraceparent:
+//
+//	resdk.BaseHandler{
+//		...
+//		Interceptors: []resdk.Interceptor{resdk.TracingInterceptor{Tracer: tracer}},
+//	}
+type TracingInterceptor struct {
+	// Required
+	Tracer Tracer
+	// Name of the server span. Defaults to the request method, since
+	// paths with ids would make too many distinct names.
+	Name string
+}
+
+type tracingSpanKey Phase
+
+func (t TracingInterceptor) Before(ctx context.Context, phase Phase, r *http.Request) context.Context {
+	name := string(phase)
+	if phase == PhaseRequest {
+		name = orDefault(t.Name, r.Method)
+		if parent, ok := ParseTraceparent(r.Header.Get("traceparent")); ok {
+			parent.TraceState = r.Header.Get("tracestate")
+			ctx = ContextWithSpanContext(ctx, parent)
+		}
+	}
+	span := t.Tracer.Start(ctx, name, phase == PhaseRequest)
+	ctx = ContextWithSpanContext(ctx, span.SpanContext())
+	return context.WithValue(ctx, tracingSpanKey(phase), span)
+}
+
+func (t TracingInterceptor) After(ctx context.Context, phase Phase, r *http.Request, err error) {
+	if span, ok := ctx.Value(tracingSpanKey(phase)).(Span); ok {
+		span.End(err)
+	}
+}
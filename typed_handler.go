@@ -0,0 +1,126 @@
+package resdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Typed variant of Deserializable which returns a concrete Inputable
+// type instead of the Inputable interface.
+type TypedDeserializable[In Inputable] interface {
+	// Deserializes a request into an In
+	Deserialize(r *http.Request) (In, error)
+}
+
+// Typed variant of Processable which receives and returns concrete
+// types, so no type assertions are needed inside Process.
+type TypedProcessable[In Inputable, Out Outputable] interface {
+	// Processes an In and returns an Out or an error. A nil pointer
+	// Out is treated the same as a nil Outputable, i.e. Not found.
+	// auth_details is nil if the handler has no Authenticator.
+	Process(ctx context.Context, in In, auth_details interface{}) (Out, error)
+}
+
+// Typed variant of Serializable for success responses
+type TypedSerializable[Out Outputable] interface {
+	// Serializes an Out onto the response writer
+	Serialize(out Out, w http.ResponseWriter, r *http.Request)
+}
+
+// Adapts a TypedDeserializable to a Deserializable
+func AsDeserializer[In Inputable](d TypedDeserializable[In]) Deserializable {
+	return typedDeserializer[In]{d: d}
+}
+
+// Adapts a TypedProcessable to a Processable. The returned Processor
+// also implements AuthAwareProcessable.
+func AsProcessor[In Inputable, Out Outputable](p TypedProcessable[In, Out]) Processable {
+	return typedProcessor[In, Out]{p: p}
+}
+
+// Adapts a TypedSerializable to a Serializable. The returned
+// Serializer panics if it receives anything other than an Out, so it
+// should only be used as a SuccessSerializer.
+func AsSerializer[Out Outputable](s TypedSerializable[Out]) Serializable {
+	return typedSerializer[Out]{s: s}
+}
+
+type typedDeserializer[In Inputable] struct {
+	d TypedDeserializable[In]
+}
+
+func (t typedDeserializer[In]) Deserialize(r *http.Request) (Inputable, error) {
+	in, err := t.d.Deserialize(r)
+	if err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+type typedProcessor[In Inputable, Out Outputable] struct {
+	p TypedProcessable[In, Out]
+}
+
+func (t typedProcessor[In, Out]) Process(in Inputable) (Outputable, error) {
+	return t.ProcessAuth(context.Background(), in, nil)
+}
+
+func (t typedProcessor[In, Out]) ProcessAuth(ctx context.Context, in Inputable, auth_details interface{}) (Outputable, error) {
+	typed_in, ok := in.(In)
+	if !ok {
+		return nil, fmt.Errorf("resdk: typed processor received %T", in)
+	}
+	out, err := t.p.Process(ctx, typed_in, auth_details)
+	if err != nil {
+		return nil, err
+	}
+	if isNil(out) {
+		return nil, nil
+	}
+	return out, nil
+}
+
+type typedSerializer[Out Outputable] struct {
+	s TypedSerializable[Out]
+}
+
+func (t typedSerializer[Out]) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	typed_out, ok := out.(Out)
+	if !ok {
+		panic(fmt.Sprintf("resdk: typed serializer received %T", out))
+	}
+	t.s.Serialize(typed_out, w, r)
+}
+
+// A BaseHandler whose Deserializer and Processor work on concrete
+// types. Use NewTypedHandler to create one.
+type TypedHandler[In Inputable, Out Outputable] struct {
+	BaseHandler
+}
+
+// Creates a new TypedHandler from a BaseHandler. The Deserializer and
+// Processor of base are replaced by adapters around d and p, all other
+// fields (serializers, Authenticator) are used as is. Pass
+// NewJsonHandler(base).BaseHandler to get the default Json serializers.
+func NewTypedHandler[In Inputable, Out Outputable](base BaseHandler, d TypedDeserializable[In], p TypedProcessable[In, Out]) TypedHandler[In, Out] {
+	base.Deserializer = AsDeserializer[In](d)
+	base.Processor = AsProcessor[In, Out](p)
+	return TypedHandler[In, Out]{
+		BaseHandler: base,
+	}
+}
+
+// Reports whether v is nil or a nil pointer/interface
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package resdk
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Usage of the API by a caller within a period
+type Usage struct {
+	Requests int64 `json:"requests"`
+	// Size of the request bodies read, before decompression
+	BytesIn int64 `json:"bytes_in"`
+	// Size of the response bodies
+	BytesOut int64 `json:"bytes_out"`
+}
+
+// Returns the billing period of t, the calendar month in UTC, e.g.
+// "2026-10"
+func UsagePeriod(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// Storage of Usage per key and period, shared by UsageMeter and
+// QuotaConfig
+type UsageStore interface {
+	// Adds usage to the Usage of key in period
+	Add(ctx context.Context, key, period string, usage Usage) error
+	// Returns the Usage of key in period, the zero Usage if there is
+	// none
+	Get(ctx context.Context, key, period string) (Usage, error)
+}
+
+// A UsageStore keeping Usage in memory. Use NewMemoryUsageStore to
+// create one.
+type MemoryUsageStore struct {
+	mu    sync.Mutex
+	usage map[[2]string]Usage
+}
+
+// Creates an empty MemoryUsageStore
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{
+		usage: make(map[[2]string]Usage),
+	}
+}
+
+func (s *MemoryUsageStore) Add(ctx context.Context, key, period string, usage Usage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := s.usage[[2]string{key, period}]
+	total.Requests += usage.Requests
+	total.BytesIn += usage.BytesIn
+	total.BytesOut += usage.BytesOut
+	s.usage[[2]string{key, period}] = total
+	return nil
+}
+
+func (s *MemoryUsageStore) Get(ctx context.Context, key, period string) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage[[2]string{key, period}], nil
+}
+
+// Returns the key usage of r is metered by: the PrincipalID of
+// auth_details, "" for anonymous callers, which are not metered
+func usageKey(key func(r *http.Request, auth_details interface{}) string, r *http.Request, auth_details interface{}) string {
+	if key != nil {
+		return key(r, auth_details)
+	}
+	return PrincipalID(auth_details)
+}
+
+// An Interceptor adding the Usage of every request to Store, e.g. for
+// billing or to enforce a QuotaConfig. Failed requests count too.
+//
+//	usage := resdk.NewMemoryUsageStore()
+//	resdk.BaseHandler{
+//		...
+//		Interceptors: []resdk.Interceptor{&resdk.UsageMeter{Store: usage}},
+//		Quota: &resdk.QuotaConfig{Store: usage, Allowance: allowances},
+//	}
+type UsageMeter struct {
+	// Required
+	Store UsageStore
+	// Returns the key r is metered by. Defaults to the PrincipalID of
+	// auth_details. Requests with an empty key are not metered.
+	Key func(r *http.Request, auth_details interface{}) string
+	// Optional callback receiving errors of Store
+	OnError func(r *http.Request, err error)
+}
+
+func (u *UsageMeter) Before(ctx context.Context, phase Phase, r *http.Request) context.Context {
+	return ctx
+}
+
+func (u *UsageMeter) After(ctx context.Context, phase Phase, r *http.Request, err error) {
+	x := ExchangeFromContext(ctx)
+	if phase != PhaseRequest || x == nil {
+		return
+	}
+	key := usageKey(u.Key, r, x.AuthDetails)
+	if key == "" {
+		return
+	}
+	usage := Usage{Requests: 1, BytesIn: x.BytesRead(), BytesOut: x.BytesWritten()}
+	err = u.Store.Add(context.WithoutCancel(ctx), key, UsagePeriod(x.Started), usage)
+	if err != nil && u.OnError != nil {
+		u.OnError(r, err)
+	}
+}
+
+// Error for a caller who used up its allowance for the current period.
+// Responds with 429 Too Many Requests and a Retry-After header pointing
+// to the start of the next period.
+type QuotaExceededError struct {
+	Allowance Usage
+	Used      Usage
+	// Start of the next period
+	Reset time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return "Quota exceeded"
+}
+
+func (e *QuotaExceededError) StatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+func (e *QuotaExceededError) Headers() http.Header {
+	return http.Header{"Retry-After": {strconv.Itoa(ceilSeconds(time.Until(e.Reset)))}}
+}
+
+// Monthly quotas of a BaseHandler. Requests of callers whose Usage in
+// the current period (see UsagePeriod) reached their allowance fail
+// with *QuotaExceededError. Usage must be recorded into Store, usually
+// by a UsageMeter with the same Key.
+type QuotaConfig struct {
+	// Required
+	Store UsageStore
+	// Required. Returns the allowance of a caller per period. Zero
+	// fields are unlimited.
+	Allowance func(auth_details interface{}) Usage
+	// Same as UsageMeter.Key. Requests with an empty key are not
+	// checked.
+	Key func(r *http.Request, auth_details interface{}) string
+	// Error response serializer in case of exceeded quotas and Store
+	// failures. Falls back to AuthorizationErrorSerializer.
+	ErrorSerializer Serializable
+}
+
+// Fails with *QuotaExceededError if the caller of r used up its
+// allowance
+func (c *QuotaConfig) check(r *http.Request, auth_details interface{}) error {
+	key := usageKey(c.Key, r, auth_details)
+	if key == "" {
+		return nil
+	}
+	now := time.Now().UTC()
+	used, err := c.Store.Get(r.Context(), key, UsagePeriod(now))
+	if err != nil {
+		return err
+	}
+	allowance := c.Allowance(auth_details)
+	if exceeded(used.Requests, allowance.Requests) || exceeded(used.BytesIn, allowance.BytesIn) || exceeded(used.BytesOut, allowance.BytesOut) {
+		return &QuotaExceededError{
+			Allowance: allowance,
+			Used:      used,
+			Reset:     time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	return nil
+}
+
+// Reports whether used reached a non zero allowance
+func exceeded(used, allowance int64) bool {
+	return allowance > 0 && used >= allowance
+}
@@ -0,0 +1,59 @@
+package resdk
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// A UUID in its canonical textual form
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx. It binds from strings, e.g.
+// query or path parameters, and marshals back to one. Types of UUID
+// libraries implementing encoding.TextUnmarshaler bind as well.
+type UUID [16]byte
+
+// Parses a UUID in canonical form, optionally in braces or with a
+// urn:uuid: prefix
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	err := u.UnmarshalText([]byte(s))
+	return u, err
+}
+
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *UUID) UnmarshalText(text []byte) error {
+	s := string(text)
+	if len(s) == 45 && s[:9] == "urn:uuid:" {
+		s = s[9:]
+	} else if len(s) == 38 && s[0] == '{' && s[37] == '}' {
+		s = s[1:37]
+	}
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return fmt.Errorf("invalid UUID %q", text)
+	}
+	var parsed UUID
+	for i, span := range [][2]int{{0, 8}, {9, 13}, {14, 18}, {19, 23}, {24, 36}} {
+		offset := [...]int{0, 4, 6, 8, 10}[i]
+		if _, err := hex.Decode(parsed[offset:], []byte(s[span[0]:span[1]])); err != nil {
+			return fmt.Errorf("invalid UUID %q", text)
+		}
+	}
+	*u = parsed
+	return nil
+}
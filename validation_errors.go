@@ -0,0 +1,141 @@
+package resdk
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// One failed check of a field
+type ValidationError struct {
+	// Machine readable name of the check, e.g. "required"
+	Code string `json:"code"`
+	// Human readable message
+	Message string `json:"message"`
+}
+
+// Field level validation failures: every field can have several failed
+// checks, and nested objects have their own ValidationErrors. Responds
+// with 400 Bad Request and renders in Json as
+//
+//	{"errors": {
+//		"email": [{"code": "email", "message": "must be a valid email address"}],
+//		"address": {"city": [{"code": "required", "message": "is required"}]}
+//	}}
+//
+// Build it up in Validate and return Err, which is nil if nothing
+// failed:
+//
+//	errs := resdk.NewValidationErrors()
+//	if c.Email == "" {
+//		errs.Add("email", "required", "is required")
+//	}
+//	return errs.Err()
+type ValidationErrors struct {
+	fields map[string][]ValidationError
+	nested map[string]*ValidationErrors
+}
+
+// Creates an empty ValidationErrors
+func NewValidationErrors() *ValidationErrors {
+	return &ValidationErrors{
+		fields: make(map[string][]ValidationError),
+		nested: make(map[string]*ValidationErrors),
+	}
+}
+
+// Records a failed check of field
+func (v *ValidationErrors) Add(field, code, message string) *ValidationErrors {
+	v.fields[field] = append(v.fields[field], ValidationError{Code: code, Message: message})
+	return v
+}
+
+// Returns the ValidationErrors of the nested object field, creating it
+// if needed
+func (v *ValidationErrors) Nest(field string) *ValidationErrors {
+	nested, ok := v.nested[field]
+	if !ok {
+		nested = NewValidationErrors()
+		v.nested[field] = nested
+	}
+	return nested
+}
+
+// Records a failed check of the field at a dotted path such as
+// "address.city", nesting along the way
+func (v *ValidationErrors) AddPath(path, code, message string) *ValidationErrors {
+	target := v
+	parts := strings.Split(path, ".")
+	for _, part := range parts[:len(parts)-1] {
+		target = target.Nest(part)
+	}
+	return target.Add(parts[len(parts)-1], code, message)
+}
+
+// Returns the failed checks of field
+func (v *ValidationErrors) Field(field string) []ValidationError {
+	return v.fields[field]
+}
+
+// Reports whether nothing failed
+func (v *ValidationErrors) Empty() bool {
+	if len(v.fields) > 0 {
+		return false
+	}
+	for _, nested := range v.nested {
+		if !nested.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns v as an error, or nil if it is empty
+func (v *ValidationErrors) Err() error {
+	if v == nil || v.Empty() {
+		return nil
+	}
+	return v
+}
+
+// Returns all failures as "path: message" sorted by path
+func (v *ValidationErrors) Error() string {
+	var messages []string
+	v.flatten("", &messages)
+	sort.Strings(messages)
+	return strings.Join(messages, "; ")
+}
+
+func (v *ValidationErrors) flatten(prefix string, messages *[]string) {
+	for field, errs := range v.fields {
+		for _, err := range errs {
+			*messages = append(*messages, prefix+field+": "+err.Message)
+		}
+	}
+	for field, nested := range v.nested {
+		nested.flatten(prefix+field+".", messages)
+	}
+}
+
+func (v *ValidationErrors) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// Returns the failures as a tree of field -> []ValidationError or
+// nested tree
+func (v *ValidationErrors) tree() map[string]interface{} {
+	tree := make(map[string]interface{}, len(v.fields)+len(v.nested))
+	for field, nested := range v.nested {
+		if !nested.Empty() {
+			tree[field] = nested.tree()
+		}
+	}
+	for field, errs := range v.fields {
+		tree[field] = errs
+	}
+	return tree
+}
+
+func (v *ValidationErrors) MarshalJSON() ([]byte, error) {
+	return JsonEngine.Marshal(map[string]interface{}{"errors": v.tree()})
+}
@@ -0,0 +1,132 @@
+package resdk
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// A named value checked by the cross field helpers of ValidationErrors
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// Creates a Field
+func F(name string, value interface{}) Field {
+	return Field{Name: name, Value: value}
+}
+
+// Reports whether the field has a value: not nil and not the zero value
+// of its type. A non-nil pointer counts as set even if it points to a
+// zero value.
+func (f Field) set() bool {
+	if f.Value == nil {
+		return false
+	}
+	return !reflect.ValueOf(f.Value).IsZero()
+}
+
+// Returns the names of fields
+func fieldNames(fields []Field) string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Records a "required" failure for field if cond holds and it is not
+// set, e.g. errs.RequiredIf(c.Type == "company", resdk.F("vat_id", c.VatID))
+func (v *ValidationErrors) RequiredIf(cond bool, field Field) *ValidationErrors {
+	if cond && !field.set() {
+		v.Add(field.Name, "required", "is required")
+	}
+	return v
+}
+
+// Records a "mutually_exclusive" failure for each set field if more
+// than one of fields is set
+func (v *ValidationErrors) MutuallyExclusive(fields ...Field) *ValidationErrors {
+	var set []Field
+	for _, f := range fields {
+		if f.set() {
+			set = append(set, f)
+		}
+	}
+	if len(set) < 2 {
+		return v
+	}
+	for _, f := range set {
+		v.Add(f.Name, "mutually_exclusive", "cannot be combined with "+otherFieldNames(set, f.Name))
+	}
+	return v
+}
+
+// Returns the names of fields other than name
+func otherFieldNames(fields []Field, name string) string {
+	var others []Field
+	for _, f := range fields {
+		if f.Name != name {
+			others = append(others, f)
+		}
+	}
+	return fieldNames(others)
+}
+
+// Records an "at_least_one_of" failure for each of fields if none of
+// them is set
+func (v *ValidationErrors) AtLeastOneOf(fields ...Field) *ValidationErrors {
+	for _, f := range fields {
+		if f.set() {
+			return v
+		}
+	}
+	for _, f := range fields {
+		v.Add(f.Name, "at_least_one_of", "at least one of "+fieldNames(fields)+" is required")
+	}
+	return v
+}
+
+// Records a "range" failure for high if both fields are set and high is
+// less than low, e.g. for a from/to date range. Values may be numbers,
+// strings or time.Time, or pointers to them, of the same kind.
+func (v *ValidationErrors) RangeWith(low, high Field) *ValidationErrors {
+	if !low.set() || !high.set() {
+		return v
+	}
+	c, ok := compareValues(low.Value, high.Value)
+	if !ok {
+		panic(fmt.Sprintf("resdk: cannot compare %T with %T", low.Value, high.Value))
+	}
+	if c > 0 {
+		v.Add(high.Name, "range", "must not be less than "+low.Name)
+	}
+	return v
+}
+
+// Compares two values of the same ordered kind. Returns false if they
+// cannot be compared.
+func compareValues(a, b interface{}) (int, bool) {
+	av, bv := reflect.Indirect(reflect.ValueOf(a)), reflect.Indirect(reflect.ValueOf(b))
+	if at, ok := av.Interface().(time.Time); ok {
+		bt, ok := bv.Interface().(time.Time)
+		if !ok {
+			return 0, false
+		}
+		return at.Compare(bt), true
+	}
+	switch {
+	case av.CanInt() && bv.CanInt():
+		return cmp.Compare(av.Int(), bv.Int()), true
+	case av.CanUint() && bv.CanUint():
+		return cmp.Compare(av.Uint(), bv.Uint()), true
+	case av.CanFloat() && bv.CanFloat():
+		return cmp.Compare(av.Float(), bv.Float()), true
+	case av.Kind() == reflect.String && bv.Kind() == reflect.String:
+		return strings.Compare(av.String(), bv.String()), true
+	}
+	return 0, false
+}
@@ -0,0 +1,164 @@
+package resdk
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Returns the vendor media type of version of an API, e.g.
+// application/vnd.myapp.v2+json for vendor "myapp", version 2 and
+// suffix "json"
+func VendorMediaType(vendor string, version int, suffix string) string {
+	media_type := "application/vnd." + vendor + ".v" + strconv.Itoa(version)
+	if suffix != "" {
+		media_type += "+" + suffix
+	}
+	return media_type
+}
+
+// Parses a vendor media type of the form
+// application/vnd.<vendor>.v<version>[+<suffix>]. Parameters are
+// ignored.
+func parseVendorMediaType(media_type string) (vendor string, version int, suffix string, ok bool) {
+	media_type, _, _ = strings.Cut(media_type, ";")
+	media_type = strings.ToLower(strings.TrimSpace(media_type))
+	name, found := strings.CutPrefix(media_type, "application/vnd.")
+	if !found {
+		return "", 0, "", false
+	}
+	name, suffix, _ = strings.Cut(name, "+")
+	dot := strings.LastIndex(name, ".v")
+	if dot < 0 {
+		return "", 0, "", false
+	}
+	version, err := strconv.Atoi(name[dot+2:])
+	if err != nil || version < 0 {
+		return "", 0, "", false
+	}
+	return name[:dot], version, suffix, true
+}
+
+// Returns the sorted keys of a version registry
+func sortedVersions[T any](versions map[int]T) []int {
+	keys := make([]int, 0, len(versions))
+	for version := range versions {
+		keys = append(keys, version)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// A Serializer which picks the Serializer for the API version requested
+// through a vendor media type in the Accept header, e.g.
+// application/vnd.myapp.v2+json, so several versions of an API can be
+// served side by side on the same route. The response Content-Type is
+// the vendor media type of the chosen version.
+//
+// Requests which do not ask for a registered version of Vendor get the
+// Default version.
+//
+//	s := &resdk.VersionedSerializer{Vendor: "myapp", Suffix: "json", Default: 1, Versions: map[int]resdk.Serializable{
+//		1: resdk.SerializerFunc(serializeV1),
+//		2: &resdk.JsonSerializer{StatusCode: http.StatusOK},
+//	}}
+type VersionedSerializer struct {
+	// Vendor name in the media type, e.g. "myapp"
+	Vendor string
+	// Structured syntax suffix of the media type, e.g. "json"
+	Suffix string
+	// Serializer of every supported version
+	Versions map[int]Serializable
+	// Version used if the request does not ask for a supported one
+	Default int
+}
+
+// Returns the version best matching the Accept header of r
+func (v *VersionedSerializer) version(r *http.Request) int {
+	ranges := parseAccept(r.Header.Values("Accept"))
+	versions := sortedVersions(v.Versions)
+	best, best_q := v.Default, 0.0
+	// Newest first, so it wins among equally acceptable versions
+	for i := len(versions) - 1; i >= 0; i-- {
+		media_type := VendorMediaType(v.Vendor, versions[i], v.Suffix)
+		// Only explicit requests for a version count
+		if q, specificity := acceptQuality(ranges, media_type); specificity == 2 && q > best_q {
+			best, best_q = versions[i], q
+		}
+	}
+	return best
+}
+
+func (v *VersionedSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	v.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns the error reported by the chosen
+// Serializer if it is a CheckedSerializable
+func (v *VersionedSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	version := v.version(r)
+	s, ok := v.Versions[version]
+	if !ok {
+		return fmt.Errorf("resdk: no serializer for version %d", version)
+	}
+	w.Header().Add("Vary", "Accept")
+	media_type := VendorMediaType(v.Vendor, version, v.Suffix)
+	hw := &headerWriter{ResponseWriter: w, onHeader: func(header http.Header) {
+		if header.Get("Content-Type") != "" {
+			header.Set("Content-Type", media_type)
+		}
+	}}
+	return callSerializer(s, out, hw, r)
+}
+
+// Error returned for a request body whose media type is not supported.
+// Responds with 415 Unsupported Media Type.
+type UnsupportedMediaTypeError struct {
+	// Content-Type of the request
+	MediaType string
+}
+
+func (u *UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported media type %q", u.MediaType)
+}
+
+func (u *UnsupportedMediaTypeError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+// A Deserializer which picks the Deserializer for the API version of
+// the request body given through a vendor media type in the
+// Content-Type header, e.g. application/vnd.myapp.v2+json.
+//
+// Requests with any other Content-Type use the Default version. Vendor
+// media types of other vendors or unsupported versions fail with an
+// *UnsupportedMediaTypeError.
+type VersionedDeserializer struct {
+	// Vendor name in the media type, e.g. "myapp"
+	Vendor string
+	// Deserializer of every supported version
+	Versions map[int]Deserializable
+	// Version used if the request has no vendor media type
+	Default int
+}
+
+func (v *VersionedDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	content_type := r.Header.Get("Content-Type")
+	version := v.Default
+	if vendor, requested, _, ok := parseVendorMediaType(content_type); ok {
+		if vendor != strings.ToLower(v.Vendor) {
+			return nil, &UnsupportedMediaTypeError{MediaType: content_type}
+		}
+		version = requested
+	} else if _, _, err := mime.ParseMediaType(content_type); content_type != "" && err != nil {
+		return nil, &UnsupportedMediaTypeError{MediaType: content_type}
+	}
+	d, ok := v.Versions[version]
+	if !ok {
+		return nil, &UnsupportedMediaTypeError{MediaType: content_type}
+	}
+	return d.Deserialize(r)
+}
@@ -0,0 +1,63 @@
+package resdk
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Extends BaseHandler with default serializers for Xml responses
+// Also assumes standard response status codes
+// Use NewXmlHandler to use it properly
+type XmlHandler struct {
+	BaseHandler
+}
+
+// Creates a new XmlHandler from a BaseHandler with default serializers
+func NewXmlHandler(base BaseHandler) XmlHandler {
+	x := XmlHandler{
+		BaseHandler: base,
+	}
+	x.setDefaults()
+	return x
+}
+
+func (x *XmlHandler) setDefaults() {
+	if x.SuccessSerializer == nil {
+		x.SuccessSerializer = &XmlSerializer{StatusCode: http.StatusOK}
+	}
+	if x.DeserializationErrorSerializer == nil {
+		x.DeserializationErrorSerializer = &XmlErrorSerializer{StatusCode: http.StatusBadRequest}
+	}
+	if x.ValidationErrorSerializer == nil {
+		x.ValidationErrorSerializer = &XmlErrorSerializer{StatusCode: http.StatusBadRequest}
+	}
+	if x.ExternalValidationErrorSerializer == nil {
+		x.ExternalValidationErrorSerializer = &XmlErrorSerializer{StatusCode: http.StatusUnprocessableEntity}
+	}
+	if x.AuthenticationErrorSerializer == nil {
+		x.AuthenticationErrorSerializer = &XmlErrorSerializer{StatusCode: http.StatusUnauthorized}
+	}
+	if x.ProcessingErrorSerializer == nil {
+		x.ProcessingErrorSerializer = &XmlErrorSerializer{StatusCode: http.StatusInternalServerError}
+	}
+	if x.NotFoundSerializer == nil {
+		x.NotFoundSerializer = &XmlErrorSerializer{StatusCode: http.StatusNotFound}
+	}
+	if x.AuthorizationErrorSerializer == nil {
+		x.AuthorizationErrorSerializer = &XmlErrorSerializer{StatusCode: http.StatusForbidden}
+	}
+	if x.MethodNotAllowedSerializer == nil {
+		x.MethodNotAllowedSerializer = &XmlErrorSerializer{StatusCode: http.StatusMethodNotAllowed}
+	}
+	if x.TimeoutSerializer == nil {
+		// TimeoutError picks 408 or 504 depending on the phase
+		x.TimeoutSerializer = &XmlErrorSerializer{StatusCode: http.StatusGatewayTimeout}
+	}
+	if x.RecoverySerializer == nil {
+		// Panic values are not meant for clients
+		x.RecoverySerializer = &XmlErrorSerializer{
+			StatusCode: http.StatusInternalServerError,
+			Error:      errors.New("Internal server error"),
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package resdk
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+)
+
+// Body sent instead of the response if it cannot be marshaled
+var xmlMarshalFailureBody = []byte(xml.Header + `<error>Internal server error</error>`)
+
+// Marshals v (inside an element named root unless root is empty) and
+// writes it with the status and headers chosen for out (see
+// writeMarshaled)
+func writeXml(w http.ResponseWriter, out Outputable, v interface{}, root string, def_status int) error {
+	return writeMarshaled(w, out, "application/xml", def_status, xmlMarshalFailureBody, func(buf *bytes.Buffer) error {
+		buf.WriteString(xml.Header)
+		enc := xml.NewEncoder(buf)
+		if root != "" {
+			return enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: root}})
+		}
+		return enc.Encode(v)
+	})
+}
+
+// A serializer for response in xml
+type XmlSerializer struct {
+	// HTTP Status Code to be returned unless out implements
+	// StatusCoder
+	StatusCode int
+	// If set it overrides the name of the root element. Otherwise
+	// the rules of encoding/xml apply (XMLName field or type name).
+	RootElement string
+}
+
+// Serializes Outputable to a ResponseWriter
+func (x XmlSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	x.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors. Responds
+// with a 500 error if out cannot be marshaled.
+func (x XmlSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	return writeXml(w, out, out, x.RootElement, x.StatusCode)
+}
+
+// A serializer for error response in xml
+type XmlErrorSerializer struct {
+	// HTTP Status Code to be returned unless the error implements
+	// StatusCoder
+	StatusCode int
+	// If set it overrides the error message in response
+	Error Outputable
+	// Name of the root element. Defaults to "error".
+	RootElement string
+}
+
+// Serializes out to a ResponseWriter in standard error format
+// If out is not Xml Marshalable but is an error, its error message
+// is used instead.
+// Error response format: <error>error message</error>
+func (x XmlErrorSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	x.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors
+func (x XmlErrorSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	// Status and headers always come from the original error
+	original := out
+	if x.Error != nil {
+		out = x.Error
+	}
+	root := x.RootElement
+	if root == "" {
+		root = "error"
+	}
+
+	var out_obj interface{} = out
+
+	if out_err, ok := out.(error); ok {
+		if _, ok = out.(xml.Marshaler); !ok {
+			// If out type is error but out is not a marshaller use error string
+			out_obj = out_err.Error()
+		}
+	}
+
+	return writeXml(w, original, out_obj, root, x.StatusCode)
+}
@@ -0,0 +1,116 @@
+package resdk
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+)
+
+// Unmarshals bytes into a value. Used to plug in decoders resdk does
+// not ship with, so that it stays free of dependencies.
+type UnmarshalFunc func(data []byte, v interface{}) error
+
+// Media type of Yaml bodies
+const YamlMediaType = "application/yaml"
+
+// Body sent instead of the response if it cannot be marshaled
+var yamlMarshalFailureBody = []byte("error: Internal server error\n")
+
+// Marshals v with marshal and writes it with the status and headers
+// chosen for out (see writeMarshaled)
+func writeYaml(w http.ResponseWriter, out Outputable, v interface{}, marshal MarshalFunc, def_status int) error {
+	return writeMarshaled(w, out, YamlMediaType, def_status, yamlMarshalFailureBody, func(buf *bytes.Buffer) error {
+		if marshal == nil {
+			return errors.New("resdk: yaml serializer has no Marshal function")
+		}
+		b, err := marshal(v)
+		buf.Write(b)
+		return err
+	})
+}
+
+// A serializer for response in Yaml. Add it to a NegotiatingSerializer
+// with YamlMediaType to offer Yaml next to other formats.
+type YamlSerializer struct {
+	// HTTP Status Code to be returned unless out implements
+	// StatusCoder
+	StatusCode int
+	// Required. Marshals the response, e.g. yaml.Marshal of
+	// gopkg.in/yaml.v3
+	Marshal MarshalFunc
+}
+
+// Serializes Outputable to a ResponseWriter
+func (y YamlSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	y.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors. Responds
+// with a 500 error if out cannot be marshaled.
+func (y YamlSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	return writeYaml(w, out, out, y.Marshal, y.StatusCode)
+}
+
+// A serializer for error response in Yaml
+type YamlErrorSerializer struct {
+	// HTTP Status Code to be returned unless the error implements
+	// StatusCoder
+	StatusCode int
+	// If set it overrides the error message in response
+	Error Outputable
+	// Required. Marshals the response, e.g. yaml.Marshal of
+	// gopkg.in/yaml.v3
+	Marshal MarshalFunc
+}
+
+// Serializes out to a ResponseWriter in standard error format.
+// Errors are sent as their error message.
+// Error response format: error: <object or error message>
+func (y YamlErrorSerializer) Serialize(out Outputable, w http.ResponseWriter, r *http.Request) {
+	y.SerializeChecked(out, w, r)
+}
+
+// Same as Serialize but returns marshaling and write errors
+func (y YamlErrorSerializer) SerializeChecked(out Outputable, w http.ResponseWriter, r *http.Request) error {
+	// Status and headers always come from the original error
+	original := out
+	if y.Error != nil {
+		out = y.Error
+	}
+
+	var out_obj interface{} = out
+	if out_err, ok := out.(error); ok {
+		out_obj = map[string]interface{}{
+			"error": out_err.Error(),
+		}
+	}
+
+	return writeYaml(w, original, out_obj, y.Marshal, y.StatusCode)
+}
+
+// A deserializer for request bodies in Yaml
+type YamlDeserializer struct {
+	// Required. Unmarshals the request body, e.g. yaml.Unmarshal of
+	// gopkg.in/yaml.v3
+	Unmarshal UnmarshalFunc
+	// Required. Returns an empty Inputable to unmarshal into,
+	// usually a pointer to a struct.
+	New func() Inputable
+	// Maximum size of the request body. No limit if zero.
+	MaxBytes int64
+}
+
+func (y YamlDeserializer) Deserialize(r *http.Request) (Inputable, error) {
+	b, err := readBody(r, y.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	in := y.New()
+	if err = applyDefaults(in); err != nil {
+		return nil, err
+	}
+	if err = y.Unmarshal(b, in); err != nil {
+		return nil, err
+	}
+	return in, nil
+}